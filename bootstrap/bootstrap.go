@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -11,9 +12,24 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"kiramishima/ionix/config"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/auth"
 	"kiramishima/ionix/internal/drugs"
+	"kiramishima/ionix/internal/health"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/patients"
 	"kiramishima/ionix/internal/pkg/database"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/mailer"
+	"kiramishima/ionix/internal/pkg/metrics"
+	"kiramishima/ionix/internal/pkg/migrate"
+	"kiramishima/ionix/internal/pkg/password"
+	"kiramishima/ionix/internal/pkg/problem"
+	"kiramishima/ionix/internal/pkg/realip"
+	"kiramishima/ionix/internal/pkg/signer"
+	"kiramishima/ionix/internal/pkg/timezone"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"kiramishima/ionix/internal/pkg/utils"
 	"kiramishima/ionix/internal/server"
 	"kiramishima/ionix/internal/vaccinations"
 	"time"
@@ -26,16 +42,27 @@ const (
 func bootstrap(
 	lifecycle fx.Lifecycle,
 	logger *zap.Logger,
+	cfg *models.Configuration,
+	migrator *migrate.Migrator,
 	server *server.Server,
 ) {
 
 	lifecycle.Append(
 		fx.Hook{
 			OnStart: func(ctx context.Context) error {
+				if cfg.MigrateOnStart {
+					logger.Info("Running migrations")
+					if err := migrator.Up(ctx); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+						return err
+					}
+				}
 				logger.Info("Starting API")
 				return server.Run()
 			},
 			OnStop: func(ctx context.Context) error {
+				if err := server.Shutdown(ctx); err != nil {
+					return err
+				}
 				return logger.Sync()
 			},
 		},
@@ -43,44 +70,80 @@ func bootstrap(
 
 }
 
+// Logger production logger of the API
+var Logger = fx.Provide(func() *zap.Logger {
+	logger, _ := config.NewProductionLogger(logPath)
+	return logger
+	// logger, _ := zap.NewProduction()
+	// return logger.Sugar()
+})
+
+// Migrate dependencies of the migrate command, logs go to the console
+var Migrate = fx.Options(
+	config.Module,
+	fx.Provide(zap.NewProduction),
+	database.Module,
+	migrate.Module,
+)
+
 var Module = fx.Options(
 	config.Module,
-	fx.Provide(func() *zap.Logger {
-		logger, _ := config.NewProductionLogger(logPath)
-		return logger
-		// logger, _ := zap.NewProduction()
-		// return logger.Sugar()
-	}),
-	fx.Provide(func() *chi.Mux {
+	Logger,
+	fx.Provide(func(cfg *models.Configuration) (*chi.Mux, error) {
+		loc, err := timezone.Load(cfg.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		proxies, err := realip.Parse(cfg.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+
 		var r = chi.NewRouter()
 		r.Use(cors.Handler(cors.Options{
 			AllowedOrigins:   []string{"*"},
-			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Accept-Language", "Authorization", "Content-Type", "If-Match", "If-None-Match", "Time-Zone", "X-CSRF-Token", "traceparent", "tracestate"},
+			ExposedHeaders:   []string{"Accept-Patch", "Content-Disposition", "ETag", "Link", "Content-Language", "Time-Zone"},
 			AllowCredentials: true,
 			MaxAge:           300, // Maximum value not ignored by any of major browsers
 		}))
 
-		r.Use(middleware.Timeout(60 * time.Second))
+		// the exports stream large tables and the imports have their own timeout
+		r.Use(utils.Timeout(60 * time.Second))
 		r.Use(middleware.RequestID)
-		r.Use(middleware.RealIP)
+		r.Use(i18n.Middleware)
+		r.Use(tracing.Middleware)
+		r.Use(metrics.Middleware)
+		r.Use(timezone.Middleware(loc))
+		r.Use(realip.Middleware(proxies))
 		r.Use(middleware.Recoverer)
 		r.Use(middleware.Logger)
 		r.Use(httprate.LimitByIP(1000, 1*time.Minute))
 		r.Use(middleware.Compress(5))
-		return r
+		return r, nil
 	}),
 	fx.Provide(func() *render.Render {
 		return render.New()
 	}),
 	fx.Provide(func() *validator.Validate {
-		return validator.New(validator.WithRequiredStructEnabled())
+		return models.NewValidator()
 	}),
+	i18n.Module,
 	server.Module,
+	tracing.Module,
 	database.Module,
+	migrate.Module,
+	health.Module,
+	metrics.Module,
+	problem.Module,
+	mailer.Module,
+	password.Module,
+	signer.Module,
 	auth.Module,
 	drugs.Module,
+	patients.Module,
 	vaccinations.Module,
+	audit.Module,
 	fx.Invoke(bootstrap),
 )
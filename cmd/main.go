@@ -1,11 +1,37 @@
 package main
 
 import (
+	"fmt"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/fx"
 	"kiramishima/ionix/bootstrap"
+	"os"
 )
 
+const usage = `Usage:
+  api_drugs [serve]               starts the API
+  api_drugs migrate up            applies all the pending migrations
+  api_drugs migrate down [N]      reverts the last N migrations (default 1)
+  api_drugs migrate goto VERSION  migrates up or down to VERSION, 0 reverts all of them
+  api_drugs migrate status        lists the migrations and the current version`
+
 func main() {
-	fx.New(bootstrap.Module).Run()
+	var command = "serve"
+	var args = os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		fx.New(bootstrap.Module).Run()
+	case "migrate":
+		if err := migrateCommand(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"kiramishima/ionix/bootstrap"
+	"kiramishima/ionix/internal/pkg/migrate"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+var errUsage = errors.New(usage)
+
+// migrateCommand runs the migrate subcommands against the configured database
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var migrator *migrate.Migrator
+	app := fx.New(bootstrap.Migrate, fx.Populate(&migrator), fx.NopLogger)
+	if err := app.Err(); err != nil {
+		return err
+	}
+
+	var ctx = context.Background()
+	var err error
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		var steps = 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errUsage
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "goto":
+		if len(args) < 2 {
+			return errUsage
+		}
+		version, parseErr := strconv.ParseUint(args[1], 10, 64)
+		if parseErr != nil {
+			return errUsage
+		}
+		err = migrator.Goto(ctx, uint(version))
+	case "status":
+		return printStatus(ctx, migrator)
+	default:
+		return errUsage
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
+	return err
+}
+
+func printStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	list, current, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, item := range list {
+		var status = "pending"
+		if item.Applied {
+			status = "applied"
+		}
+		_, _ = fmt.Fprintf(w, "%06d\t%s\t%s\n", item.Version, item.Name, status)
+	}
+	_, _ = fmt.Fprintf(w, "\ncurrent version: %d\n", current)
+	return w.Flush()
+}
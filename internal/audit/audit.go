@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/unrolled/render"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"time"
+)
+
+// Module audit
+var Module = fx.Module("audit",
+	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, denylist impl.TokenDenylist, signer impl.TokenSigner) error {
+		// loads repository
+		var repo = NewAuditRepository(conn, logger)
+		// loads service
+		var svc = NewAuditService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+		// loads handlers
+		NewAuditHandlers(r, logger, svc, render, denylist, signer)
+		return nil
+	}),
+)
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+// Entity Errors
+var (
+	// Audit
+	InternalServerError = errors.New("Error interno del servidor. Intente más tarde")
+	ErrTimeout          = errors.New("context timeout")
+	ErrPrepapareQuery   = errors.New("failed to prepare query")
+	ErrExecuteStatement = errors.New("failed to execute statement")
+	ErrServiceAudit     = errors.New("service audit error")
+	ErrRecordFailed     = errors.New("failed to record the audit entry")
+)
+
+// problems returned to the clients, the errors without entry are internal errors
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrTimeout, Code: "timeout", Status: http.StatusGatewayTimeout},
+	)
+}
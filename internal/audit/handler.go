@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/problem"
+	"kiramishima/ionix/internal/pkg/timezone"
+	httpUtils "kiramishima/ionix/internal/pkg/utils"
+	"net/http"
+)
+
+var _ impl.AuditHandlers = (*handler)(nil)
+
+// NewAuditHandlers creates an instance of audit handlers
+func NewAuditHandlers(r *chi.Mux, logger *zap.Logger, s impl.AuditService, render *render.Render, denylist impl.TokenDenylist, signer impl.TokenSigner) {
+	handler := &handler{
+		logger:   logger,
+		service:  s,
+		response: render,
+	}
+
+	r.Route("/v1/audit", func(r chi.Router) {
+		r.Use(signer.Verifier)
+		r.Use(httpUtils.Authenticator)
+		r.Use(httpUtils.Denylist(denylist))
+		r.Use(httpUtils.Authorize(models.PermAuditRead))
+
+		r.Get("/", handler.ListAuditHandler)
+	})
+}
+
+type handler struct {
+	logger   *zap.Logger
+	service  impl.AuditService
+	response *render.Render
+}
+
+func (h handler) ListAuditHandler(w http.ResponseWriter, req *http.Request) {
+	// query string
+	query, err := models.ParseAuditQuery(req.URL.Query())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+	// Call Service
+	resp, total, err := h.service.GetAuditLog(ctx, query)
+
+	if err != nil {
+		h.logger.Info(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	for _, entry := range resp {
+		entry.In(loc)
+	}
+
+	var body = models.ResponseWrapper[[]*models.AuditEntry]{
+		Data:  resp,
+		Meta:  query.Meta(total),
+		Links: httpUtils.PaginationLinks(req, query.Page, query.TotalPages(total)),
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, body); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
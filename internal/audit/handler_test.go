@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unrolled/render"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/mocks"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_ListAuditHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		query         string
+		buildStubs    func(uc *mocks.MockAuditService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Getting Data": {
+			query: "?entity=drug&entity_id=1",
+			buildStubs: func(uc *mocks.MockAuditService) {
+				uc.EXPECT().GetAuditLog(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ any, q *models.AuditQuery) ([]*models.AuditEntry, int, error) {
+						assert.Equal(t, "drug", *q.Entity)
+						assert.Equal(t, "1", *q.EntityID)
+						return []*models.AuditEntry{{ID: 1, Action: ActionUpdate, Entity: EntityDrug}}, 1, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"entity":"drug"`)
+				assert.Contains(t, recorder.Body.String(), `"total":1`)
+			},
+		},
+		"Bad actor": {
+			query:      "?actor_id=abc",
+			buildStubs: func(uc *mocks.MockAuditService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		"Internal error": {
+			buildStubs: func(uc *mocks.MockAuditService) {
+				uc.EXPECT().GetAuditLog(gomock.Any(), gomock.Any()).Times(1).Return(nil, 0, ErrServiceAudit)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockAuditService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/v1/audit"+tc.query, nil)
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+			}
+			h.ListAuditHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jmoiron/sqlx"
+	"kiramishima/ionix/internal/models"
+	"reflect"
+	"strconv"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	EntityDrug         = "drug"
+	EntityDrugSchedule = "drug_schedule"
+	EntityVaccination  = "vaccination"
+	EntityUser         = "user"
+	EntityRole         = "role"
+)
+
+// InsertQuery statement used by Record
+const InsertQuery = `INSERT INTO audit_log (actor_id, action, entity, entity_id, changes, request_id) VALUES ($1, $2, $3, $4, $5, $6)`
+
+// Record stores an audit entry inside the transaction of the mutation, so the entry
+// and the change are committed or rolled back together.
+// before is nil for a create and after is nil for a delete
+func Record(ctx context.Context, tx *sqlx.Tx, action string, entity string, entityID any, before any, after any) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return ErrRecordFailed
+	}
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return ErrRecordFailed
+	}
+
+	var id *string
+	if entityID != nil {
+		var v = fmt.Sprint(entityID)
+		id = &v
+	}
+	var requestID *string
+	if v := middleware.GetReqID(ctx); v != "" {
+		requestID = &v
+	}
+
+	_, err = tx.ExecContext(ctx, InsertQuery, ActorFromContext(ctx), action, entity, id, string(body), requestID)
+	if err != nil {
+		return ErrRecordFailed
+	}
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor sets the actor of the changes made without an access token, like the sign in or the
+// links sent by mail, the subject of an access token takes precedence
+func WithActor(ctx context.Context, actor int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext user id in the subject of the verified access token or the one set by WithActor,
+// nil when there is none
+func ActorFromContext(ctx context.Context) *int64 {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err == nil {
+		sub, _ := claims["sub"].(string)
+		if actor, err := strconv.ParseInt(sub, 10, 64); err == nil {
+			return &actor
+		}
+	}
+	if actor, ok := ctx.Value(actorKey{}).(int64); ok {
+		return &actor
+	}
+	return nil
+}
+
+// Diff keeps only the fields whose value changed, values are compared by their JSON form
+func Diff(before any, after any) (*models.AuditChanges, error) {
+	b, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil || a == nil {
+		return &models.AuditChanges{Before: b, After: a}, nil
+	}
+
+	var changes = &models.AuditChanges{Before: map[string]any{}, After: map[string]any{}}
+	for key, value := range a {
+		if old, ok := b[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes.Before[key] = b[key]
+			changes.After[key] = value
+		}
+	}
+	for key, old := range b {
+		if _, ok := a[key]; !ok {
+			changes.Before[key] = old
+			changes.After[key] = nil
+		}
+	}
+	return changes, nil
+}
+
+func toMap(v any) (map[string]any, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err = json.Unmarshal(buf, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	type item struct {
+		Name string `json:"name"`
+		Dose int    `json:"dose"`
+	}
+
+	t.Run("Only changed fields", func(t *testing.T) {
+		changes, err := Diff(&item{Name: "Aspirina", Dose: 1}, &item{Name: "Aspirina", Dose: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"dose": float64(1)}, changes.Before)
+		assert.Equal(t, map[string]any{"dose": float64(2)}, changes.After)
+	})
+
+	t.Run("Create keeps the whole state", func(t *testing.T) {
+		var before *item
+		changes, err := Diff(before, &item{Name: "Aspirina", Dose: 2})
+		assert.NoError(t, err)
+		assert.Nil(t, changes.Before)
+		assert.Equal(t, "Aspirina", changes.After["name"])
+	})
+
+	t.Run("Delete keeps the whole state", func(t *testing.T) {
+		changes, err := Diff(&item{Name: "Aspirina", Dose: 2}, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, changes.After)
+		assert.Equal(t, float64(2), changes.Before["dose"])
+	})
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "7"})
+	assert.NoError(t, err)
+	ctx := jwtauth.NewContext(context.Background(), token, nil)
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "host/abc-000001")
+
+	var actor int64 = 7
+	var requestID = "host/abc-000001"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(InsertQuery).
+		WithArgs(&actor, ActionDelete, EntityDrug, "3", `{"before":{"name":"Aspirina"},"after":null}`, &requestID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+	assert.NoError(t, Record(ctx, tx, ActionDelete, EntityDrug, 3, map[string]string{"name": "Aspirina"}, nil))
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestActorFromContext(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ActorFromContext(context.Background()))
+
+	var ctx = WithActor(context.Background(), 7)
+	assert.Equal(t, int64(7), *ActorFromContext(ctx))
+
+	// the subject of the access token takes precedence
+	token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *ActorFromContext(jwtauth.NewContext(ctx, token, nil)))
+}
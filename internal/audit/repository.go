@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+)
+
+// implement audit repository
+var _ interfaces.AuditRepository = (*repository)(nil)
+
+// Repository struct
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+// NewAuditRepository Creates a new instance of Repository
+func NewAuditRepository(conn *sqlx.DB, logger *zap.Logger) *repository {
+	return &repository{
+		db:  conn,
+		log: logger,
+	}
+}
+
+// GetAuditData gets a page of the audit log, newest first
+func (repo repository) GetAuditData(ctx context.Context, q *models.AuditQuery) ([]*models.AuditEntry, int, error) {
+	var where, args = q.Where()
+	var list = make([]*models.AuditEntry, 0)
+
+	var total int
+	err := repo.db.QueryRowxContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM audit_log WHERE %s`, where), args...).Scan(&total)
+	if err != nil {
+		repo.log.Error(err.Error())
+		return list, 0, ErrExecuteStatement
+	}
+
+	var query = fmt.Sprintf(`SELECT id, actor_id, action, entity, entity_id, changes, request_id, created_at
+	FROM audit_log WHERE %s ORDER BY created_at DESC, id DESC LIMIT %d OFFSET %d`, where, q.Limit, q.Offset())
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, 0, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			repo.log.Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	if err = stmt.SelectContext(ctx, &list, args...); err != nil {
+		repo.log.Error(err.Error())
+		return list, total, ErrExecuteStatement
+	}
+
+	return list, total, nil
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/models"
+	"testing"
+	"time"
+)
+
+func TestRepository_GetAuditData(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	repo := NewAuditRepository(sqlxDB, logger)
+
+	var entity = "drug"
+	var q = models.NewAuditQuery()
+	q.Entity = &entity
+
+	var countQuery = `SELECT COUNT(*) FROM audit_log WHERE 1 = 1 AND entity = $1`
+	var query = `SELECT id, actor_id, action, entity, entity_id, changes, request_id, created_at
+	FROM audit_log WHERE 1 = 1 AND entity = $1 ORDER BY created_at DESC, id DESC LIMIT 20 OFFSET 0`
+
+	t.Run("OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectQuery(countQuery).
+			WithArgs("drug").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs("drug").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "entity", "entity_id", "changes", "request_id", "created_at"}).
+				AddRow(1, 7, ActionUpdate, EntityDrug, "1", []byte(`{"before":{"max_dose":5},"after":{"max_dose":2}}`), nil, time.Now()))
+
+		data, total, err := repo.GetAuditData(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, 1, len(data))
+		assert.Equal(t, int64(7), *data[0].ActorID)
+		assert.JSONEq(t, `{"before":{"max_dose":5},"after":{"max_dose":2}}`, string(data[0].Changes))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
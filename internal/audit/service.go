@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"time"
+)
+
+var _ impl.AuditService = (*service)(nil)
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo impl.AuditRepository, logger *zap.Logger, timeout time.Duration) *service {
+	return &service{
+		logger:         logger,
+		repository:     repo,
+		contextTimeOut: timeout,
+	}
+}
+
+type service struct {
+	logger         *zap.Logger
+	repository     impl.AuditRepository
+	contextTimeOut time.Duration
+}
+
+func (svc service) GetAuditLog(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, int, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	if query == nil {
+		query = models.NewAuditQuery()
+	}
+
+	data, total, err := svc.repository.GetAuditData(cxt, query)
+
+	if err != nil {
+		svc.logger.Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ErrTimeout
+		default:
+			if errors.Is(err, ErrExecuteStatement) {
+				return nil, 0, ErrExecuteStatement
+			} else {
+				return nil, 0, ErrServiceAudit
+			}
+		}
+	}
+
+	return data, total, nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"kiramishima/ionix/internal/mocks"
+	"kiramishima/ionix/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestService_GetAuditLog(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuditRepository(mockCtrl)
+
+	var data = []*models.AuditEntry{
+		{ID: 1, Action: ActionCreate, Entity: EntityDrug},
+		{ID: 2, Action: ActionUpdate, Entity: EntityDrug},
+	}
+
+	repo.EXPECT().GetAuditData(gomock.Any(), gomock.Any()).Times(1).Return(data, len(data), nil)
+	repo.EXPECT().GetAuditData(gomock.Any(), gomock.Any()).Times(1).Return(nil, 0, ErrExecuteStatement)
+
+	svc := NewAuditService(repo, logger, 5*time.Second)
+
+	t.Run("Ok - Getting Data", func(t *testing.T) {
+		var items, total, err = svc.GetAuditLog(context.Background(), models.NewAuditQuery())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(items))
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Fail executing statement", func(t *testing.T) {
+		var items, _, err = svc.GetAuditLog(context.Background(), nil)
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.Equal(t, 0, len(items))
+	})
+}
@@ -7,19 +7,37 @@ import (
 	"github.com/unrolled/render"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
 	"time"
 )
 
 // Module auth
 var Module = fx.Module("auth",
-	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate) error {
+	// the denylist of access tokens is shared by the routers protected with jwt
+	fx.Provide(func(conn *sqlx.DB, logger *zap.Logger) impl.TokenDenylist {
+		return NewAuthRepository(conn, logger)
+	}),
+	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner, mailer impl.Mailer, hasher impl.PasswordHasher, policy impl.PasswordPolicy) error {
 		// loads repository
 		var repo = NewAuthRepository(conn, logger)
 		// loads service
-		var svc = NewAuthService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+		var accounts = AccountOptions{
+			PasswordResetTTL:         time.Duration(cfg.PasswordResetTTL) * time.Second,
+			EmailVerificationTTL:     time.Duration(cfg.EmailVerificationTTL) * time.Second,
+			RequireEmailVerification: cfg.RequireEmailVerification,
+			AppURL:                   cfg.AppURL,
+			SignInMaxFailures:        cfg.SignInMaxFailures,
+			SignInMaxIPFailures:      cfg.SignInMaxIPFailures,
+			SignInFailureDelay:       time.Duration(cfg.SignInFailureDelay) * time.Second,
+			SignInLockout:            time.Duration(cfg.SignInLockout) * time.Second,
+			MFAIssuer:                cfg.MFAIssuer,
+			MFAChallengeTTL:          time.Duration(cfg.MFAChallengeTTL) * time.Second,
+			MFARecoveryCodes:         cfg.MFARecoveryCodes,
+		}
+		var svc = NewTracedAuthService(NewAuthService(repo, mailer, hasher, policy, signer, logger, time.Duration(cfg.ContextTimeout)*time.Second, time.Duration(cfg.RefreshTokenTTL)*time.Second, accounts))
 		// loads handlers
-		NewAuthHandlers(r, logger, svc, render, validate)
+		NewAuthHandlers(r, logger, svc, render, validate, denylist, signer)
 		return nil
 	}),
 )
@@ -1,21 +1,80 @@
 package auth
 
-import "errors"
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+	"time"
+)
 
 // Entity Errors
 var (
 	// User
-	ErrPrepapareQuery    = errors.New("Falló al preparar la consulta")
-	ErrFieldWithSpaces   = errors.New("username and password can't have spaces")
-	ErrShortPassword     = errors.New("password shorter than 6 characters")
-	ErrInvalidPassword   = errors.New("invalid password")
-	ErrMissingPassword   = errors.New("El campo password es requerido")
-	ErrMissingEmail      = errors.New("El campo email es requerido")
-	ErrInvalidEmail      = errors.New("El campo email es invalido")
-	ErrUserNotFound      = errors.New("Usuario no existe")
-	ErrUserExist         = errors.New("Ya existe una cuenta con esta email")
-	ErrFailInsertUser    = errors.New("Falló al registrar nuevo usuario")
-	ErrServiceAuth       = errors.New("Falló el servicio auth")
-	ErrBeginTransaction  = errors.New("Error al iniciar la transacción")
-	ErrCommitTransaction = errors.New("Error al realizar el commit")
+	ErrPrepapareQuery     = errors.New("Falló al preparar la consulta")
+	ErrFieldWithSpaces    = errors.New("username and password can't have spaces")
+	ErrShortPassword      = errors.New("password shorter than 6 characters")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrInvalidCredentials = errors.New("El email y/o contraseña son erroneos")
+	ErrMissingPassword    = errors.New("El campo password es requerido")
+	ErrMissingEmail       = errors.New("El campo email es requerido")
+	ErrInvalidEmail       = errors.New("El campo email es invalido")
+	ErrUserNotFound       = errors.New("Usuario no existe")
+	ErrUserExist          = errors.New("Ya existe una cuenta con esta email")
+	ErrFailInsertUser     = errors.New("Falló al registrar nuevo usuario")
+	ErrServiceAuth        = errors.New("Falló el servicio auth")
+	ErrBeginTransaction   = errors.New("Error al iniciar la transacción")
+	ErrCommitTransaction  = errors.New("Error al realizar el commit")
+	ErrExecuteStatement   = errors.New("Falló al ejecutar la consulta")
+	ErrInvalidRole        = errors.New("El rol es invalido")
+	// Tokens
+	ErrInvalidRefreshToken = errors.New("El refresh token es invalido")
+	ErrRefreshTokenExpired = errors.New("El refresh token ha expirado")
+	ErrRefreshTokenReused  = errors.New("El refresh token ya fue utilizado, la sesión ha sido revocada")
+	ErrGenerateToken       = errors.New("Falló al generar el token")
+	ErrRevokeToken         = errors.New("Falló al revocar el token")
+	// Password reset and email verification
+	ErrInvalidToken     = errors.New("El token es invalido o ya fue utilizado")
+	ErrTokenExpired     = errors.New("El token ha expirado")
+	ErrEmailNotVerified = errors.New("El email de la cuenta no ha sido verificado")
+	// Lockout
+	ErrTooManyAttempts = errors.New("Demasiados intentos fallidos de inicio de sesión, intente más tarde")
+	// MFA
+	ErrInvalidMFACode    = errors.New("El código de verificación es invalido")
+	ErrMFAAlreadyEnabled = errors.New("La verificación en dos pasos ya está activada")
+	ErrMFANotEnrolled    = errors.New("La verificación en dos pasos no está activada")
+	ErrMFARequired       = errors.New("El rol del usuario requiere la verificación en dos pasos")
 )
+
+// LockedError the sign in is rejected until RetryAfter passes
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// problems returned to the clients, the errors without entry are internal errors
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrInvalidCredentials, Code: "invalid_credentials", Status: http.StatusUnauthorized},
+		problem.Entry{Err: ErrUserNotFound, Code: "user_not_found", Status: http.StatusNotFound},
+		problem.Entry{Err: ErrUserExist, Code: "user_exists", Status: http.StatusConflict},
+		problem.Entry{Err: ErrInvalidRole, Code: "invalid_role", Status: http.StatusBadRequest},
+		problem.Entry{Err: ErrInvalidRefreshToken, Code: "invalid_refresh_token", Status: http.StatusUnauthorized},
+		problem.Entry{Err: ErrRefreshTokenExpired, Code: "refresh_token_expired", Status: http.StatusUnauthorized},
+		problem.Entry{Err: ErrRefreshTokenReused, Code: "refresh_token_reused", Status: http.StatusUnauthorized},
+		problem.Entry{Err: ErrInvalidToken, Code: "invalid_token", Status: http.StatusBadRequest},
+		problem.Entry{Err: ErrTokenExpired, Code: "token_expired", Status: http.StatusBadRequest},
+		problem.Entry{Err: ErrEmailNotVerified, Code: "email_not_verified", Status: http.StatusForbidden},
+		problem.Entry{Err: ErrTooManyAttempts, Code: "too_many_attempts", Status: http.StatusTooManyRequests},
+		problem.Entry{Err: ErrInvalidMFACode, Code: "invalid_mfa_code", Status: http.StatusUnauthorized},
+		problem.Entry{Err: ErrMFAAlreadyEnabled, Code: "mfa_already_enabled", Status: http.StatusConflict},
+		problem.Entry{Err: ErrMFANotEnrolled, Code: "mfa_not_enrolled", Status: http.StatusConflict},
+		problem.Entry{Err: ErrMFARequired, Code: "mfa_required", Status: http.StatusForbidden},
+	)
+}
@@ -3,19 +3,27 @@ package auth
 import (
 	"errors"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/problem"
 	httpUtils "kiramishima/ionix/internal/pkg/utils"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 var _ impl.AuthHandlers = (*handler)(nil)
 
 // NewAuthHandlers creates an instance of auth handlers
-func NewAuthHandlers(r *chi.Mux, logger *zap.Logger, s impl.AuthService, render *render.Render, validate *validator.Validate) {
+func NewAuthHandlers(r *chi.Mux, logger *zap.Logger, s impl.AuthService, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) {
 	handler := &handler{
 		logger:   logger,
 		service:  s,
@@ -26,6 +34,30 @@ func NewAuthHandlers(r *chi.Mux, logger *zap.Logger, s impl.AuthService, render
 	r.Route("/v1/auth", func(r chi.Router) {
 		r.Post("/sign-in", handler.LoginHandler)
 		r.Post("/sign-up", handler.SignUpHandler)
+		r.Post("/refresh", handler.RefreshHandler)
+		// the routes that send mails have a lower limit
+		r.With(httprate.LimitByIP(5, 1*time.Minute)).Post("/forgot-password", handler.ForgotPasswordHandler)
+		r.With(httprate.LimitByIP(5, 1*time.Minute)).Post("/resend-verification", handler.ResendVerificationHandler)
+		r.Post("/reset-password", handler.ResetPasswordHandler)
+		r.Post("/verify-email", handler.VerifyEmailHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist)).
+			Post("/logout", handler.LogoutHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermUsersManage)).
+			Put("/users/{id}/role", handler.UpdateRoleHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermUsersManage)).
+			Delete("/users/{id}/lockout", handler.UnlockHandler)
+		// second factor, the verify route finishes the sign in so it has no access token
+		r.Post("/mfa/verify", handler.VerifyMFAHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist)).
+			Post("/mfa/enroll", handler.EnrollMFAHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist)).
+			Post("/mfa/confirm", handler.ConfirmMFAHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist)).
+			Post("/mfa/disable", handler.DisableMFAHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermUsersManage)).
+			Delete("/users/{id}/mfa", handler.ResetMFAHandler)
+		r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermUsersManage)).
+			Put("/roles/{role}/mfa", handler.UpdateRoleMFAHandler)
 	})
 }
 
@@ -43,7 +75,7 @@ func (h handler) SignUpHandler(w http.ResponseWriter, req *http.Request) {
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "La petición es invalidad o esta mal formateada"})
+		problem.Write(w, req, err)
 		return
 	}
 	h.logger.Info("Form", zap.Any("data", form))
@@ -51,16 +83,7 @@ func (h handler) SignUpHandler(w http.ResponseWriter, req *http.Request) {
 	err = form.Validate(h.validate)
 	if err != nil {
 		h.logger.Error(err.Error())
-		switch err.Error() {
-		case "Password. This field is required":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrMissingPassword.Error()})
-		case "Email. This field is required":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrMissingEmail.Error()})
-		case "Email. Bad email format":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidEmail.Error()})
-		default:
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: err.Error()})
-		}
+		problem.Write(w, req, err)
 		return
 	}
 	ctx := req.Context()
@@ -68,25 +91,13 @@ func (h handler) SignUpHandler(w http.ResponseWriter, req *http.Request) {
 	err = h.service.SignUp(ctx, form)
 	if err != nil {
 		// h.logger.Error(err.Error())
-
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: err.Error()})
-		default:
-			if errors.Is(err, ErrServiceAuth) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else if errors.Is(err, ErrUserExist) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ya existe una cuenta registrada con este correo"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+		problem.Write(w, req, err)
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Registro exitoso."}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.signed_up")}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Internal Server Error"})
+		problem.Write(w, req, err)
 		return
 	}
 }
@@ -98,7 +109,7 @@ func (h handler) LoginHandler(w http.ResponseWriter, req *http.Request) {
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "La petición es invalida o esta mal formateada"})
+		problem.Write(w, req, err)
 		return
 	}
 
@@ -107,46 +118,512 @@ func (h handler) LoginHandler(w http.ResponseWriter, req *http.Request) {
 	err = form.Validate(h.validate)
 	if err != nil {
 		h.logger.Error(err.Error())
-		switch err.Error() {
-		case "Password. This field is required":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrMissingPassword.Error()})
-		case "Email. This field is required":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrMissingEmail.Error()})
-		case "Email. Bad email format":
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidEmail.Error()})
-		default:
-			_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: err.Error()})
-		}
+		problem.Write(w, req, err)
 		return
 	}
 	ctx := req.Context()
 
 	// Service
-	resp, err := h.service.SignIn(ctx, form)
-	if err != nil {
-
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: err.Error()})
-		default:
-
-			if errors.Is(err, ErrUserNotFound) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "El email y/o contraseña son erroneos"})
-			} else if errors.Is(err, ErrInvalidPassword) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Contraseña invalida"})
-			} else if errors.Is(err, ErrServiceAuth) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
+	resp, err := h.service.SignIn(ctx, form, clientIP(req))
+	if err != nil {
+		// the client must not know if the account exists
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrInvalidPassword) {
+			err = ErrInvalidCredentials
 		}
+		setRetryAfter(w, err)
+		problem.Write(w, req, err)
+		return
+	}
+
+	// response
+	if err := h.response.JSON(w, http.StatusOK, resp); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) RefreshHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.RefreshTokenForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	resp, err := h.service.Refresh(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
 
 	// response
 	if err := h.response.JSON(w, http.StatusOK, resp); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Internal Server Error"})
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) LogoutHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.RefreshTokenForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// access token of the session
+	token, _, err := jwtauth.FromContext(ctx)
+	if err != nil || token == nil {
+		problem.Write(w, req, problem.ErrUnauthorized)
+		return
+	}
+	userID, _ := strconv.Atoi(token.Subject())
+
+	// Service
+	err = h.service.Logout(ctx, userID, token.JwtID(), token.Expiration(), form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.logged_out")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) UpdateRoleHandler(w http.ResponseWriter, req *http.Request) {
+	UserID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.RoleForm{}
+
+	err = httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.UpdateRole(ctx, UserID, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.role_updated")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ForgotPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.EmailForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.ForgotPassword(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusAccepted, models.Message{Message: i18n.T(req.Context(), "auth.password_reset_requested")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ResetPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.ResetPasswordForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.ResetPassword(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.password_reset")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) VerifyEmailHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.VerifyEmailForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.VerifyEmail(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.email_verified")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ResendVerificationHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.EmailForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
 		return
 	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.ResendVerification(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusAccepted, models.Message{Message: i18n.T(req.Context(), "auth.verification_sent")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) UnlockHandler(w http.ResponseWriter, req *http.Request) {
+	UserID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.Unlock(ctx, UserID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.user_unlocked")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) VerifyMFAHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.MFAVerifyForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	resp, err := h.service.VerifyMFA(ctx, form, clientIP(req))
+	if err != nil {
+		setRetryAfter(w, err)
+		problem.Write(w, req, err)
+		return
+	}
+
+	// response
+	if err := h.response.JSON(w, http.StatusOK, resp); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) EnrollMFAHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := sessionUserID(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	resp, err := h.service.EnrollMFA(ctx, userID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	// response
+	if err := h.response.JSON(w, http.StatusOK, resp); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ConfirmMFAHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := sessionUserID(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.MFACodeForm{}
+
+	err = httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.ConfirmMFA(ctx, userID, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.mfa_enabled")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) DisableMFAHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := sessionUserID(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.MFACodeForm{}
+
+	err = httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.DisableMFA(ctx, userID, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.mfa_disabled")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ResetMFAHandler(w http.ResponseWriter, req *http.Request) {
+	UserID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.ResetMFA(ctx, UserID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.mfa_reset")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) UpdateRoleMFAHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.RoleMFAForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate data
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	ctx := req.Context()
+
+	// Service
+	err = h.service.UpdateRoleMFA(ctx, chi.URLParam(req, "role"), form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "auth.role_mfa_updated")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+// sessionUserID user id in the subject of the access token of the request
+func sessionUserID(req *http.Request) (int, error) {
+	token, _, err := jwtauth.FromContext(req.Context())
+	if err != nil || token == nil {
+		return 0, problem.ErrUnauthorized
+	}
+	userID, err := strconv.Atoi(token.Subject())
+	if err != nil {
+		return 0, problem.ErrUnauthorized
+	}
+	return userID, nil
+}
+
+// setRetryAfter tells a locked client when it can sign in again
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	}
+}
+
+// clientIP address of the client without port, realip.Middleware only takes it from the headers of TRUSTED_PROXIES
+func clientIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
 }
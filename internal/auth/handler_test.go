@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/unrolled/render"
 	"go.uber.org/mock/gomock"
@@ -15,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHandler_LoginHandler(t *testing.T) {
@@ -30,7 +30,7 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "giny@mail.com", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
-					SignIn(gomock.Any(), gomock.Any()).
+					SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(&models.AuthResponse{AccessToken: "123456"}, nil)
 			},
@@ -45,14 +45,14 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "giny@mail.com", Password: ""},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo password es requerido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"password: This field is required","instance":"/v1/auth/sign-in","code":"validation_failed","errors":[{"field":"password","message":"This field is required","rule":"required"}]}`+"\n")
 			},
 		},
 		"Bad Email format": {
@@ -60,14 +60,14 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "giny_mail.com", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo email es invalido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"email: Bad email format","instance":"/v1/auth/sign-in","code":"validation_failed","errors":[{"field":"email","message":"Bad email format","rule":"email"}]}`+"\n")
 			},
 		},
 		"Email required": {
@@ -75,14 +75,14 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo email es requerido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"email: This field is required","instance":"/v1/auth/sign-in","code":"validation_failed","errors":[{"field":"email","message":"This field is required","rule":"required"}]}`+"\n")
 			},
 		},
 		"User not found": {
@@ -90,13 +90,13 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "giny@mail.com", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
-					SignIn(gomock.Any(), gomock.Any()).
+					SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(nil, ErrUserNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
-				assert.Equal(t, recorder.Body.String(), `{"error":"El email y/o contraseña son erroneos"}`)
+				assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/invalid_credentials","title":"Credenciales invalidas","status":401,"detail":"El email y/o contraseña son erroneos","instance":"/v1/auth/sign-in","code":"invalid_credentials"}`+"\n")
 				t.Log(recorder.Body.String())
 			},
 		},
@@ -105,28 +105,43 @@ func TestHandler_LoginHandler(t *testing.T) {
 			form: &models.AuthForm{Email: "giny@mail.com", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
-					SignIn(gomock.Any(), gomock.Any()).
+					SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(nil, ErrInvalidPassword)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
-				assert.Equal(t, recorder.Body.String(), `{"error":"Contraseña invalida"}`)
+				assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/invalid_credentials","title":"Credenciales invalidas","status":401,"detail":"El email y/o contraseña son erroneos","instance":"/v1/auth/sign-in","code":"invalid_credentials"}`+"\n")
 				t.Log(recorder.Body.String())
 			},
 		},
+		"Locked": {
+			ID:   7,
+			form: &models.AuthForm{Email: "giny@mail.com", Password: "123456"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().
+					SignIn(gomock.Any(), gomock.Any(), "192.0.2.1").
+					Times(1).
+					Return(nil, &LockedError{RetryAfter: 1500 * time.Millisecond})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/too_many_attempts","title":"Demasiados intentos","status":429,"detail":"Demasiados intentos fallidos de inicio de sesión, intente más tarde","instance":"/v1/auth/sign-in","code":"too_many_attempts"}`+"\n")
+			},
+		},
 		"General service": {
 			ID:   6,
 			form: &models.AuthForm{Email: "giny@mail.com", Password: "123456"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
-					SignIn(gomock.Any(), gomock.Any()).
+					SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(nil, ErrServiceAuth)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
-				assert.Equal(t, recorder.Body.String(), `{"error":"Ocurrio un error por favor intente más tarde"}`)
+				assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/internal_error","title":"Error interno","status":500,"instance":"/v1/auth/sign-in","code":"internal_error"}`+"\n")
 				t.Log(recorder.Body.String())
 			},
 		},
@@ -156,10 +171,10 @@ func TestHandler_LoginHandler(t *testing.T) {
 
 			router := chi.NewRouter()
 			logger := zap.NewNop()
-			validate := validator.New()
+			validate := models.NewValidator()
 			r := render.New()
 
-			NewAuthHandlers(router, logger, uc, r, validate)
+			NewAuthHandlers(router, logger, uc, r, validate, mocks.NewMockTokenDenylist(ctrl), testSigner)
 			router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -176,7 +191,7 @@ func TestHandler_SignUpHandler(t *testing.T) {
 	}{
 		"OK": {
 			ID:   1,
-			form: &models.RegisterForm{Email: "giny@mail.com", Password: "123456", Name: "Jhon"},
+			form: &models.RegisterForm{Email: "giny@mail.com", Password: "Secret123", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
 					SignUp(gomock.Any(), gomock.Any()).
@@ -194,49 +209,59 @@ func TestHandler_SignUpHandler(t *testing.T) {
 			form: &models.RegisterForm{Email: "giny@mail.com", Password: "", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo password es requerido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"password: This field is required","instance":"/v1/auth/sign-up","code":"validation_failed","errors":[{"field":"password","message":"This field is required","rule":"required"}]}`+"\n")
 			},
 		},
 		"Bad Email format": {
 			ID:   3,
-			form: &models.RegisterForm{Email: "giny[at]mail.com", Password: "123456", Name: "Jhon"},
+			form: &models.RegisterForm{Email: "giny[at]mail.com", Password: "Secret123", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo email es invalido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"email: Bad email format","instance":"/v1/auth/sign-up","code":"validation_failed","errors":[{"field":"email","message":"Bad email format","rule":"email"}]}`+"\n")
 			},
 		},
 		"Email required": {
 			ID:   4,
-			form: &models.RegisterForm{Email: "", Password: "123456", Name: "Jhon"},
+			form: &models.RegisterForm{Email: "", Password: "Secret123", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				/*uc.EXPECT().
-				SignIn(gomock.Any(), gomock.Any()).
+				SignIn(gomock.Any(), gomock.Any(), gomock.Any()).
 				Times(1).
 				Return(nil, ErrMissingPassword)*/
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				t.Log(recorder.Body.String())
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"email: This field is required","instance":"/v1/auth/sign-up","code":"validation_failed","errors":[{"field":"email","message":"This field is required","rule":"required"}]}`+"\n")
+			},
+		},
+		"Weak password": {
+			ID:         7,
+			form:       &models.RegisterForm{Email: "giny", Password: "secret123", Name: "Jhon"},
+			buildStubs: func(uc *mocks.MockAuthService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				t.Log(recorder.Body.String())
-				assert.Equal(t, recorder.Body.String(), `{"error":"El campo email es requerido"}`)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"email: Bad email format; password: The password needs an uppercase letter, a lowercase letter and a digit","instance":"/v1/auth/sign-up","code":"validation_failed","errors":[{"field":"email","message":"Bad email format","rule":"email"},{"field":"password","message":"The password needs an uppercase letter, a lowercase letter and a digit","rule":"password"}]}`+"\n")
 			},
 		},
 		"User exists": {
 			ID:   5,
-			form: &models.RegisterForm{Email: "giny@mail.com", Password: "123456", Name: "Jhon"},
+			form: &models.RegisterForm{Email: "giny@mail.com", Password: "Secret123", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
 					SignUp(gomock.Any(), gomock.Any()).
@@ -244,14 +269,14 @@ func TestHandler_SignUpHandler(t *testing.T) {
 					Return(ErrUserExist)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
-				assert.Equal(t, recorder.Body.String(), `{"error":"Ya existe una cuenta registrada con este correo"}`)
+				assert.Equal(t, http.StatusConflict, recorder.Code)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/user_exists","title":"La cuenta ya existe","status":409,"detail":"Ya existe una cuenta con esta email","instance":"/v1/auth/sign-up","code":"user_exists"}`+"\n")
 				t.Log(recorder.Body.String())
 			},
 		},
 		"General service": {
 			ID:   6,
-			form: &models.RegisterForm{Email: "giny@mail.com", Password: "123456", Name: "Jhon"},
+			form: &models.RegisterForm{Email: "giny@mail.com", Password: "Secret123", Name: "Jhon"},
 			buildStubs: func(uc *mocks.MockAuthService) {
 				uc.EXPECT().
 					SignUp(gomock.Any(), gomock.Any()).
@@ -259,8 +284,8 @@ func TestHandler_SignUpHandler(t *testing.T) {
 					Return(ErrServiceAuth)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				assert.Equal(t, http.StatusBadRequest, recorder.Code)
-				assert.Equal(t, recorder.Body.String(), `{"error":"Ocurrio un error por favor intente más tarde"}`)
+				assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+				assert.Equal(t, recorder.Body.String(), `{"type":"/problems/internal_error","title":"Error interno","status":500,"instance":"/v1/auth/sign-up","code":"internal_error"}`+"\n")
 				t.Log(recorder.Body.String())
 			},
 		},
@@ -290,12 +315,173 @@ func TestHandler_SignUpHandler(t *testing.T) {
 
 			router := chi.NewRouter()
 			logger := zap.NewNop()
-			validate := validator.New()
+			validate := models.NewValidator()
 			r := render.New()
 
-			NewAuthHandlers(router, logger, uc, r, validate)
+			NewAuthHandlers(router, logger, uc, r, validate, mocks.NewMockTokenDenylist(ctrl), testSigner)
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_ResetPasswordHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		form          *models.ResetPasswordForm
+		buildStubs    func(uc *mocks.MockAuthService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Success": {
+			form: &models.ResetPasswordForm{Token: "reset-token", Password: "Secret123"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().
+					ResetPassword(gomock.Any(), &models.ResetPasswordForm{Token: "reset-token", Password: "Secret123"}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `{"message":"La contraseña se ha restablecido de manera exitosa"}`, recorder.Body.String())
+			},
+		},
+		"Weak password": {
+			form: &models.ResetPasswordForm{Token: "reset-token", Password: "secret123"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().ResetPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		"Expired token": {
+			form: &models.ResetPasswordForm{Token: "reset-token", Password: "Secret123"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(ErrTokenExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, `{"type":"/problems/token_expired","title":"Token expirado","status":400,"detail":"El token ha expirado, solicite uno nuevo","instance":"/v1/auth/reset-password","code":"token_expired"}`+"\n", recorder.Body.String())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockAuthService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			marshalled, err := json.Marshal(tc.form)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/reset-password", bytes.NewReader(marshalled))
+
+			router := chi.NewRouter()
+			NewAuthHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), mocks.NewMockTokenDenylist(ctrl), testSigner)
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_VerifyMFAHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		form          *models.MFAVerifyForm
+		buildStubs    func(uc *mocks.MockAuthService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Success": {
+			form: &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "287082"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().
+					VerifyMFA(gomock.Any(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "287082"}, "192.0.2.1").
+					Times(1).
+					Return(&models.AuthResponse{AccessToken: "access-token", RefreshToken: "refresh-token", ExpiresIn: 60}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `{"access_token":"access-token","refresh_token":"refresh-token","expires_in":60}`, recorder.Body.String())
+			},
+		},
+		"Missing code": {
+			form: &models.MFAVerifyForm{MFAToken: "mfa-token"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().VerifyMFA(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		"Invalid code": {
+			form: &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "000000"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().VerifyMFA(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(nil, ErrInvalidMFACode)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"code":"invalid_mfa_code"`)
+			},
+		},
+		"Locked": {
+			form: &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "000000"},
+			buildStubs: func(uc *mocks.MockAuthService) {
+				uc.EXPECT().VerifyMFA(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(nil, &LockedError{RetryAfter: 2 * time.Second})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockAuthService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			marshalled, err := json.Marshal(tc.form)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/mfa/verify", bytes.NewReader(marshalled))
+
+			router := chi.NewRouter()
+			NewAuthHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), mocks.NewMockTokenDenylist(ctrl), testSigner)
 			router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
 	}
 }
+
+func TestHandler_ForgotPasswordHandler(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uc := mocks.NewMockAuthService(ctrl)
+	uc.EXPECT().ForgotPassword(gomock.Any(), &models.EmailForm{Email: "kratos@gmail.com"}).Times(5).Return(nil)
+
+	router := chi.NewRouter()
+	NewAuthHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), mocks.NewMockTokenDenylist(ctrl), testSigner)
+
+	for i := 0; i < 5; i++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/auth/forgot-password", bytes.NewBufferString(`{"email":"kratos@gmail.com"}`)))
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		assert.Equal(t, `{"message":"Si la cuenta existe se le ha enviado un correo para restablecer la contraseña"}`, recorder.Body.String())
+	}
+
+	// the requests that send mails are limited by IP
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/auth/forgot-password", bytes.NewBufferString(`{"email":"kratos@gmail.com"}`)))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
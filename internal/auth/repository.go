@@ -8,8 +8,11 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"time"
 )
 
 // implement auth repository
@@ -30,10 +33,17 @@ func NewAuthRepository(conn *sqlx.DB, logger *zap.Logger) *repository {
 }
 
 func (repo repository) FindUserByCredentials(ctx context.Context, form *models.AuthForm) (*models.User, error) {
+	return repo.FindUserByEmail(ctx, form.Email)
+}
+
+// FindUserByEmail finds the account of the email with its password hash
+func (repo repository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var query = `SELECT id,
        	   name,
 		   email,
 		   password,
+		   role,
+		   email_verified_at,
 		   created_at,
 		   updated_at
 	FROM users
@@ -47,12 +57,14 @@ func (repo repository) FindUserByCredentials(ctx context.Context, form *models.A
 
 	u := &models.User{}
 
-	row := stmt.QueryRowContext(ctx, form.Email)
+	row := stmt.QueryRowContext(ctx, email)
 	var createdAt sql.NullTime
 	var updatedAt sql.NullTime
-	err = row.Scan(&u.ID, &u.Name, &u.Email, &u.Password, &createdAt, &updatedAt)
+	err = row.Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Role, &u.EmailVerifiedAt, &createdAt, &updatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
 	}
 
 	if createdAt.Valid {
@@ -65,30 +77,131 @@ func (repo repository) FindUserByCredentials(ctx context.Context, form *models.A
 	return u, nil
 }
 
+func (repo repository) FindUserByID(ctx context.Context, userId int) (*models.User, error) {
+	var query = `SELECT id, name, email, role FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, ErrPrepapareQuery
+	}
+	defer stmt.Close()
+
+	u := &models.User{}
+	var name sql.NullString
+	err = stmt.QueryRowContext(ctx, userId).Scan(&u.ID, &name, &u.Email, &u.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+	u.Name = name.String
+
+	return u, nil
+}
+
+func (repo repository) UpdateUserRole(ctx context.Context, userId int, role models.Role) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer tx.Rollback()
+
+	var before auditUser
+	err = tx.QueryRowxContext(ctx, `SELECT role FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userId).Scan(&before.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	var query = `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, role, userId); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, userId, &before, &auditUser{Role: role}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// auditUser state of a user stored in the audit log, the password is never recorded
+type auditUser struct {
+	Name  string      `json:"name,omitempty"`
+	Email string      `json:"email,omitempty"`
+	Role  models.Role `json:"role"`
+}
+
+const (
+	mfaDisabled = "disabled"
+	mfaPending  = "pending"
+	mfaEnabled  = "enabled"
+)
+
+// auditMFA second factor of a user stored in the audit log, the secret and the codes are never recorded
+type auditMFA struct {
+	MFA           string `json:"mfa" db:"mfa"`
+	RecoveryCodes int    `json:"recovery_codes" db:"recovery_codes"`
+}
+
+// passwordReplaced value of the password in the audit log when its hash is replaced
+const passwordReplaced = "replaced"
+
+// auditCredentials password and email verification of a user stored in the audit log, the password
+// only tells that its hash was replaced
+type auditCredentials struct {
+	Password      string `json:"password,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// auditSignIn failed sign ins of the email of a user stored in the audit log
+type auditSignIn struct {
+	SignInFailures int `json:"sign_in_failures"`
+}
+
+// auditRoleMFA whether the users of a role have to sign in with a second factor
+type auditRoleMFA struct {
+	MFARequired bool `json:"mfa_required"`
+}
+
 func (repo repository) CreateAccount(ctx context.Context, form *models.RegisterForm) error {
 	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
-		repo.log.Info(err.Error())
+		tracing.Logger(ctx, repo.log).Info(err.Error())
 		return ErrBeginTransaction
 	}
 	defer tx.Rollback()
 
 	// Prepare STMT
-	var query = `INSERT INTO users(name, email, password) VALUES($1, $2, $3)`
+	var query = `INSERT INTO users(name, email, password) VALUES($1, $2, $3) RETURNING id`
 	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, form.Name, form.Email, form.Password)
+	var userId int
+	err = stmt.QueryRowxContext(ctx, form.Name, form.Email, form.Password).Scan(&userId)
 
 	if err != nil {
-		repo.log.Info(err.Error())
+		tracing.Logger(ctx, repo.log).Info(err.Error())
 		// log.Println("Code 2 ", errors.Is(err, my.ErrDupeKey))
 		pgErr, ok := err.(*pgconn.PgError)
 		if ok {
-			repo.log.Info(pgErr.Code)
+			tracing.Logger(ctx, repo.log).Info(pgErr.Code)
 			if pgErr.Code == "23505" {
 				return ErrUserExist
 			} else {
@@ -99,8 +212,713 @@ func (repo repository) CreateAccount(ctx context.Context, form *models.RegisterF
 		}
 
 	}
+
+	var after = &auditUser{Name: form.Name, Email: form.Email, Role: models.RoleReadOnly}
+	if err = audit.Record(ctx, tx, audit.ActionCreate, audit.EntityUser, userId, nil, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+func (repo repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	var query = `INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES($1, $2, $3, $4)`
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	return nil
+}
+
+// RotateRefreshToken revokes the presented token and stores next in the same family.
+// A token that was already revoked means it was stolen or replayed, the whole family is revoked
+func (repo repository) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var current = &models.RefreshToken{}
+	err = tx.QueryRowxContext(ctx, `SELECT id, user_id, family_id, token_hash, expires_at, revoked_at
+	FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, tokenHash).StructScan(current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidRefreshToken
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if current.RevokedAt != nil {
+		if err = revokeFamily(ctx, tx, current.FamilyID); err != nil {
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return ErrCommitTransaction
+		}
+		return ErrRefreshTokenReused
+	}
+	if current.ExpiresAt.Before(time.Now()) {
+		return ErrRefreshTokenExpired
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1`, current.ID)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	next.UserID = current.UserID
+	next.FamilyID = current.FamilyID
+	_, err = tx.ExecContext(ctx, `INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES($1, $2, $3, $4)`,
+		next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// RevokeRefreshToken revokes the family of the token owned by the user
+func (repo repository) RevokeRefreshToken(ctx context.Context, tokenHash string, userId int) error {
+	var query = `UPDATE refresh_tokens SET revoked_at = NOW()
+	WHERE revoked_at IS NULL AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2)`
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, tokenHash, userId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrRevokeToken
+	}
+	return nil
+}
+
+// RevokeAccessToken adds the jti to the denylist until the token expires
+func (repo repository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	// expired tokens are rejected by the verifier, there is no need to keep them
+	if _, err = tx.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrRevokeToken
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO revoked_tokens(jti, expires_at) VALUES($1, $2) ON CONFLICT (jti) DO NOTHING`, jti, expiresAt)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrRevokeToken
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+func (repo repository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := repo.db.QueryRowxContext(ctx, `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return false, ErrExecuteStatement
+	}
+	return revoked, nil
+}
+
+// CreateUserToken stores the token sent by mail, the previous tokens of the user for the same purpose
+// stop working so only the last mail is valid
+func (repo repository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		token.UserID, token.Purpose)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO user_tokens(user_id, purpose, token_hash, expires_at) VALUES($1, $2, $3, $4)`,
+		token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// ResetPassword uses the password reset token to replace the password, the refresh tokens of the user
+// are revoked and the email is verified because the user received the mail
+func (repo repository) ResetPassword(ctx context.Context, tokenHash string, password string) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	token, err := useToken(ctx, tx, tokenHash, models.TokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	before, err := repo.findAuditCredentials(ctx, tx, token.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET password = $1, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $2`,
+		password, token.UserID)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, token.UserID)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrRevokeToken
+	}
+
+	// the link of the mail has no access token, the user is the actor
+	var after = &auditCredentials{Password: passwordReplaced, EmailVerified: true}
+	if err = audit.Record(audit.WithActor(ctx, int64(token.UserID)), tx, audit.ActionUpdate, audit.EntityUser, token.UserID, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// VerifyEmail uses the email verification token to mark the email of the user as verified
+func (repo repository) VerifyEmail(ctx context.Context, tokenHash string) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	token, err := useToken(ctx, tx, tokenHash, models.TokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	before, err := repo.findAuditCredentials(ctx, tx, token.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`, token.UserID)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	// the link of the mail has no access token, the user is the actor
+	var after = &auditCredentials{EmailVerified: true}
+	if err = audit.Record(audit.WithActor(ctx, int64(token.UserID)), tx, audit.ActionUpdate, audit.EntityUser, token.UserID, before, after); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrCommitTransaction
 	}
 	return nil
 }
+
+// findAuditCredentials locks the user and reads whether its email is verified for the audit log
+func (repo repository) findAuditCredentials(ctx context.Context, tx *sqlx.Tx, userId int32) (*auditCredentials, error) {
+	var state = &auditCredentials{}
+	err := tx.QueryRowxContext(ctx, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE`, userId).Scan(&state.EmailVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrExecuteStatement
+	}
+	return state, nil
+}
+
+// useToken marks the token as used, a token can be used once and before it expires
+func useToken(ctx context.Context, tx *sqlx.Tx, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token = &models.UserToken{}
+	err := tx.QueryRowxContext(ctx, `SELECT id, user_id, purpose, token_hash, expires_at, used_at
+	FROM user_tokens WHERE token_hash = $1 AND purpose = $2 FOR UPDATE`, tokenHash, purpose).StructScan(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+
+	if err = validToken(token); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = NOW() WHERE id = $1`, token.ID); err != nil {
+		return nil, ErrExecuteStatement
+	}
+	return token, nil
+}
+
+// validToken rejects the tokens that were used or expired
+func validToken(token *models.UserToken) error {
+	if token.UsedAt != nil {
+		return ErrInvalidToken
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// FindUserToken finds a token that can still be used, it is not marked as used
+func (repo repository) FindUserToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token = &models.UserToken{}
+	err := repo.db.QueryRowxContext(ctx, `SELECT id, user_id, purpose, token_hash, expires_at, used_at
+	FROM user_tokens WHERE token_hash = $1 AND purpose = $2`, tokenHash, purpose).StructScan(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrExecuteStatement
+	}
+
+	if err = validToken(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UseUserToken marks the token as used, only one of the requests that use the same token succeeds
+func (repo repository) UseUserToken(ctx context.Context, tokenId int64) error {
+	res, err := repo.db.ExecContext(ctx, `UPDATE user_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, tokenId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func revokeFamily(ctx context.Context, tx *sqlx.Tx, familyId string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyId)
+	if err != nil {
+		return ErrRevokeToken
+	}
+	return nil
+}
+
+// FindSignInFailures failed sign ins of the email and the IP
+func (repo repository) FindSignInFailures(ctx context.Context, email string, ip string) ([]*models.SignInFailure, error) {
+	var failures []*models.SignInFailure
+	err := repo.db.SelectContext(ctx, &failures, `SELECT scope, subject, failures, last_failure_at
+	FROM sign_in_failures WHERE (scope = $1 AND subject = $2) OR (scope = $3 AND subject = $4)`,
+		models.SignInScopeEmail, email, models.SignInScopeIP, ip)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrExecuteStatement
+	}
+	return failures, nil
+}
+
+// RecordSignInFailure counts a failed sign in of the email and the IP, the counts whose last failure
+// is older than window start again
+func (repo repository) RecordSignInFailure(ctx context.Context, email string, ip string, window time.Duration) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	// the forgotten counts are not needed anymore
+	_, err = tx.ExecContext(ctx, `DELETE FROM sign_in_failures WHERE last_failure_at < NOW() - make_interval(secs => $1)`, window.Seconds())
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sign_in_failures(scope, subject, failures, last_failure_at) VALUES($1, $2, 1, NOW()), ($3, $4, 1, NOW())
+	ON CONFLICT (scope, subject) DO UPDATE SET failures = sign_in_failures.failures + 1, last_failure_at = NOW()`,
+		models.SignInScopeEmail, email, models.SignInScopeIP, ip)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// ClearSignInFailures forgets the failed sign ins of the email, the failures of the IPs are kept
+func (repo repository) ClearSignInFailures(ctx context.Context, email string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM sign_in_failures WHERE scope = $1 AND subject = $2`, models.SignInScopeEmail, email)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	return nil
+}
+
+// UnlockAccount forgets the failed sign ins of the email of the user like ClearSignInFailures, the
+// admin that unlocks it is recorded in the audit log
+func (repo repository) UnlockAccount(ctx context.Context, userId int, email string) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var before auditSignIn
+	err = tx.QueryRowxContext(ctx, `DELETE FROM sign_in_failures WHERE scope = $1 AND subject = $2 RETURNING failures`,
+		models.SignInScopeEmail, email).Scan(&before.SignInFailures)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, userId, &before, &auditSignIn{}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// UpdatePassword replaces the hash of the password with the upgraded hash of the same password
+func (repo repository) UpdatePassword(ctx context.Context, userId int, password string) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	if _, err = tx.ExecContext(ctx, `UPDATE users SET password = $1 WHERE id = $2`, password, userId); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	// the hash is upgraded on sign in without an access token, the user is the actor
+	var before, after = &auditCredentials{}, &auditCredentials{Password: passwordReplaced}
+	if err = audit.Record(audit.WithActor(ctx, int64(userId)), tx, audit.ActionUpdate, audit.EntityUser, userId, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// FindMFA second factor of the user and whether the role of the user requires it
+func (repo repository) FindMFA(ctx context.Context, userId int) (*models.UserMFA, error) {
+	var mfa = &models.UserMFA{}
+	err := repo.db.QueryRowxContext(ctx, `SELECT id, COALESCE(mfa_secret, '') AS mfa_secret, mfa_enabled_at, mfa_last_step,
+	EXISTS(SELECT 1 FROM mfa_required_roles r WHERE r.role = users.role) AS mfa_required
+	FROM users WHERE id = $1 AND deleted_at IS NULL`, userId).StructScan(mfa)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrExecuteStatement
+	}
+	return mfa, nil
+}
+
+// SaveMFAEnrollment stores the pending secret and replaces the recovery codes, an enabled second
+// factor is not replaced
+func (repo repository) SaveMFAEnrollment(ctx context.Context, userId int, secret string, codeHashes []string) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.findAuditMFA(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+	if before.MFA == mfaEnabled {
+		return ErrMFAAlreadyEnabled
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET mfa_secret = $1, mfa_enabled_at = NULL, mfa_last_step = 0 WHERE id = $2 AND mfa_enabled_at IS NULL`,
+		secret, userId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	for _, codeHash := range codeHashes {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES($1, $2)`, userId, codeHash); err != nil {
+			tracing.Logger(ctx, repo.log).Info(err.Error())
+			return ErrExecuteStatement
+		}
+	}
+
+	// the enrollment of the sign in has no access token, the user is the actor
+	var after = &auditMFA{MFA: mfaPending, RecoveryCodes: len(codeHashes)}
+	if err = audit.Record(audit.WithActor(ctx, int64(userId)), tx, audit.ActionUpdate, audit.EntityUser, userId, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// EnableMFA confirms the pending secret, step is the period of the code that confirmed it
+func (repo repository) EnableMFA(ctx context.Context, userId int, step int64) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.findAuditMFA(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET mfa_enabled_at = NOW(), mfa_last_step = $1
+	WHERE id = $2 AND mfa_secret IS NOT NULL AND mfa_enabled_at IS NULL`, step, userId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	// the code of the sign in confirms it without an access token, the user is the actor
+	var after = &auditMFA{MFA: mfaEnabled, RecoveryCodes: before.RecoveryCodes}
+	if err = audit.Record(audit.WithActor(ctx, int64(userId)), tx, audit.ActionUpdate, audit.EntityUser, userId, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// UseMFAStep keeps the period of the accepted code, the codes of that period or older are rejected
+func (repo repository) UseMFAStep(ctx context.Context, userId int, step int64) error {
+	res, err := repo.db.ExecContext(ctx, `UPDATE users SET mfa_last_step = $1 WHERE id = $2 AND mfa_last_step < $1`, step, userId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the recovery code of the user as used
+func (repo repository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) error {
+	res, err := repo.db.ExecContext(ctx, `UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userId, codeHash)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// DeleteMFA removes the second factor and the recovery codes of the user
+func (repo repository) DeleteMFA(ctx context.Context, userId int) error {
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.findAuditMFA(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = 0 WHERE id = $1 AND deleted_at IS NULL`, userId)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrUserNotFound
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	// the actor is the user or the admin that resets it
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, userId, before, &auditMFA{MFA: mfaDisabled}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// UpdateRoleMFA sets whether the users of the role have to sign in with a second factor
+func (repo repository) UpdateRoleMFA(ctx context.Context, role models.Role, required bool) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var before auditRoleMFA
+	err = tx.QueryRowxContext(ctx, `SELECT EXISTS(SELECT 1 FROM mfa_required_roles WHERE role = $1)`, role).Scan(&before.MFARequired)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	var query = `DELETE FROM mfa_required_roles WHERE role = $1`
+	if required {
+		query = `INSERT INTO mfa_required_roles(role) VALUES($1) ON CONFLICT (role) DO NOTHING`
+	}
+	if _, err = tx.ExecContext(ctx, query, role); err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrExecuteStatement
+	}
+
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityRole, role, &before, &auditRoleMFA{MFARequired: required}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// findAuditMFA locks the user and reads the state of its second factor for the audit log
+func (repo repository) findAuditMFA(ctx context.Context, tx *sqlx.Tx, userId int) (*auditMFA, error) {
+	var state = &auditMFA{}
+	err := tx.QueryRowxContext(ctx, findAuditMFAQuery, userId).StructScan(state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrExecuteStatement
+	}
+	return state, nil
+}
+
+const findAuditMFAQuery = `SELECT CASE WHEN mfa_enabled_at IS NOT NULL THEN 'enabled' WHEN mfa_secret IS NOT NULL THEN 'pending' ELSE 'disabled' END AS mfa,
+	(SELECT COUNT(*) FROM mfa_recovery_codes c WHERE c.user_id = users.id AND c.used_at IS NULL) AS recovery_codes
+	FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
@@ -5,11 +5,14 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/password"
 	"testing"
 	"time"
 )
@@ -32,7 +35,7 @@ func TestRepository_CreateAccount(t *testing.T) {
 
 	repo := NewAuthRepository(sqlxDB, logger)
 
-	var query = `INSERT INTO users(name, email, password) VALUES($1, $2, $3)`
+	var query = `INSERT INTO users(name, email, password) VALUES($1, $2, $3) RETURNING id`
 
 	var item = &models.RegisterForm{
 		Name:     "Jhon Wick",
@@ -49,8 +52,12 @@ func TestRepository_CreateAccount(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WithArgs(item.Name, item.Email, item.Password).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityUser, "1", sqlmock.AnyArg(), nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectCommit()
@@ -110,7 +117,7 @@ func TestRepository_CreateAccount(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WithArgs(item.Name, item.Email, item.Password).
 			WillReturnError(&pgconn.PgError{
 				Code: "23505", // Duplicate key error code
@@ -132,7 +139,7 @@ func TestRepository_CreateAccount(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WithArgs(item.Name, item.Email, item.Password).
 			WillReturnError(ErrFailInsertUser)
 
@@ -152,9 +159,13 @@ func TestRepository_CreateAccount(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WithArgs(item.Name, item.Email, item.Password).
-			WillReturnResult(sqlmock.NewResult(0, 0))
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityUser, "1", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectCommit().WillReturnError(ErrCommitTransaction)
 
@@ -188,6 +199,8 @@ func TestRepository_FindUserByCredentials(t *testing.T) {
        	   name,
 		   email,
 		   password,
+		   role,
+		   email_verified_at,
 		   created_at,
 		   updated_at
 	FROM users
@@ -206,8 +219,8 @@ func TestRepository_FindUserByCredentials(t *testing.T) {
 		Password: "123456",
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
-		AddRow(item.ID, item.Name, item.Email, item.Password, item.CreatedAt, nil)
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "role", "email_verified_at", "created_at", "updated_at"}).
+		AddRow(item.ID, item.Name, item.Email, item.Password, "clinician", nil, item.CreatedAt, nil)
 
 	t.Run("OK", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
@@ -222,6 +235,7 @@ func TestRepository_FindUserByCredentials(t *testing.T) {
 		t.Log(user, "err ", err)
 		assert.NoError(t, err)
 		assert.Equal(t, user.Email, item.Email)
+		assert.Equal(t, models.RoleClinician, user.Role)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -255,3 +269,583 @@ func TestRepository_FindUserByCredentials(t *testing.T) {
 	})
 
 }
+
+func TestRepository_Argon2idPassword(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	hasher, err := password.NewHasher(models.Password{PasswordAlgorithm: password.AlgorithmArgon2id, Argon2Memory: 65536, Argon2Iterations: 3, Argon2Parallelism: 2})
+	assert.NoError(t, err)
+	hash, err := hasher.Hash("Secret123")
+	assert.NoError(t, err)
+	// users.password is VARCHAR(255) since 000015, the CHAR(60) of bcrypt did not fit the hash
+	assert.Greater(t, len(hash), 60)
+	assert.LessOrEqual(t, len(hash), 255)
+
+	var form = &models.RegisterForm{Name: "Jhon Wick", Email: "jhonwick@gmail.com", Password: hash}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO users(name, email, password) VALUES($1, $2, $3) RETURNING id`).
+		ExpectQuery().
+		WithArgs(form.Name, form.Email, hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(audit.InsertQuery).
+		WithArgs(nil, audit.ActionCreate, audit.EntityUser, "1", sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectPrepare(`SELECT id,
+       	   name,
+		   email,
+		   password,
+		   role,
+		   email_verified_at,
+		   created_at,
+		   updated_at
+	FROM users
+	WHERE email = $1`).
+		ExpectQuery().
+		WithArgs(form.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "role", "email_verified_at", "created_at", "updated_at"}).
+			AddRow(1, form.Name, form.Email, hash, "readonly", nil, time.Now(), nil))
+
+	assert.NoError(t, repo.CreateAccount(context.Background(), form))
+	user, err := repo.FindUserByEmail(context.Background(), form.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, user.Password)
+
+	ok, rehash, err := hasher.Verify("Secret123", user.Password)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_RotateRefreshToken(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	repo := NewAuthRepository(sqlxDB, logger)
+
+	var selectQuery = `SELECT id, user_id, family_id, token_hash, expires_at, revoked_at
+	FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`
+	var columns = []string{"id", "user_id", "family_id", "token_hash", "expires_at", "revoked_at"}
+	var expiresAt = time.Now().Add(time.Hour)
+
+	t.Run("Rotate is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+		var next = &models.RefreshToken{TokenHash: "next", ExpiresAt: expiresAt}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("current").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "family", "current", expiresAt, nil))
+		mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES($1, $2, $3, $4)`).
+			WithArgs(int32(7), "family", "next", expiresAt).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		err := repo.RotateRefreshToken(ctx, "current", next)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(7), next.UserID)
+		assert.Equal(t, "family", next.FamilyID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reuse revokes the family", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("current").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "family", "current", expiresAt, time.Now()))
+		mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`).
+			WithArgs("family").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.RotateRefreshToken(ctx, "current", &models.RefreshToken{TokenHash: "next", ExpiresAt: expiresAt})
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_CreateUserToken(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var token = &models.UserToken{UserID: 7, Purpose: models.TokenPasswordReset, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectBegin()
+	// the previous tokens stop working
+	mock.ExpectExec(`UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`).
+		WithArgs(int32(7), models.TokenPasswordReset).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_tokens(user_id, purpose, token_hash, expires_at) VALUES($1, $2, $3, $4)`).
+		WithArgs(int32(7), models.TokenPasswordReset, "hash", token.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.CreateUserToken(context.Background(), token))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_ResetPassword(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	var selectQuery = `SELECT id, user_id, purpose, token_hash, expires_at, used_at
+	FROM user_tokens WHERE token_hash = $1 AND purpose = $2 FOR UPDATE`
+	var columns = []string{"id", "user_id", "purpose", "token_hash", "expires_at", "used_at"}
+
+	t.Run("Reset is OK", func(t *testing.T) {
+		var actor int64 = 7
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("hash", models.TokenPasswordReset).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "password_reset", "hash", time.Now().Add(time.Hour), nil))
+		mock.ExpectExec(`UPDATE user_tokens SET used_at = NOW() WHERE id = $1`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE`).
+			WithArgs(int32(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"verified"}).AddRow(false))
+		mock.ExpectExec(`UPDATE users SET password = $1, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $2`).
+			WithArgs("bcrypt", int32(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// the open sessions are closed
+		mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`).
+			WithArgs(int32(7)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		// the hash is never recorded
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(&actor, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"email_verified":false,"password":null},"after":{"email_verified":true,"password":"replaced"}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.ResetPassword(context.Background(), "hash", "bcrypt"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Used token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("hash", models.TokenPasswordReset).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "password_reset", "hash", time.Now().Add(time.Hour), time.Now()))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.ResetPassword(context.Background(), "hash", "bcrypt"), ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("hash", models.TokenPasswordReset).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "password_reset", "hash", time.Now().Add(-time.Minute), nil))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.ResetPassword(context.Background(), "hash", "bcrypt"), ErrTokenExpired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs("other", models.TokenPasswordReset).
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.ResetPassword(context.Background(), "other", "bcrypt"), ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_VerifyEmail(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var actor int64 = 7
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, purpose, token_hash, expires_at, used_at
+	FROM user_tokens WHERE token_hash = $1 AND purpose = $2 FOR UPDATE`).
+		WithArgs("hash", models.TokenEmailVerification).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "expires_at", "used_at"}).
+			AddRow(1, 7, "email_verification", "hash", time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE user_tokens SET used_at = NOW() WHERE id = $1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE`).
+		WithArgs(int32(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"verified"}).AddRow(false))
+	mock.ExpectExec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`).
+		WithArgs(int32(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(audit.InsertQuery).
+		WithArgs(&actor, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"email_verified":false},"after":{"email_verified":true}}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.VerifyEmail(context.Background(), "hash"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_RecordSignInFailure(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM sign_in_failures WHERE last_failure_at < NOW() - make_interval(secs => $1)`).
+		WithArgs(float64(900)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`INSERT INTO sign_in_failures(scope, subject, failures, last_failure_at) VALUES($1, $2, 1, NOW()), ($3, $4, 1, NOW())
+	ON CONFLICT (scope, subject) DO UPDATE SET failures = sign_in_failures.failures + 1, last_failure_at = NOW()`).
+		WithArgs(models.SignInScopeEmail, "jhonwick@gmail.com", models.SignInScopeIP, "10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.RecordSignInFailure(context.Background(), "jhonwick@gmail.com", "10.0.0.1", 15*time.Minute))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UnlockAccount(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "1"})
+	assert.NoError(t, err)
+	ctx := jwtauth.NewContext(context.Background(), token, nil)
+	var admin int64 = 1
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM sign_in_failures WHERE scope = $1 AND subject = $2 RETURNING failures`).
+		WithArgs(models.SignInScopeEmail, "jhonwick@gmail.com").
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(5))
+	mock.ExpectExec(audit.InsertQuery).
+		WithArgs(&admin, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"sign_in_failures":5},"after":{"sign_in_failures":0}}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UnlockAccount(ctx, 7, "jhonwick@gmail.com"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_FindSignInFailures(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var lastFailureAt = time.Now()
+
+	mock.ExpectQuery(`SELECT scope, subject, failures, last_failure_at
+	FROM sign_in_failures WHERE (scope = $1 AND subject = $2) OR (scope = $3 AND subject = $4)`).
+		WithArgs(models.SignInScopeEmail, "jhonwick@gmail.com", models.SignInScopeIP, "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"scope", "subject", "failures", "last_failure_at"}).
+			AddRow("email", "jhonwick@gmail.com", 3, lastFailureAt))
+
+	failures, err := repo.FindSignInFailures(context.Background(), "jhonwick@gmail.com", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.SignInFailure{{Scope: models.SignInScopeEmail, Subject: "jhonwick@gmail.com", Failures: 3, LastFailureAt: lastFailureAt}}, failures)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdatePassword(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	var actor int64 = 7
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET password = $1 WHERE id = $2`).
+		WithArgs("$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$a2V5", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(audit.InsertQuery).
+		WithArgs(&actor, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"password":null},"after":{"password":"replaced"}}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdatePassword(context.Background(), 7, "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$a2V5"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdateUserRole(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	repo := NewAuthRepository(sqlxDB, logger)
+
+	var selectQuery = `SELECT role FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	var query = `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`
+
+	t.Run("Update is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("readonly"))
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs(models.RoleClinician, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"role":"readonly"},"after":{"role":"clinician"}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.UpdateUserRole(ctx, 7, models.RoleClinician)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).
+			WithArgs(8).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.UpdateUserRole(ctx, 8, models.RoleClinician)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindMFA(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var query = `SELECT id, COALESCE(mfa_secret, '') AS mfa_secret, mfa_enabled_at, mfa_last_step,
+	EXISTS(SELECT 1 FROM mfa_required_roles r WHERE r.role = users.role) AS mfa_required
+	FROM users WHERE id = $1 AND deleted_at IS NULL`
+	var enabledAt = time.Now()
+
+	mock.ExpectQuery(query).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "mfa_secret", "mfa_enabled_at", "mfa_last_step", "mfa_required"}).
+			AddRow(7, "SECRET", enabledAt, 100, true))
+	mock.ExpectQuery(query).WithArgs(8).WillReturnError(sql.ErrNoRows)
+
+	mfa, err := repo.FindMFA(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.UserMFA{UserID: 7, Secret: "SECRET", EnabledAt: &enabledAt, LastStep: 100, Required: true}, mfa)
+	assert.True(t, mfa.Enabled())
+
+	_, err = repo.FindMFA(context.Background(), 8)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_SaveMFAEnrollment(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var update = `UPDATE users SET mfa_secret = $1, mfa_enabled_at = NULL, mfa_last_step = 0 WHERE id = $2 AND mfa_enabled_at IS NULL`
+
+	t.Run("Save", func(t *testing.T) {
+		var actor int64 = 7
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"mfa", "recovery_codes"}).AddRow("pending", 10))
+		mock.ExpectExec(update).WithArgs("SECRET", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectExec(`INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES($1, $2)`).WithArgs(7, "hash1").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES($1, $2)`).WithArgs(7, "hash2").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(&actor, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"recovery_codes":10},"after":{"recovery_codes":2}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.SaveMFAEnrollment(context.Background(), 7, "SECRET", []string{"hash1", "hash2"}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Enabled second factor is kept", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"mfa", "recovery_codes"}).AddRow("enabled", 10))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.SaveMFAEnrollment(context.Background(), 7, "SECRET", []string{"hash1"}), ErrMFAAlreadyEnabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_EnableMFA(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var update = `UPDATE users SET mfa_enabled_at = NOW(), mfa_last_step = $1
+	WHERE id = $2 AND mfa_secret IS NOT NULL AND mfa_enabled_at IS NULL`
+
+	t.Run("Enable", func(t *testing.T) {
+		var actor int64 = 7
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"mfa", "recovery_codes"}).AddRow("pending", 10))
+		mock.ExpectExec(update).WithArgs(int64(100), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(&actor, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"mfa":"pending"},"after":{"mfa":"enabled"}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.EnableMFA(context.Background(), 7, 100))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"mfa", "recovery_codes"}).AddRow("enabled", 10))
+		mock.ExpectExec(update).WithArgs(int64(100), 7).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.EnableMFA(context.Background(), 7, 100), ErrMFAAlreadyEnabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_DeleteMFA(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	t.Run("Reset by an admin", func(t *testing.T) {
+		token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "1"})
+		assert.NoError(t, err)
+		ctx := jwtauth.NewContext(context.Background(), token, nil)
+		var admin int64 = 1
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"mfa", "recovery_codes"}).AddRow("enabled", 8))
+		mock.ExpectExec(`UPDATE users SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = 0 WHERE id = $1 AND deleted_at IS NULL`).
+			WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(&admin, audit.ActionUpdate, audit.EntityUser, "7", `{"before":{"mfa":"enabled","recovery_codes":8},"after":{"mfa":"disabled","recovery_codes":0}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.DeleteMFA(ctx, 7))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(findAuditMFAQuery).WithArgs(8).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.DeleteMFA(context.Background(), 8), ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_UpdateRoleMFA(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS(SELECT 1 FROM mfa_required_roles WHERE role = $1)`).WithArgs(models.RoleClinician).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`INSERT INTO mfa_required_roles(role) VALUES($1) ON CONFLICT (role) DO NOTHING`).WithArgs(models.RoleClinician).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(audit.InsertQuery).
+		WithArgs(nil, audit.ActionUpdate, audit.EntityRole, "clinician", `{"before":{"mfa_required":false},"after":{"mfa_required":true}}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdateRoleMFA(context.Background(), models.RoleClinician, true))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UseMFAStep(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuthRepository(sqlx.NewDb(db, "sqlmock"), zap.NewNop())
+	var query = `UPDATE users SET mfa_last_step = $1 WHERE id = $2 AND mfa_last_step < $1`
+
+	mock.ExpectExec(query).WithArgs(int64(100), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	// the step was already used
+	mock.ExpectExec(query).WithArgs(int64(100), 7).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UseMFAStep(context.Background(), 7, 100))
+	assert.ErrorIs(t, repo.UseMFAStep(context.Background(), 7, 100), ErrInvalidMFACode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
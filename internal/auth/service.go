@@ -3,89 +3,419 @@ package auth
 import (
 	"context"
 	"errors"
-	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/metrics"
+	"kiramishima/ionix/internal/pkg/totp"
+	"kiramishima/ionix/internal/pkg/tracing"
 	"kiramishima/ionix/internal/pkg/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 var _ impl.AuthService = (*service)(nil)
 
+// AccountOptions settings of the password reset and email verification mails, of the sign in lockout
+// and of the second factor
+type AccountOptions struct {
+	PasswordResetTTL         time.Duration
+	EmailVerificationTTL     time.Duration
+	RequireEmailVerification bool
+	// AppURL base of the links sent by mail
+	AppURL string
+	// SignInMaxFailures and SignInMaxIPFailures failures until the email or the IP is locked, 0 disables the lock
+	SignInMaxFailures   int
+	SignInMaxIPFailures int
+	// SignInFailureDelay wait after the first failure of an email, it doubles with every failure
+	SignInFailureDelay time.Duration
+	// SignInLockout time that a locked email or IP waits, the failures older than it are forgotten
+	SignInLockout time.Duration
+	// MFAIssuer name of the accounts in the authenticator apps
+	MFAIssuer string
+	// MFAChallengeTTL time to send the code after the password
+	MFAChallengeTTL time.Duration
+	// MFARecoveryCodes recovery codes given on enrollment
+	MFARecoveryCodes int
+}
+
+// retryAfter time left until the email or IP of failure can sign in again, the IPs are shared by
+// many users so they only wait when they are locked
+func (o AccountOptions) retryAfter(failure *models.SignInFailure, now time.Time) time.Duration {
+	var max, wait = o.SignInMaxFailures, time.Duration(0)
+	if failure.Scope == models.SignInScopeIP {
+		max = o.SignInMaxIPFailures
+	}
+
+	switch {
+	case failure.Failures <= 0:
+		return 0
+	case max > 0 && failure.Failures >= max:
+		wait = o.SignInLockout
+	case failure.Scope == models.SignInScopeEmail:
+		wait = o.SignInFailureDelay
+		for i := 1; i < failure.Failures && wait < o.SignInLockout; i++ {
+			wait *= 2
+		}
+		wait = min(wait, o.SignInLockout)
+	}
+	return failure.LastFailureAt.Add(wait).Sub(now)
+}
+
 type service struct {
-	logger         *zap.Logger
-	repository     impl.AuthRepository
-	contextTimeOut time.Duration
+	logger          *zap.Logger
+	repository      impl.AuthRepository
+	mailer          impl.Mailer
+	hasher          impl.PasswordHasher
+	policy          impl.PasswordPolicy
+	signer          impl.TokenSigner
+	contextTimeOut  time.Duration
+	refreshTokenTTL time.Duration
+	accounts        AccountOptions
+	// dummyPassword hash compared with the password of the sign ins of emails without account
+	dummyPassword func() string
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(repo impl.AuthRepository, logger *zap.Logger, timeout time.Duration) *service {
+func NewAuthService(repo impl.AuthRepository, mailer impl.Mailer, hasher impl.PasswordHasher, policy impl.PasswordPolicy, signer impl.TokenSigner, logger *zap.Logger, timeout time.Duration, refreshTokenTTL time.Duration, accounts AccountOptions) *service {
 	return &service{
-		logger:         logger,
-		repository:     repo,
-		contextTimeOut: timeout,
+		logger:          logger,
+		repository:      repo,
+		mailer:          mailer,
+		hasher:          hasher,
+		policy:          policy,
+		signer:          signer,
+		contextTimeOut:  timeout,
+		refreshTokenTTL: refreshTokenTTL,
+		accounts:        accounts,
+		dummyPassword: sync.OnceValue(func() string {
+			hash, _ := hasher.Hash("dummy password")
+			return hash
+		}),
 	}
 }
 
-func (svc service) SignIn(ctx context.Context, form *models.AuthForm) (*models.AuthResponse, error) {
-	form.Password = form.Hash256Password(form.Password)
+// signInEmail email as it is counted by the sign in lockout
+func signInEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (svc service) SignIn(ctx context.Context, form *models.AuthForm, ip string) (*models.AuthResponse, error) {
+	var email = signInEmail(form.Email)
 
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 
+	if err := svc.checkSignInFailures(ctx, cxt, email, ip); err != nil {
+		return nil, err
+	}
+
 	user, err := svc.repository.FindUserByCredentials(ctx, form)
-	svc.logger.Info("", zap.Any("user", user), zap.Any("error", err))
+	tracing.Logger(ctx, svc.logger).Info("", zap.Any("user", user), zap.Any("error", err))
+	if errors.Is(err, ErrUserNotFound) {
+		// the password is compared anyway so the answer takes the same time as with an account
+		_, _, _ = svc.hasher.Verify(form.Password, svc.dummyPassword())
+		svc.recordSignInFailure(ctx, cxt, email, ip)
+	}
 	if err != nil {
 		select {
 		case <-cxt.Done():
-			svc.logger.Info("Timeout", zap.Error(ctx.Err()))
+			tracing.Logger(ctx, svc.logger).Info("Timeout", zap.Error(ctx.Err()))
+			metrics.SignInsFailed.WithLabelValues("error").Inc()
 			return nil, ErrServiceAuth
 		default:
 			if errors.Is(err, ErrPrepapareQuery) {
-				svc.logger.Info(ErrPrepapareQuery.Error())
+				tracing.Logger(ctx, svc.logger).Info(ErrPrepapareQuery.Error())
+				metrics.SignInsFailed.WithLabelValues("error").Inc()
 				return nil, ErrPrepapareQuery
 			} else if errors.Is(err, ErrUserNotFound) {
-				svc.logger.Info(ErrUserNotFound.Error())
+				tracing.Logger(ctx, svc.logger).Info(ErrUserNotFound.Error())
+				metrics.SignInsFailed.WithLabelValues("user_not_found").Inc()
 				return nil, ErrUserNotFound
 			} else {
-				svc.logger.Info(ErrServiceAuth.Error())
+				tracing.Logger(ctx, svc.logger).Info(ErrServiceAuth.Error())
+				metrics.SignInsFailed.WithLabelValues("error").Inc()
 				return nil, ErrServiceAuth
 			}
 		}
 	}
 
 	// Check Password
-	if !form.ValidateBcryptPassword(user.Password, form.Password) {
-		svc.logger.Info(ErrInvalidPassword.Error())
+	valid, rehash, err := svc.hasher.Verify(form.Password, user.Password)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error("failed to verify the password", zap.Int32("user_id", user.ID), zap.Error(err))
+		metrics.SignInsFailed.WithLabelValues("error").Inc()
+		return nil, ErrServiceAuth
+	}
+	if !valid {
+		tracing.Logger(ctx, svc.logger).Info(ErrInvalidPassword.Error())
+		metrics.SignInsFailed.WithLabelValues("invalid_password").Inc()
+		svc.recordSignInFailure(ctx, cxt, email, ip)
 		return nil, ErrInvalidPassword
 	}
+	if rehash {
+		svc.rehashPassword(ctx, cxt, user, form.Password)
+	}
+
+	// the check is after the password so it does not tell if the account exists
+	if svc.accounts.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		tracing.Logger(ctx, svc.logger).Info(ErrEmailNotVerified.Error())
+		metrics.SignInsFailed.WithLabelValues("email_not_verified").Inc()
+		return nil, ErrEmailNotVerified
+	}
+
+	// the users with a second factor get a challenge instead of the tokens, the failures are kept
+	// until the code is accepted so the password does not reset the count of wrong codes
+	mfa, err := svc.repository.FindMFA(cxt, int(user.ID))
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		metrics.SignInsFailed.WithLabelValues("error").Inc()
+		return nil, ErrServiceAuth
+	}
+	if mfa.Enabled() || mfa.Required {
+		return svc.mfaChallenge(ctx, cxt, user, mfa)
+	}
+
+	if err := svc.repository.ClearSignInFailures(cxt, email); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+	}
+	return svc.issueTokens(ctx, cxt, user)
+}
 
+// issueTokens signs the access token and starts the refresh token family of a new session
+func (svc service) issueTokens(ctx context.Context, cxt context.Context, user *models.User) (*models.AuthResponse, error) {
 	// Generate Token
-	token, err := utils.GenerateJWT(user)
+	token, err := svc.signer.Sign(user)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(ErrGenerateToken.Error(), zap.Error(err))
+		return nil, ErrGenerateToken
+	}
+
+	// every sign in starts a new refresh token family
+	familyID, err := utils.NewTokenID()
+	if err != nil {
+		return nil, ErrGenerateToken
+	}
+	refreshToken, tokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, ErrGenerateToken
+	}
+	err = svc.repository.CreateRefreshToken(cxt, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(svc.refreshTokenTTL),
+	})
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return nil, ErrServiceAuth
+	}
+
+	return &models.AuthResponse{AccessToken: token, RefreshToken: refreshToken, ExpiresIn: int(svc.signer.TTL().Seconds())}, nil
+}
+
+func (svc service) Refresh(ctx context.Context, form *models.RefreshTokenForm) (*models.AuthResponse, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	refreshToken, tokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, ErrGenerateToken
+	}
+	var next = &models.RefreshToken{
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(svc.refreshTokenTTL),
+	}
+
+	err = svc.repository.RotateRefreshToken(cxt, utils.HashToken(form.RefreshToken), next)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrServiceAuth
+		default:
+			if errors.Is(err, ErrInvalidRefreshToken) {
+				return nil, ErrInvalidRefreshToken
+			} else if errors.Is(err, ErrRefreshTokenExpired) {
+				return nil, ErrRefreshTokenExpired
+			} else if errors.Is(err, ErrRefreshTokenReused) {
+				tracing.Logger(ctx, svc.logger).Warn("refresh token reuse detected, the token family was revoked")
+				return nil, ErrRefreshTokenReused
+			} else {
+				return nil, ErrServiceAuth
+			}
+		}
+	}
+
+	// the role may have changed since the sign in
+	user, err := svc.repository.FindUserByID(cxt, int(next.UserID))
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, ErrServiceAuth
+	}
+
+	token, err := svc.signer.Sign(user)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(ErrGenerateToken.Error(), zap.Error(err))
+		return nil, ErrGenerateToken
+	}
+
+	return &models.AuthResponse{AccessToken: token, RefreshToken: refreshToken, ExpiresIn: int(svc.signer.TTL().Seconds())}, nil
+}
+
+// rehashPassword replaces the hash of an old scheme or parameters, the password is only known on
+// sign in, a failure keeps the old hash that still works
+func (svc service) rehashPassword(ctx context.Context, cxt context.Context, user *models.User, password string) {
+	hash, err := svc.hasher.Hash(password)
+	if err == nil {
+		err = svc.repository.UpdatePassword(cxt, int(user.ID), hash)
+	}
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error("failed to upgrade the password hash", zap.Int32("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// checkSignInFailures rejects the sign in while the email or the IP has to wait after its failures
+func (svc service) checkSignInFailures(ctx context.Context, cxt context.Context, email string, ip string) error {
+	failures, err := svc.repository.FindSignInFailures(cxt, email, ip)
 	if err != nil {
-		svc.logger.Info("Token Gen Error", zap.Any("TokenGenError", fmt.Sprintf("%T", err)))
-		return nil, jwt.ErrSignatureInvalid
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		metrics.SignInsFailed.WithLabelValues("error").Inc()
+		return ErrServiceAuth
+	}
+
+	var now = time.Now()
+	var wait time.Duration
+	for _, failure := range failures {
+		if d := svc.accounts.retryAfter(failure, now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		tracing.Logger(ctx, svc.logger).Info(ErrTooManyAttempts.Error(), zap.Duration("retry_after", wait))
+		metrics.SignInsFailed.WithLabelValues("locked").Inc()
+		return &LockedError{RetryAfter: wait}
 	}
+	return nil
+}
 
-	return &models.AuthResponse{AccessToken: token}, nil
+// recordSignInFailure counts the failure, an error only loses the count so it is logged
+func (svc service) recordSignInFailure(ctx context.Context, cxt context.Context, email string, ip string) {
+	if err := svc.repository.RecordSignInFailure(cxt, email, ip, svc.accounts.SignInLockout); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+	}
+}
+
+// Unlock forgets the failed sign ins of the account of the user, the IPs stay locked
+func (svc service) Unlock(ctx context.Context, userId int) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	user, err := svc.repository.FindUserByID(cxt, userId)
+	if err == nil {
+		err = svc.repository.UnlockAccount(cxt, userId, signInEmail(user.Email))
+	}
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrUserNotFound) {
+				return ErrUserNotFound
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) UpdateRole(ctx context.Context, userId int, form *models.RoleForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	var role = models.Role(*form.Role)
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	err := svc.repository.UpdateUserRole(cxt, userId, role)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrUserNotFound) {
+				return ErrUserNotFound
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) Logout(ctx context.Context, userId int, jti string, expiresAt time.Time, form *models.RefreshTokenForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	err := svc.repository.RevokeRefreshToken(cxt, utils.HashToken(form.RefreshToken), userId)
+	if err == nil {
+		err = svc.repository.RevokeAccessToken(cxt, jti, expiresAt)
+	}
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrRevokeToken) {
+				return ErrRevokeToken
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
 }
 
 func (svc service) SignUp(ctx context.Context, form *models.RegisterForm) error {
-	// Hash password
-	form.Password = form.Hash256Password(form.Password)
-	// Hash Bcrypt
-	form.Password, _ = form.BcryptPassword(form.Password)
+	if err := svc.policy.Check(form.Password); err != nil {
+		return err
+	}
+	hash, err := svc.hasher.Hash(form.Password)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
+	form.Password = hash
 
 	// context
 	ctx, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 	// Call repository
-	err := svc.repository.CreateAccount(ctx, form)
+	err = svc.repository.CreateAccount(ctx, form)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
@@ -101,5 +431,434 @@ func (svc service) SignUp(ctx context.Context, form *models.RegisterForm) error
 		}
 	}
 
+	svc.sendVerification(ctx, form.Email)
+	return nil
+}
+
+func (svc service) ForgotPassword(ctx context.Context, form *models.EmailForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	user, err := svc.repository.FindUserByEmail(cxt, form.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		// the answer is the same so the client does not know if the account exists
+		return nil
+	} else if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
+
+	svc.sendToken(ctx, cxt, user, models.TokenPasswordReset)
+	return nil
+}
+
+func (svc service) ResetPassword(ctx context.Context, form *models.ResetPasswordForm) error {
+	// the token is not used while the password is rejected
+	if err := svc.policy.Check(form.Password); err != nil {
+		return err
+	}
+	hash, err := svc.hasher.Hash(form.Password)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
+
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	err = svc.repository.ResetPassword(cxt, utils.HashToken(form.Token), hash)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrInvalidToken) {
+				return ErrInvalidToken
+			} else if errors.Is(err, ErrTokenExpired) {
+				return ErrTokenExpired
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) VerifyEmail(ctx context.Context, form *models.VerifyEmailForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	err := svc.repository.VerifyEmail(cxt, utils.HashToken(form.Token))
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrInvalidToken) {
+				return ErrInvalidToken
+			} else if errors.Is(err, ErrTokenExpired) {
+				return ErrTokenExpired
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) ResendVerification(ctx context.Context, form *models.EmailForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	user, err := svc.repository.FindUserByEmail(cxt, form.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
+
+	if user.EmailVerifiedAt == nil {
+		svc.sendToken(ctx, cxt, user, models.TokenEmailVerification)
+	}
+	return nil
+}
+
+// sendVerification mails the verification link to a new account, the account is created even if
+// the mail fails because the link can be sent again
+func (svc service) sendVerification(ctx context.Context, email string) {
+	user, err := svc.repository.FindUserByEmail(ctx, email)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return
+	}
+	svc.sendToken(ctx, ctx, user, models.TokenEmailVerification)
+}
+
+// sendToken stores a new token of the purpose and mails its link to the user, the failures are only
+// logged because the answer can not depend on the account
+func (svc service) sendToken(ctx context.Context, cxt context.Context, user *models.User, purpose models.TokenPurpose) {
+	var ttl, page = svc.accounts.EmailVerificationTTL, "verify-email"
+	if purpose == models.TokenPasswordReset {
+		ttl, page = svc.accounts.PasswordResetTTL, "reset-password"
+	}
+
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(ErrGenerateToken.Error(), zap.Error(err))
+		return
+	}
+	err = svc.repository.CreateUserToken(cxt, &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return
+	}
+
+	var link = strings.TrimRight(svc.accounts.AppURL, "/") + "/" + page + "?token=" + url.QueryEscape(token)
+	var mail = &models.Mail{
+		To:      user.Email,
+		Subject: i18n.T(ctx, "mail."+string(purpose)+".subject"),
+		Body:    i18n.T(ctx, "mail."+string(purpose)+".body", strconv.Itoa(int(ttl.Minutes())), link),
+	}
+	if err := svc.mailer.Send(cxt, mail); err != nil {
+		tracing.Logger(ctx, svc.logger).Error("failed to send mail", zap.String("purpose", string(purpose)), zap.Error(err))
+	}
+}
+
+// mfaChallenge returns the token to send the code with, the users whose role requires a second factor
+// and did not add it get a new enrollment that the code confirms
+func (svc service) mfaChallenge(ctx context.Context, cxt context.Context, user *models.User, mfa *models.UserMFA) (*models.AuthResponse, error) {
+	var res = &models.AuthResponse{ExpiresIn: int(svc.accounts.MFAChallengeTTL.Seconds())}
+	if !mfa.Enabled() {
+		enrollment, err := svc.enroll(ctx, cxt, user)
+		if err != nil {
+			return nil, err
+		}
+		res.MFAEnrollment = enrollment
+	}
+
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, ErrGenerateToken
+	}
+	err = svc.repository.CreateUserToken(cxt, &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenMFAChallenge,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(svc.accounts.MFAChallengeTTL),
+	})
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return nil, ErrServiceAuth
+	}
+	res.MFAToken = token
+	return res, nil
+}
+
+// enroll stores a new pending secret with its recovery codes, the enrollment is confirmed by the first code
+func (svc service) enroll(ctx context.Context, cxt context.Context, user *models.User) (*models.MFAEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, ErrGenerateToken
+	}
+	var codes = make([]string, svc.accounts.MFARecoveryCodes)
+	var hashes = make([]string, svc.accounts.MFARecoveryCodes)
+	for i := range codes {
+		if codes[i], hashes[i], err = utils.GenerateRecoveryCode(); err != nil {
+			return nil, ErrGenerateToken
+		}
+	}
+
+	err = svc.repository.SaveMFAEnrollment(cxt, int(user.ID), secret, hashes)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrServiceAuth
+		default:
+			if errors.Is(err, ErrMFAAlreadyEnabled) {
+				return nil, ErrMFAAlreadyEnabled
+			} else {
+				return nil, ErrServiceAuth
+			}
+		}
+	}
+
+	return &models.MFAEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(svc.accounts.MFAIssuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// checkMFACode accepts a code of the authenticator app, which confirms a pending enrollment, or a
+// recovery code once the enrollment is confirmed
+func (svc service) checkMFACode(ctx context.Context, cxt context.Context, mfa *models.UserMFA, code string) error {
+	var err = ErrInvalidMFACode
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now()); ok && mfa.Enabled() {
+		err = svc.repository.UseMFAStep(cxt, int(mfa.UserID), step)
+	} else if ok {
+		err = svc.repository.EnableMFA(cxt, int(mfa.UserID), step)
+	} else if mfa.Enabled() {
+		err = svc.repository.UseRecoveryCode(cxt, int(mfa.UserID), utils.HashRecoveryCode(code))
+	}
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Info(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrInvalidMFACode) {
+				return ErrInvalidMFACode
+			} else if errors.Is(err, ErrMFAAlreadyEnabled) {
+				return ErrMFAAlreadyEnabled
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) VerifyMFA(ctx context.Context, form *models.MFAVerifyForm, ip string) (*models.AuthResponse, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	var user *models.User
+	var mfa *models.UserMFA
+	token, err := svc.repository.FindUserToken(cxt, utils.HashToken(form.MFAToken), models.TokenMFAChallenge)
+	if err == nil {
+		user, err = svc.repository.FindUserByID(cxt, int(token.UserID))
+	}
+	if err == nil {
+		mfa, err = svc.repository.FindMFA(cxt, int(token.UserID))
+	}
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrServiceAuth
+		default:
+			if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrUserNotFound) {
+				return nil, ErrInvalidToken
+			} else if errors.Is(err, ErrTokenExpired) {
+				return nil, ErrTokenExpired
+			} else {
+				return nil, ErrServiceAuth
+			}
+		}
+	}
+
+	// the wrong codes count as failed sign ins of the email, so the codes can not be guessed
+	var email = signInEmail(user.Email)
+	if err := svc.checkSignInFailures(ctx, cxt, email, ip); err != nil {
+		return nil, err
+	}
+	// the second factor was removed after the password was sent
+	if mfa.Secret == "" {
+		return nil, ErrInvalidToken
+	}
+	if err := svc.checkMFACode(ctx, cxt, mfa, form.Code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			metrics.SignInsFailed.WithLabelValues("invalid_mfa_code").Inc()
+			svc.recordSignInFailure(ctx, cxt, email, ip)
+		}
+		return nil, err
+	}
+
+	if err := svc.repository.UseUserToken(cxt, token.ID); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		if errors.Is(err, ErrInvalidToken) {
+			return nil, ErrInvalidToken
+		}
+		return nil, ErrServiceAuth
+	}
+	if err := svc.repository.ClearSignInFailures(cxt, email); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+	}
+	return svc.issueTokens(ctx, cxt, user)
+}
+
+func (svc service) EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	user, err := svc.repository.FindUserByID(cxt, userId)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrServiceAuth
+		default:
+			if errors.Is(err, ErrUserNotFound) {
+				return nil, ErrUserNotFound
+			} else {
+				return nil, ErrServiceAuth
+			}
+		}
+	}
+
+	return svc.enroll(ctx, cxt, user)
+}
+
+// findMFA second factor of the user of an authenticated request
+func (svc service) findMFA(ctx context.Context, cxt context.Context, userId int) (*models.UserMFA, error) {
+	mfa, err := svc.repository.FindMFA(cxt, userId)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrServiceAuth
+		default:
+			if errors.Is(err, ErrUserNotFound) {
+				return nil, ErrUserNotFound
+			} else {
+				return nil, ErrServiceAuth
+			}
+		}
+	}
+	return mfa, nil
+}
+
+func (svc service) ConfirmMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	mfa, err := svc.findMFA(ctx, cxt, userId)
+	if err != nil {
+		return err
+	}
+	if mfa.Secret == "" {
+		return ErrMFANotEnrolled
+	}
+	if mfa.Enabled() {
+		return ErrMFAAlreadyEnabled
+	}
+	return svc.checkMFACode(ctx, cxt, mfa, form.Code)
+}
+
+func (svc service) DisableMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	mfa, err := svc.findMFA(ctx, cxt, userId)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled() {
+		return ErrMFANotEnrolled
+	}
+	if mfa.Required {
+		return ErrMFARequired
+	}
+	if err := svc.checkMFACode(ctx, cxt, mfa, form.Code); err != nil {
+		return err
+	}
+
+	if err := svc.repository.DeleteMFA(cxt, userId); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
+	return nil
+}
+
+// ResetMFA removes the second factor of a user that lost the device and the recovery codes, the role
+// may ask for a new enrollment on the next sign in
+func (svc service) ResetMFA(ctx context.Context, userId int) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	err := svc.repository.DeleteMFA(cxt, userId)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrServiceAuth
+		default:
+			if errors.Is(err, ErrUserNotFound) {
+				return ErrUserNotFound
+			} else {
+				return ErrServiceAuth
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateRoleMFA sets whether the users of the role have to sign in with a second factor, it applies
+// from their next sign in
+func (svc service) UpdateRoleMFA(ctx context.Context, role string, form *models.RoleMFAForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	if !models.Role(role).Valid() {
+		return ErrInvalidRole
+	}
+
+	if err := svc.repository.UpdateRoleMFA(cxt, models.Role(role), *form.Required); err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+		return ErrServiceAuth
+	}
 	return nil
 }
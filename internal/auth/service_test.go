@@ -5,12 +5,27 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 	"kiramishima/ionix/internal/mocks"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/mailer"
+	"kiramishima/ionix/internal/pkg/password"
+	"kiramishima/ionix/internal/pkg/signer"
+	"kiramishima/ionix/internal/pkg/totp"
+	"kiramishima/ionix/internal/pkg/utils"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
 
+var (
+	// testHasher cheap argon2id parameters so the tests run fast
+	testHasher, _ = password.NewHasher(models.Password{PasswordAlgorithm: password.AlgorithmArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: bcrypt.MinCost})
+	testPolicy, _ = password.NewPolicy(models.Password{PasswordMinLength: 8, PasswordMaxLength: 128})
+	testSigner, _ = signer.New(models.JWT{JWTPrivateKey: "Megaman", TokenTTL: 300})
+)
+
 func TestService_SignIn(t *testing.T) {
 	t.Parallel()
 	logger := zap.NewNop()
@@ -47,20 +62,35 @@ func TestService_SignIn(t *testing.T) {
 	repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
 	repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).Return(user2, ErrInvalidPassword)
 	repo.EXPECT().FindUserByCredentials(gomock.Any(), notExist).Times(1).Return(nil, ErrUserNotFound)
+	repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	repo.EXPECT().FindSignInFailures(gomock.Any(), gomock.Any(), "10.0.0.1").AnyTimes().Return(nil, nil)
+	repo.EXPECT().RecordSignInFailure(gomock.Any(), gomock.Any(), "10.0.0.1", gomock.Any()).Times(1).Return(nil)
+	repo.EXPECT().ClearSignInFailures(gomock.Any(), "johnwick@gmail.com").Times(1).Return(nil)
+	repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1}, nil)
+	// the bcrypt hash of the account is upgraded to argon2id
+	repo.EXPECT().UpdatePassword(gomock.Any(), 1, gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, _ int, hash string) error {
+			ok, rehash, err := testHasher.Verify(good.Password, hash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.False(t, rehash)
+			return nil
+		})
 
-	svc := NewAuthService(repo, logger, 5)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, logger, 5, time.Hour, AccountOptions{})
 
 	t.Run("Good credentials", func(t *testing.T) {
 		ctx := context.Background()
-		var item, err = svc.SignIn(ctx, good)
+		var item, err = svc.SignIn(ctx, good, "10.0.0.1")
 		// t.Log(item, err)
 		assert.NoError(t, err)
 		assert.Equal(t, len(item.AccessToken) > 0, true)
+		assert.Equal(t, len(item.RefreshToken) > 0, true)
 	})
 
 	t.Run("Bad credentials", func(t *testing.T) {
 		ctx := context.Background()
-		var item, err = svc.SignIn(ctx, badPassword)
+		var item, err = svc.SignIn(ctx, badPassword, "10.0.0.1")
 		t.Log(item, err)
 		assert.Error(t, err)
 		// assert.Equal(t, len(item.AccessToken) > 0, true)
@@ -68,9 +98,568 @@ func TestService_SignIn(t *testing.T) {
 
 	t.Run("User Not Found", func(t *testing.T) {
 		ctx := context.Background()
-		var item, err = svc.SignIn(ctx, notExist)
+		var item, err = svc.SignIn(ctx, notExist, "10.0.0.1")
 		t.Log(item, err)
 		assert.Error(t, err)
 		// assert.Equal(t, len(item.AccessToken) > 0, true)
 	})
 }
+
+func TestService_Refresh(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, logger, 5*time.Second, time.Hour, AccountOptions{})
+
+	t.Run("Rotate token", func(t *testing.T) {
+		ctx := context.Background()
+		var form = &models.RefreshTokenForm{RefreshToken: "refresh-token"}
+
+		repo.EXPECT().RotateRefreshToken(gomock.Any(), utils.HashToken(form.RefreshToken), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ string, next *models.RefreshToken) error {
+				assert.NotEmpty(t, next.TokenHash)
+				next.UserID = 1
+				next.FamilyID = "family"
+				return nil
+			})
+
+		repo.EXPECT().FindUserByID(gomock.Any(), 1).Times(1).Return(&models.User{ID: 1, Role: models.RoleClinician}, nil)
+
+		item, err := svc.Refresh(ctx, form)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, item.AccessToken)
+		assert.NotEmpty(t, item.RefreshToken)
+		assert.NotEqual(t, form.RefreshToken, item.RefreshToken)
+	})
+
+	t.Run("Reused token", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(ErrRefreshTokenReused)
+
+		_, err := svc.Refresh(ctx, &models.RefreshTokenForm{RefreshToken: "used-token"})
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	})
+}
+
+func TestService_Logout(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, logger, 5*time.Second, time.Hour, AccountOptions{})
+
+	t.Run("Revoke tokens", func(t *testing.T) {
+		ctx := context.Background()
+		var form = &models.RefreshTokenForm{RefreshToken: "refresh-token"}
+		var expiresAt = time.Now().Add(time.Minute)
+
+		repo.EXPECT().RevokeRefreshToken(gomock.Any(), utils.HashToken(form.RefreshToken), 1).Times(1).Return(nil)
+		repo.EXPECT().RevokeAccessToken(gomock.Any(), "jti", expiresAt).Times(1).Return(nil)
+
+		assert.NoError(t, svc.Logout(ctx, 1, "jti", expiresAt, form))
+	})
+}
+
+func TestService_UpdateRole(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, logger, 5*time.Second, time.Hour, AccountOptions{})
+
+	t.Run("Update role", func(t *testing.T) {
+		ctx := context.Background()
+		var role = "clinician"
+		repo.EXPECT().UpdateUserRole(gomock.Any(), 1, models.RoleClinician).Times(1).Return(nil)
+
+		assert.NoError(t, svc.UpdateRole(ctx, 1, &models.RoleForm{Role: &role}))
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		ctx := context.Background()
+		var role = "admin"
+		repo.EXPECT().UpdateUserRole(gomock.Any(), 2, models.RoleAdmin).Times(1).Return(ErrUserNotFound)
+
+		assert.ErrorIs(t, svc.UpdateRole(ctx, 2, &models.RoleForm{Role: &role}), ErrUserNotFound)
+	})
+
+	t.Run("Invalid role", func(t *testing.T) {
+		ctx := context.Background()
+		var role = "root"
+
+		assert.ErrorIs(t, svc.UpdateRole(ctx, 1, &models.RoleForm{Role: &role}), ErrInvalidRole)
+	})
+}
+
+var testAccounts = AccountOptions{
+	PasswordResetTTL:         time.Hour,
+	EmailVerificationTTL:     24 * time.Hour,
+	RequireEmailVerification: true,
+	AppURL:                   "http://localhost:8080/",
+	MFAIssuer:                "Ionix",
+	MFAChallengeTTL:          5 * time.Minute,
+	MFARecoveryCodes:         10,
+}
+
+// mailToken returns the token of the link of the mail
+func mailToken(t *testing.T, mail models.Mail, page string) string {
+	var start = strings.Index(mail.Body, "http://localhost:8080/"+page+"?token=")
+	if !assert.GreaterOrEqual(t, start, 0) {
+		return ""
+	}
+	link, err := url.Parse(strings.Fields(mail.Body[start:])[0])
+	assert.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestService_SignInUnverified(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var form = &models.AuthForm{Email: "jhonwick@gmail.com", Password: "123456"}
+	hash, _ := testHasher.Hash(form.Password)
+	repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).
+		Return(&models.User{ID: 1, Email: form.Email, Password: hash}, nil)
+	// the failures are only cleared by a sign in that succeeds
+	repo.EXPECT().FindSignInFailures(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1").Times(1).Return(nil, nil)
+
+	_, err := svc.SignIn(context.Background(), form, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+}
+
+func TestService_SignUp(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	var mails = mailer.NewMemoryMailer()
+	svc := NewAuthService(repo, mails, testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var tokenHash string
+	repo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	repo.EXPECT().FindUserByEmail(gomock.Any(), "jhonwick@gmail.com").Times(1).Return(&models.User{ID: 1, Email: "jhonwick@gmail.com"}, nil)
+	repo.EXPECT().CreateUserToken(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, token *models.UserToken) error {
+			assert.Equal(t, int32(1), token.UserID)
+			assert.Equal(t, models.TokenEmailVerification, token.Purpose)
+			assert.WithinDuration(t, time.Now().Add(24*time.Hour), token.ExpiresAt, time.Minute)
+			tokenHash = token.TokenHash
+			return nil
+		})
+
+	assert.NoError(t, svc.SignUp(context.Background(), &models.RegisterForm{Email: "jhonwick@gmail.com", Password: "Secret123"}))
+	assert.Len(t, mails.Mails(), 1)
+	assert.Equal(t, "jhonwick@gmail.com", mails.Mails()[0].To)
+	// only the hash of the token of the link is stored
+	assert.Equal(t, tokenHash, utils.HashToken(mailToken(t, mails.Mails()[0], "verify-email")))
+}
+
+func TestService_ForgotPassword(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	var mails = mailer.NewMemoryMailer()
+	svc := NewAuthService(repo, mails, testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	t.Run("Send mail", func(t *testing.T) {
+		repo.EXPECT().FindUserByEmail(gomock.Any(), "jhonwick@gmail.com").Times(1).Return(&models.User{ID: 1, Email: "jhonwick@gmail.com"}, nil)
+		repo.EXPECT().CreateUserToken(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, token *models.UserToken) error {
+				assert.Equal(t, models.TokenPasswordReset, token.Purpose)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+				return nil
+			})
+
+		assert.NoError(t, svc.ForgotPassword(context.Background(), &models.EmailForm{Email: "jhonwick@gmail.com"}))
+		assert.Len(t, mails.Mails(), 1)
+		assert.Equal(t, "Restablecer contraseña", mails.Mails()[0].Subject)
+		assert.NotEmpty(t, mailToken(t, mails.Mails()[0], "reset-password"))
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		repo.EXPECT().FindUserByEmail(gomock.Any(), "kratos@gmail.com").Times(1).Return(nil, ErrUserNotFound)
+
+		assert.NoError(t, svc.ForgotPassword(context.Background(), &models.EmailForm{Email: "kratos@gmail.com"}))
+		assert.Len(t, mails.Mails(), 1)
+	})
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	t.Run("Reset password", func(t *testing.T) {
+		var form = &models.ResetPasswordForm{Token: "reset-token", Password: "Secret123"}
+		repo.EXPECT().ResetPassword(gomock.Any(), utils.HashToken(form.Token), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, _ string, hash string) error {
+				// the new password works with the sign in
+				ok, _, err := testHasher.Verify("Secret123", hash)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+				return nil
+			})
+
+		assert.NoError(t, svc.ResetPassword(context.Background(), form))
+	})
+
+	t.Run("Short password", func(t *testing.T) {
+		// the token is kept for a valid password
+		err := svc.ResetPassword(context.Background(), &models.ResetPasswordForm{Token: "reset-token", Password: "Sec123"})
+		var ve models.ValidationErrors
+		assert.ErrorAs(t, err, &ve)
+		assert.Equal(t, "password_min", ve[0].Rule)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		repo.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(ErrTokenExpired)
+
+		err := svc.ResetPassword(context.Background(), &models.ResetPasswordForm{Token: "old-token", Password: "Secret123"})
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+}
+
+func TestService_ResendVerification(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	var mails = mailer.NewMemoryMailer()
+	svc := NewAuthService(repo, mails, testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var verifiedAt = time.Now()
+	repo.EXPECT().FindUserByEmail(gomock.Any(), "jhonwick@gmail.com").Times(1).
+		Return(&models.User{ID: 1, Email: "jhonwick@gmail.com", EmailVerifiedAt: &verifiedAt}, nil)
+
+	// a verified account does not receive mails
+	assert.NoError(t, svc.ResendVerification(context.Background(), &models.EmailForm{Email: "jhonwick@gmail.com"}))
+	assert.Empty(t, mails.Mails())
+}
+
+func TestAccountOptions_retryAfter(t *testing.T) {
+	var options = AccountOptions{SignInMaxFailures: 5, SignInMaxIPFailures: 50, SignInFailureDelay: time.Second, SignInLockout: 15 * time.Minute}
+	var now = time.Now()
+
+	testCases := map[string]struct {
+		failure *models.SignInFailure
+		wait    time.Duration
+	}{
+		"First failure":    {&models.SignInFailure{Scope: models.SignInScopeEmail, Failures: 1, LastFailureAt: now}, time.Second},
+		"Delay doubles":    {&models.SignInFailure{Scope: models.SignInScopeEmail, Failures: 4, LastFailureAt: now}, 8 * time.Second},
+		"Delay passed":     {&models.SignInFailure{Scope: models.SignInScopeEmail, Failures: 2, LastFailureAt: now.Add(-3 * time.Second)}, -time.Second},
+		"Email locked":     {&models.SignInFailure{Scope: models.SignInScopeEmail, Failures: 5, LastFailureAt: now}, 15 * time.Minute},
+		"IP without delay": {&models.SignInFailure{Scope: models.SignInScopeIP, Failures: 49, LastFailureAt: now}, 0},
+		"IP locked":        {&models.SignInFailure{Scope: models.SignInScopeIP, Failures: 50, LastFailureAt: now.Add(-time.Minute)}, 14 * time.Minute},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wait, options.retryAfter(tc.failure, now))
+		})
+	}
+
+	t.Run("Lock disabled", func(t *testing.T) {
+		// without lock the delay keeps growing until the lockout
+		var disabled = options
+		disabled.SignInMaxFailures = 0
+		var failure = &models.SignInFailure{Scope: models.SignInScopeEmail, Failures: 30, LastFailureAt: now}
+		assert.Equal(t, 15*time.Minute, disabled.retryAfter(failure, now))
+	})
+}
+
+func TestService_SignInLocked(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	var options = testAccounts
+	options.SignInMaxFailures, options.SignInFailureDelay, options.SignInLockout = 5, time.Second, 15*time.Minute
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, options)
+
+	t.Run("Locked email", func(t *testing.T) {
+		// the email is compared without case so it can not skip the lock
+		repo.EXPECT().FindSignInFailures(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1").Times(1).
+			Return([]*models.SignInFailure{{Scope: models.SignInScopeEmail, Subject: "jhonwick@gmail.com", Failures: 5, LastFailureAt: time.Now()}}, nil)
+
+		_, err := svc.SignIn(context.Background(), &models.AuthForm{Email: "JhonWick@gmail.com", Password: "123456"}, "10.0.0.1")
+		var locked *LockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		assert.InDelta(t, (15 * time.Minute).Seconds(), locked.RetryAfter.Seconds(), 1)
+	})
+
+	t.Run("Unknown email counts", func(t *testing.T) {
+		repo.EXPECT().FindSignInFailures(gomock.Any(), "kratos@gmail.com", "10.0.0.1").Times(1).Return(nil, nil)
+		repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).Return(nil, ErrUserNotFound)
+		repo.EXPECT().RecordSignInFailure(gomock.Any(), "kratos@gmail.com", "10.0.0.1", 15*time.Minute).Times(1).Return(nil)
+
+		_, err := svc.SignIn(context.Background(), &models.AuthForm{Email: "kratos@gmail.com", Password: "123456"}, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestService_Unlock(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	t.Run("Unlock", func(t *testing.T) {
+		repo.EXPECT().FindUserByID(gomock.Any(), 1).Times(1).Return(&models.User{ID: 1, Email: "JhonWick@gmail.com"}, nil)
+		repo.EXPECT().UnlockAccount(gomock.Any(), 1, "jhonwick@gmail.com").Times(1).Return(nil)
+
+		assert.NoError(t, svc.Unlock(context.Background(), 1))
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		repo.EXPECT().FindUserByID(gomock.Any(), 2).Times(1).Return(nil, ErrUserNotFound)
+
+		assert.ErrorIs(t, svc.Unlock(context.Background(), 2), ErrUserNotFound)
+	})
+}
+
+func TestService_SignInMFA(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var form = &models.AuthForm{Email: "jhonwick@gmail.com", Password: "123456"}
+	hash, _ := testHasher.Hash(form.Password)
+	var now = time.Now()
+	var user = &models.User{ID: 1, Email: form.Email, Password: hash, Role: models.RoleAdmin, EmailVerifiedAt: &now}
+
+	t.Run("Challenge", func(t *testing.T) {
+		repo.EXPECT().FindSignInFailures(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1").Times(1).Return(nil, nil)
+		repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now}, nil)
+		repo.EXPECT().CreateUserToken(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, token *models.UserToken) error {
+				assert.Equal(t, models.TokenMFAChallenge, token.Purpose)
+				assert.WithinDuration(t, time.Now().Add(5*time.Minute), token.ExpiresAt, time.Minute)
+				return nil
+			})
+
+		res, err := svc.SignIn(context.Background(), form, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Empty(t, res.AccessToken)
+		assert.NotEmpty(t, res.MFAToken)
+		assert.Equal(t, 300, res.ExpiresIn)
+		assert.Nil(t, res.MFAEnrollment)
+	})
+
+	t.Run("Enrollment required by the role", func(t *testing.T) {
+		repo.EXPECT().FindSignInFailures(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1").Times(1).Return(nil, nil)
+		repo.EXPECT().FindUserByCredentials(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Required: true}, nil)
+		repo.EXPECT().SaveMFAEnrollment(gomock.Any(), 1, gomock.Any(), gomock.Len(10)).Times(1).Return(nil)
+		repo.EXPECT().CreateUserToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+		res, err := svc.SignIn(context.Background(), form, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.MFAToken)
+		if assert.NotNil(t, res.MFAEnrollment) {
+			assert.Len(t, res.MFAEnrollment.RecoveryCodes, 10)
+			assert.Contains(t, res.MFAEnrollment.URI, "secret="+res.MFAEnrollment.Secret)
+		}
+	})
+}
+
+// rfcSecret base32 secret of the test vectors of RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestService_VerifyMFA(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var now = time.Now()
+	var challenge = &models.UserToken{ID: 3, UserID: 1, Purpose: models.TokenMFAChallenge}
+	var user = &models.User{ID: 1, Email: "JhonWick@gmail.com", Role: models.RoleAdmin}
+	code, _ := totp.Code(rfcSecret, totp.Step(now))
+	var expectChallenge = func(mfa *models.UserMFA) {
+		repo.EXPECT().FindUserToken(gomock.Any(), utils.HashToken("mfa-token"), models.TokenMFAChallenge).Times(1).Return(challenge, nil)
+		repo.EXPECT().FindUserByID(gomock.Any(), 1).Times(1).Return(user, nil)
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(mfa, nil)
+		repo.EXPECT().FindSignInFailures(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1").Times(1).Return(nil, nil)
+	}
+
+	t.Run("Code", func(t *testing.T) {
+		expectChallenge(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now})
+		repo.EXPECT().UseMFAStep(gomock.Any(), 1, totp.Step(now)).Times(1).Return(nil)
+		repo.EXPECT().UseUserToken(gomock.Any(), int64(3)).Times(1).Return(nil)
+		repo.EXPECT().ClearSignInFailures(gomock.Any(), "jhonwick@gmail.com").Times(1).Return(nil)
+		repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+		res, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: code}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+		assert.NotEmpty(t, res.RefreshToken)
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		expectChallenge(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now})
+		repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, utils.HashRecoveryCode("abcd-efgh-ijkl-mnop")).Times(1).Return(nil)
+		repo.EXPECT().UseUserToken(gomock.Any(), int64(3)).Times(1).Return(nil)
+		repo.EXPECT().ClearSignInFailures(gomock.Any(), "jhonwick@gmail.com").Times(1).Return(nil)
+		repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+		// the case and the separators of the recovery codes do not matter
+		res, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "ABCDEFGH IJKLMNOP"}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+	})
+
+	t.Run("Confirms the enrollment", func(t *testing.T) {
+		expectChallenge(&models.UserMFA{UserID: 1, Secret: rfcSecret, Required: true})
+		repo.EXPECT().EnableMFA(gomock.Any(), 1, totp.Step(now)).Times(1).Return(nil)
+		repo.EXPECT().UseUserToken(gomock.Any(), int64(3)).Times(1).Return(nil)
+		repo.EXPECT().ClearSignInFailures(gomock.Any(), "jhonwick@gmail.com").Times(1).Return(nil)
+		repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+		_, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: code}, "10.0.0.1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong code counts as failure", func(t *testing.T) {
+		expectChallenge(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now})
+		repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, gomock.Any()).Times(1).Return(ErrInvalidMFACode)
+		repo.EXPECT().RecordSignInFailure(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1", gomock.Any()).Times(1).Return(nil)
+
+		_, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: "wrong-code"}, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	})
+
+	t.Run("Reused code", func(t *testing.T) {
+		expectChallenge(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now})
+		repo.EXPECT().UseMFAStep(gomock.Any(), 1, totp.Step(now)).Times(1).Return(ErrInvalidMFACode)
+		repo.EXPECT().RecordSignInFailure(gomock.Any(), "jhonwick@gmail.com", "10.0.0.1", gomock.Any()).Times(1).Return(nil)
+
+		_, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: code}, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	})
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		repo.EXPECT().FindUserToken(gomock.Any(), utils.HashToken("mfa-token"), models.TokenMFAChallenge).Times(1).Return(nil, ErrTokenExpired)
+
+		_, err := svc.VerifyMFA(context.Background(), &models.MFAVerifyForm{MFAToken: "mfa-token", Code: code}, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+}
+
+func TestService_ConfirmMFA(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var now = time.Now()
+	code, _ := totp.Code(rfcSecret, totp.Step(now))
+
+	t.Run("Enroll and confirm", func(t *testing.T) {
+		var secret string
+		repo.EXPECT().FindUserByID(gomock.Any(), 1).Times(1).Return(&models.User{ID: 1, Email: "jhonwick@gmail.com"}, nil)
+		repo.EXPECT().SaveMFAEnrollment(gomock.Any(), 1, gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(_ context.Context, _ int, s string, hashes []string) error {
+				secret = s
+				return nil
+			})
+
+		enrollment, err := svc.EnrollMFA(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, secret, enrollment.Secret)
+
+		code, _ := totp.Code(secret, totp.Step(now))
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: secret}, nil)
+		repo.EXPECT().EnableMFA(gomock.Any(), 1, totp.Step(now)).Times(1).Return(nil)
+		assert.NoError(t, svc.ConfirmMFA(context.Background(), 1, &models.MFACodeForm{Code: code}))
+	})
+
+	t.Run("Recovery codes do not confirm", func(t *testing.T) {
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: rfcSecret}, nil)
+		assert.ErrorIs(t, svc.ConfirmMFA(context.Background(), 1, &models.MFACodeForm{Code: "abcd-efgh-ijkl-mnop"}), ErrInvalidMFACode)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now}, nil)
+		assert.ErrorIs(t, svc.ConfirmMFA(context.Background(), 1, &models.MFACodeForm{Code: code}), ErrMFAAlreadyEnabled)
+	})
+}
+
+func TestService_DisableMFA(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var now = time.Now()
+	code, _ := totp.Code(rfcSecret, totp.Step(now))
+
+	t.Run("Disable", func(t *testing.T) {
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now}, nil)
+		repo.EXPECT().UseMFAStep(gomock.Any(), 1, totp.Step(now)).Times(1).Return(nil)
+		repo.EXPECT().DeleteMFA(gomock.Any(), 1).Times(1).Return(nil)
+		assert.NoError(t, svc.DisableMFA(context.Background(), 1, &models.MFACodeForm{Code: code}))
+	})
+
+	t.Run("Required by the role", func(t *testing.T) {
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1, Secret: rfcSecret, EnabledAt: &now, Required: true}, nil)
+		assert.ErrorIs(t, svc.DisableMFA(context.Background(), 1, &models.MFACodeForm{Code: code}), ErrMFARequired)
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		repo.EXPECT().FindMFA(gomock.Any(), 1).Times(1).Return(&models.UserMFA{UserID: 1}, nil)
+		assert.ErrorIs(t, svc.DisableMFA(context.Background(), 1, &models.MFACodeForm{Code: code}), ErrMFANotEnrolled)
+	})
+}
+
+func TestService_UpdateRoleMFA(t *testing.T) {
+	t.Parallel()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockAuthRepository(mockCtrl)
+	svc := NewAuthService(repo, mailer.NewMemoryMailer(), testHasher, testPolicy, testSigner, zap.NewNop(), 5*time.Second, time.Hour, testAccounts)
+
+	var required = true
+	repo.EXPECT().UpdateRoleMFA(gomock.Any(), models.RoleClinician, true).Times(1).Return(nil)
+	assert.NoError(t, svc.UpdateRoleMFA(context.Background(), "clinician", &models.RoleMFAForm{Required: &required}))
+	assert.ErrorIs(t, svc.UpdateRoleMFA(context.Background(), "root", &models.RoleMFAForm{Required: &required}), ErrInvalidRole)
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"time"
+)
+
+var _ interfaces.AuthService = (*tracedService)(nil)
+
+// tracedService starts a span per method of the service, credentials and tokens are never added to the spans
+type tracedService struct {
+	next interfaces.AuthService
+}
+
+// NewTracedAuthService wraps an auth service with spans
+func NewTracedAuthService(next interfaces.AuthService) *tracedService {
+	return &tracedService{next: next}
+}
+
+func (s tracedService) SignIn(ctx context.Context, form *models.AuthForm, ip string) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SignIn")
+	res, err := s.next.SignIn(ctx, form, ip)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s tracedService) SignUp(ctx context.Context, form *models.RegisterForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.SignUp")
+	err := s.next.SignUp(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) Refresh(ctx context.Context, form *models.RefreshTokenForm) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Refresh")
+	res, err := s.next.Refresh(ctx, form)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s tracedService) UpdateRole(ctx context.Context, userId int, form *models.RoleForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.UpdateRole", attribute.Int("user.id", userId))
+	err := s.next.UpdateRole(ctx, userId, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) Logout(ctx context.Context, userId int, jti string, expiresAt time.Time, form *models.RefreshTokenForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout", attribute.Int("user.id", userId))
+	err := s.next.Logout(ctx, userId, jti, expiresAt, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ForgotPassword(ctx context.Context, form *models.EmailForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ForgotPassword")
+	err := s.next.ForgotPassword(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ResetPassword(ctx context.Context, form *models.ResetPasswordForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetPassword")
+	err := s.next.ResetPassword(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) VerifyEmail(ctx context.Context, form *models.VerifyEmailForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyEmail")
+	err := s.next.VerifyEmail(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ResendVerification(ctx context.Context, form *models.EmailForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ResendVerification")
+	err := s.next.ResendVerification(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) Unlock(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Unlock", attribute.Int("user.id", userId))
+	err := s.next.Unlock(ctx, userId)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) VerifyMFA(ctx context.Context, form *models.MFAVerifyForm, ip string) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyMFA")
+	res, err := s.next.VerifyMFA(ctx, form, ip)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s tracedService) EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.EnrollMFA", attribute.Int("user.id", userId))
+	res, err := s.next.EnrollMFA(ctx, userId)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s tracedService) ConfirmMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ConfirmMFA", attribute.Int("user.id", userId))
+	err := s.next.ConfirmMFA(ctx, userId, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) DisableMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.DisableMFA", attribute.Int("user.id", userId))
+	err := s.next.DisableMFA(ctx, userId, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ResetMFA(ctx context.Context, userId int) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetMFA", attribute.Int("user.id", userId))
+	err := s.next.ResetMFA(ctx, userId)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) UpdateRoleMFA(ctx context.Context, role string, form *models.RoleMFAForm) error {
+	ctx, span := tracing.Start(ctx, "AuthService.UpdateRoleMFA", attribute.String("role", role))
+	err := s.next.UpdateRoleMFA(ctx, role, form)
+	tracing.End(span, err)
+	return err
+}
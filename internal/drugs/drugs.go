@@ -7,19 +7,20 @@ import (
 	"github.com/unrolled/render"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
 	"time"
 )
 
 // Module auth
 var Module = fx.Module("drugs",
-	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate) error {
+	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) error {
 		// loads repository
-		var repo = NewDrugRepository(conn, logger)
+		var repo = NewInstrumentedDrugRepository(NewDrugRepository(conn, logger))
 		// loads service
-		var svc = NewDrugService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+		var svc = NewTracedDrugService(NewDrugService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second, time.Duration(cfg.ImportTimeout)*time.Second))
 		// loads handlers
-		NewDrugHandlers(r, logger, svc, render, validate)
+		NewDrugHandlers(r, logger, svc, render, validate, denylist, signer)
 		return nil
 	}),
 )
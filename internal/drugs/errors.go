@@ -1,6 +1,10 @@
 package drugs
 
-import "errors"
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
 
 // Entity Errors
 var (
@@ -20,4 +24,18 @@ var (
 	ErrUpdatingRecord     = errors.New("failed to update record")
 	ErrDeletingRecord     = errors.New("failed to delete record")
 	ErrInvalidRequestBody = errors.New("El cuerpo de la petición es invalido")
+	ErrScheduleNotFound   = errors.New("El medicamento no tiene un esquema de dosis")
+	ErrVersionConflict    = errors.New("El medicamento fue modificado por otra petición")
 )
+
+// problems returned to the clients, the errors without entry are internal errors
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrTimeout, Code: "timeout", Status: http.StatusGatewayTimeout},
+		problem.Entry{Err: ErrDrugNotFound, Code: "drug_not_found", Status: http.StatusNotFound},
+		problem.Entry{Err: ErrDuplicateDrug, Code: "drug_exists", Status: http.StatusConflict},
+		problem.Entry{Err: ErrScheduleNotFound, Code: "drug_schedule_not_found", Status: http.StatusNotFound},
+		problem.Entry{Err: ErrVersionConflict, Code: "drug_modified", Status: http.StatusPreconditionFailed},
+		problem.Entry{Err: ErrInvalidRequestBody, Code: "invalid_body", Status: http.StatusBadRequest},
+	)
+}
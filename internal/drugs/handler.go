@@ -1,26 +1,29 @@
 package drugs
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/export"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/importer"
+	"kiramishima/ionix/internal/pkg/patch"
+	"kiramishima/ionix/internal/pkg/problem"
+	"kiramishima/ionix/internal/pkg/timezone"
 	httpUtils "kiramishima/ionix/internal/pkg/utils"
 	"net/http"
-	"os"
-	"strconv"
+	"time"
 )
 
 var _ impl.DrugsHandlers = (*handler)(nil)
 
 // NewDrugHandlers creates an instance of drug handlers
-func NewDrugHandlers(r *chi.Mux, logger *zap.Logger, s impl.DrugService, render *render.Render, validate *validator.Validate) {
-	var tokenAuth = jwtauth.New("HS256", []byte(os.Getenv("JWT_PRIVATE_KEY")), nil)
-	// logger.Info("token ->", tokenAuth)
+func NewDrugHandlers(r *chi.Mux, logger *zap.Logger, s impl.DrugService, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) {
 	handler := &handler{
 		logger:   logger,
 		service:  s,
@@ -29,14 +32,34 @@ func NewDrugHandlers(r *chi.Mux, logger *zap.Logger, s impl.DrugService, render
 	}
 
 	r.Route("/v1/drugs", func(r chi.Router) {
-		r.Use(jwtauth.Verifier(tokenAuth))
-		r.Use(jwtauth.Authenticator(tokenAuth))
+		r.Use(signer.Verifier)
+		r.Use(httpUtils.Authenticator)
+		r.Use(httpUtils.Denylist(denylist))
 
-		r.Get("/", handler.ListDrugsHandler)
-		r.Post("/", handler.CreateDrugHandler)
-		r.Put("/{id}", handler.UpdateDrugHandler)
-		r.Delete("/{id}", handler.DeleteDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsRead)).Get("/", handler.ListDrugsHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsRead)).Get("/{id}", handler.GetDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsWrite)).Post("/", handler.CreateDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsWrite)).Put("/{id}", handler.UpdateDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsWrite)).Patch("/{id}", handler.PatchDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsWrite)).Delete("/{id}", handler.DeleteDrugHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsRead)).Get("/{id}/schedule", handler.GetDrugScheduleHandler)
+		r.With(httpUtils.Authorize(models.PermDrugsWrite)).Put("/{id}/schedule", handler.SetDrugScheduleHandler)
 	})
+	// custom methods of the collection, outside of the /v1/drugs/ sub router
+	r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermDrugsWrite)).
+		Post("/v1/drugs:import", handler.ImportDrugsHandler)
+	r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermDrugsRead)).
+		Get("/v1/drugs:export", handler.ExportDrugsHandler)
+}
+
+// drugColumns columns of the CSV and XLSX exports
+var drugColumns = []export.Column[models.Drug]{
+	{Name: "id", Value: func(d *models.Drug) any { return d.ID }},
+	{Name: "name", Value: func(d *models.Drug) any { return d.Name }},
+	{Name: "approved", Value: func(d *models.Drug) any { return d.Approved }},
+	{Name: "min_dose", Value: func(d *models.Drug) any { return d.MinDose }},
+	{Name: "max_dose", Value: func(d *models.Drug) any { return d.MaxDose }},
+	{Name: "available_at", Value: func(d *models.Drug) any { return d.AvailableAt }},
 }
 
 type handler struct {
@@ -47,33 +70,114 @@ type handler struct {
 }
 
 func (h handler) ListDrugsHandler(w http.ResponseWriter, req *http.Request) {
+	// query string
+	query, err := models.ParseDrugQuery(req.URL.Query())
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
 	// context
 	ctx := req.Context()
 	// Call Service
-	resp, err := h.service.GetListDrugs(ctx)
+	resp, total, err := h.service.GetListDrugs(ctx, query)
 	h.logger.Info("[INFO]", zap.Any("SVC_RESPONSE", resp))
 
 	if err != nil {
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "El tiempo para procesar su petición ha excedido"})
-		default:
-			h.logger.Info(err.Error())
-			if errors.Is(err, ErrNoRecords) {
-				_ = h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Drug]{Data: make([]*models.Drug, 0)})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Error al procesar su petición"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error interno. Por favor intente más tarde"})
-			}
+		h.logger.Info(err.Error())
+		if errors.Is(err, ErrNoRecords) {
+			_ = h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Drug]{
+				Data:  make([]*models.Drug, 0),
+				Meta:  query.Meta(0),
+				Links: httpUtils.PaginationLinks(req, query.Page, 0),
+			})
+			return
 		}
+		problem.Write(w, req, err)
+		return
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	for _, drug := range resp {
+		drug.In(loc)
+	}
+
+	var body = models.ResponseWrapper[[]*models.Drug]{
+		Data:  resp,
+		Meta:  query.Meta(total),
+		Links: httpUtils.PaginationLinks(req, query.Page, query.TotalPages(total)),
+	}
+	if httpUtils.NotModified(w, req, httpUtils.ContentETag(body)) {
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Drug]{Data: resp}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, body); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
 
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error interno. Por favor intente más tarde"})
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+// ExportDrugsHandler streams every drug matching the filters of the list in the negotiated format
+func (h handler) ExportDrugsHandler(w http.ResponseWriter, req *http.Request) {
+	query, err := models.ParseDrugQuery(req.URL.Query())
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	format, err := export.Negotiate(req)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// the export is not bound to the write timeout of the server
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	var writer = export.NewWriter(w, format, "drugs", drugColumns)
+	err = h.service.ExportDrugs(req.Context(), query, func(drug *models.Drug) error {
+		return writer.Write(drug.In(loc))
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		if !writer.Started() {
+			problem.Write(w, req, err)
+			return
+		}
+		// the status was sent, the client must not take the truncated file as complete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h handler) GetDrugHandler(w http.ResponseWriter, req *http.Request) {
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	resp, err := h.service.GetDrug(ctx, DrugID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	if httpUtils.NotModified(w, req, httpUtils.ETag(resp.Version)) {
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.Drug]{Data: resp.In(timezone.FromContext(req.Context()))}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
 		return
 	}
 }
@@ -85,7 +189,7 @@ func (h handler) CreateDrugHandler(w http.ResponseWriter, req *http.Request) {
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidRequestBody.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 	h.logger.Info("[INFO]", zap.Any("form", form))
@@ -93,7 +197,7 @@ func (h handler) CreateDrugHandler(w http.ResponseWriter, req *http.Request) {
 	err = form.Validate(h.validate)
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: err.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 	// context
@@ -102,98 +206,268 @@ func (h handler) CreateDrugHandler(w http.ResponseWriter, req *http.Request) {
 	err = h.service.NewDrug(ctx, form)
 	if err != nil {
 		// h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
 
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrDuplicateDrug) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este medicamento ya se ha dado de alta con anterioridad"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "drug.created")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ImportDrugsHandler(w http.ResponseWriter, req *http.Request) {
+	format, err := importer.ParseFormat(req.Header.Get("Content-Type"))
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	opts, err := models.ParseImportOptions(req.URL.Query())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	// the upload and the import are not bound to the timeouts of the server, the service has IMPORT_TIMEOUT
+	var rc = http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+
+	rows, err := importer.Decode[models.DrugForm](format, http.MaxBytesReader(w, req.Body, importer.MaxBytes))
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha registrado el nuevo medicamento de manera exitosa"}); err != nil {
+	validate := func(form *models.DrugForm) error { return form.Validate(h.validate) }
+	report, err := importer.Run(req, rows, opts, validate, h.service.ImportDrugs)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	h.logger.Info("[INFO]", zap.Any("report", report))
+
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.ImportReport]{Data: report}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
 
 func (h handler) UpdateDrugHandler(w http.ResponseWriter, req *http.Request) {
-	var DrugID, _ = strconv.ParseInt(chi.URLParam(req, "id"), 10, 10)
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
 	var form = &models.DrugForm{}
 
-	err := httpUtils.ReadJSON(w, req, &form)
+	err = httpUtils.ReadJSON(w, req, &form)
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidRequestBody.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 
 	h.logger.Info("[INFO]", zap.Any("form", form))
+	// PUT replaces the whole drug
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
 	// context
 	ctx := req.Context()
 
-	err = h.service.UpdateDrug(ctx, int(DrugID), form)
+	err = h.service.UpdateDrug(ctx, DrugID, version, form)
 	if err != nil {
 		// h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// with If-Match: * the new version is unknown
+	if version != 0 {
+		w.Header().Set("ETag", httpUtils.ETag(version+1))
+	}
 
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrDuplicateDrug) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este medicamento ya se ha dado de alta con anterioridad"})
-			} else if errors.Is(err, ErrDrugNotFound) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este medicamento no existe"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "drug.updated")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) PatchDrugHandler(w http.ResponseWriter, req *http.Request) {
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	patchType, err := patch.ParseType(req.Header.Get("Content-Type"))
+	if err != nil {
+		w.Header().Set("Accept-Patch", patch.Accept)
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
+	body, err := httpUtils.ReadBody(w, req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha actualizado la información del medicamento de manera exitosa"}); err != nil {
+	drug, err := h.service.GetDrug(ctx, DrugID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// the patch is applied to this version, the update fails if the drug changes in between
+	if version == 0 {
+		version = drug.Version
+	} else if version != drug.Version {
+		problem.Write(w, req, ErrVersionConflict)
+		return
+	}
+
+	doc, err := json.Marshal(drug.Form())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	doc, err = patchType.Apply(doc, body)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.DrugForm{}
+	if err = httpUtils.DecodeJSON(doc, form); err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	h.logger.Info("[INFO]", zap.Any("form", form))
+	// the patched drug must be valid as a whole
+	if err = form.Validate(h.validate); err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	err = h.service.UpdateDrug(ctx, DrugID, version, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	w.Header().Set("ETag", httpUtils.ETag(version+1))
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "drug.updated")}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
 
 func (h handler) DeleteDrugHandler(w http.ResponseWriter, req *http.Request) {
-	var DrugID, _ = strconv.ParseInt(chi.URLParam(req, "id"), 10, 10)
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
 	// context
 	ctx := req.Context()
 
-	err := h.service.DeleteDrug(ctx, int(DrugID))
-	if err != nil {
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrDrugNotFound) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este medicamento no existe"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	err = h.service.DeleteDrug(ctx, DrugID, version)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "drug.deleted")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) GetDrugScheduleHandler(w http.ResponseWriter, req *http.Request) {
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	resp, err := h.service.GetDrugSchedule(ctx, DrugID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.DrugSchedule]{Data: resp}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) SetDrugScheduleHandler(w http.ResponseWriter, req *http.Request) {
+	DrugID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.DrugScheduleForm{}
+
+	err = httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate form
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	err = h.service.SetDrugSchedule(ctx, DrugID, form)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha eliminado el medicamento de manera exitosa"}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "drug.schedule_updated")}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
@@ -1,26 +1,31 @@
 package drugs
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/validator/v10"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/unrolled/render"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 	"kiramishima/ionix/internal/mocks"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/signer"
+	"kiramishima/ionix/internal/pkg/timezone"
 	"kiramishima/ionix/internal/pkg/utils"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
 
-func TestHandler_ListDrugsHandler(t *testing.T) {
-	t.Setenv("JWT_PRIVATE_KEY", "Megaman")
-	t.Setenv("TOKEN_TTL", "3600")
+// testSigner signs the access tokens of the requests
+var testSigner, _ = signer.New(models.JWT{JWTPrivateKey: "Megaman", TokenTTL: 3600})
 
+func TestHandler_ListDrugsHandler(t *testing.T) {
 	testCases := map[string]struct {
 		ID            any
 		buildStubs    func(uc *mocks.MockDrugService)
@@ -49,8 +54,8 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 				}
 
 				uc.EXPECT().
-					GetListDrugs(gomock.Any()).
-					Return(drugs, nil).
+					GetListDrugs(gomock.Any(), gomock.Any()).
+					Return(drugs, len(drugs), nil).
 					AnyTimes()
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -63,8 +68,8 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 			ID: 2,
 			buildStubs: func(uc *mocks.MockDrugService) {
 				uc.EXPECT().
-					GetListDrugs(gomock.Any()).
-					Return(nil, ErrNoRecords).
+					GetListDrugs(gomock.Any(), gomock.Any()).
+					Return(nil, 0, ErrNoRecords).
 					AnyTimes()
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -88,7 +93,7 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 
 			// Generate Token
 
-			token, _ := utils.GenerateJWT(&models.User{ID: 2})
+			token, _ := testSigner.Sign(&models.User{ID: 2})
 			jwtToken := fmt.Sprintf("Bearer %s", token)
 			t.Log("JWT -> ", jwtToken)
 			h := http.Header{}
@@ -103,10 +108,10 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 
 			router := chi.NewRouter()
 			logger := zap.NewNop()
-			validate := validator.New()
+			validate := models.NewValidator()
 			r := render.New()
 
-			NewDrugHandlers(router, logger, uc, r, validate)
+			NewDrugHandlers(router, logger, uc, r, validate, mocks.NewMockTokenDenylist(ctrl), testSigner)
 
 			// router.ServeHTTP(recorder, request)
 			ts := httptest.NewServer(router)
@@ -116,3 +121,713 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_GetDrugHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		ID            string
+		IfNoneMatch   string
+		buildStubs    func(uc *mocks.MockDrugService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Getting Data": {
+			ID: "1",
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().
+					GetDrug(gomock.Any(), 1).
+					Times(1).
+					Return(&models.Drug{ID: 1, Name: "medicament 1", Approved: true, MinDose: 1, MaxDose: 5, Version: 3}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+				assert.Contains(t, recorder.Body.String(), `"name":"medicament 1"`)
+			},
+		},
+		"Not modified": {
+			ID:          "1",
+			IfNoneMatch: `"2", W/"3"`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().
+					GetDrug(gomock.Any(), 1).
+					Times(1).
+					Return(&models.Drug{ID: 1, Name: "medicament 1", Approved: true, MinDose: 1, MaxDose: 5, Version: 3}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotModified, recorder.Code)
+				assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+				assert.Empty(t, recorder.Body.String())
+			},
+		},
+		"Modified": {
+			ID:          "1",
+			IfNoneMatch: `"2"`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().
+					GetDrug(gomock.Any(), 1).
+					Times(1).
+					Return(&models.Drug{ID: 1, Name: "medicament 1", Approved: true, MinDose: 1, MaxDose: 5, Version: 3}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"name":"medicament 1"`)
+			},
+		},
+		"Not found": {
+			ID: "2",
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().
+					GetDrug(gomock.Any(), 2).
+					Times(1).
+					Return(nil, ErrDrugNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		"Invalid ID": {
+			ID:         "abc",
+			buildStubs: func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Equal(t, `{"type":"/problems/invalid_id","title":"Identificador invalido","status":400,"detail":"El identificador es invalido","instance":"/v1/drugs/abc","code":"invalid_id"}`+"\n", recorder.Body.String())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockDrugService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/v1/drugs/"+tc.ID, nil)
+			if tc.IfNoneMatch != "" {
+				request.Header.Set("If-None-Match", tc.IfNoneMatch)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.ID)
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			h.GetDrugHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_DeleteDrugHandler_Permissions(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		Role          models.Role
+		buildStubs    func(uc *mocks.MockDrugService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Admin": {
+			Role: models.RoleAdmin,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().DeleteDrug(gomock.Any(), 1, 4).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		"Clinician": {
+			Role:       models.RoleClinician,
+			buildStubs: func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusForbidden, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), "drugs:write")
+			},
+		},
+		"Read only": {
+			Role:       models.RoleReadOnly,
+			buildStubs: func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	var tokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockDrugService(ctrl)
+			tc.buildStubs(uc)
+
+			token, _, err := tokenAuth.Encode(map[string]interface{}{"sub": "1", "role": string(tc.Role)})
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/v1/drugs/1", nil)
+			request.Header.Set("If-Match", `"4"`)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+			request = request.WithContext(jwtauth.NewContext(ctx, token, nil))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			utils.Authorize(models.PermDrugsWrite)(http.HandlerFunc(h.DeleteDrugHandler)).ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_CreateDrugHandler_Validation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+
+	recorder := httptest.NewRecorder()
+	body := `{"name":"medicament 1","approved":true,"min_dose":0,"max_dose":-2,"available_at":"2024-05-05T13:50:00-06:00"}`
+	request := httptest.NewRequest(http.MethodPost, "/v1/drugs", strings.NewReader(body))
+
+	validate := models.NewValidator()
+	assert.NoError(t, i18n.RegisterValidator(validate))
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: validate,
+	}
+	h.CreateDrugHandler(recorder, request)
+
+	// every failed rule is reported in the same response
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, `{"type":"/problems/validation_failed","title":"Los datos de la petición son invalidos","status":422,"detail":"min_dose: min_dose debe ser mayor que 0; max_dose: max_dose debe ser mayor que 0; max_dose: El valor no puede ser menor que min_dose","instance":"/v1/drugs","code":"validation_failed","errors":[{"field":"min_dose","message":"min_dose debe ser mayor que 0","rule":"gt","param":"0"},{"field":"max_dose","message":"max_dose debe ser mayor que 0","rule":"gt","param":"0"},{"field":"max_dose","message":"El valor no puede ser menor que min_dose","rule":"gtefield","param":"min_dose"}]}`+"\n", recorder.Body.String())
+}
+
+func TestHandler_CreateDrugHandler_InvalidDateTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+
+	recorder := httptest.NewRecorder()
+	body := `{"name":"medicament 1","approved":true,"min_dose":1,"max_dose":2,"available_at":"2024-13-01"}`
+	request := httptest.NewRequest(http.MethodPost, "/v1/drugs", strings.NewReader(body))
+
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: models.NewValidator(),
+	}
+	h.CreateDrugHandler(recorder, request)
+
+	// the dates are validated when the body is decoded
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, `{"type":"/problems/invalid_body","title":"Cuerpo de la petición invalido","status":400,"detail":"El cuerpo de la petición es invalido: invalid date-time \"2024-13-01\", use RFC 3339","instance":"/v1/drugs","code":"invalid_body"}`+"\n", recorder.Body.String())
+}
+
+func TestHandler_GetDrugHandler_TimeZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+	uc.EXPECT().
+		GetDrug(gomock.Any(), 1).
+		Times(1).
+		Return(&models.Drug{ID: 1, Name: "medicament 1", Approved: true, MinDose: 1, MaxDose: 5, AvailableAt: time.Date(2024, 5, 5, 19, 50, 0, 0, time.UTC)}, nil)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/v1/drugs/1", nil)
+	request.Header.Set(timezone.Header, "America/Mexico_City")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: models.NewValidator(),
+	}
+	timezone.Middleware(time.UTC)(http.HandlerFunc(h.GetDrugHandler)).ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"data":{"id":1,"name":"medicament 1","approved":true,"min_dose":1,"max_dose":5,"available_at":"2024-05-05T13:50:00-06:00"}}`, recorder.Body.String())
+}
+
+func TestHandler_UpdateDrugHandler_Preconditions(t *testing.T) {
+	t.Parallel()
+	const body = `{"name":"Aspirina","approved":true,"min_dose":1,"max_dose":2,"available_at":"2024-05-05T00:00:00Z"}`
+	testCases := map[string]struct {
+		IfMatch       string
+		buildStubs    func(uc *mocks.MockDrugService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Updated": {
+			IfMatch: `"3"`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().UpdateDrug(gomock.Any(), 1, 3, gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+			},
+		},
+		"Any version": {
+			IfMatch: "*",
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().UpdateDrug(gomock.Any(), 1, 0, gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Empty(t, recorder.Header().Get("ETag"))
+			},
+		},
+		"Without If-Match": {
+			buildStubs: func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+				assert.Equal(t, `{"type":"/problems/precondition_required","title":"Precondición requerida","status":428,"detail":"Se requiere el encabezado If-Match","instance":"/v1/drugs/1","code":"precondition_required"}`+"\n", recorder.Body.String())
+			},
+		},
+		"Weak ETag": {
+			IfMatch:    `W/"3"`,
+			buildStubs: func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"code":"precondition_failed"`)
+			},
+		},
+		"Stale version": {
+			IfMatch: `"2"`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().UpdateDrug(gomock.Any(), 1, 2, gomock.Any()).Times(1).Return(ErrVersionConflict)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+				assert.Equal(t, `{"type":"/problems/drug_modified","title":"Medicamento modificado","status":412,"detail":"El medicamento fue modificado por otra petición","instance":"/v1/drugs/1","code":"drug_modified"}`+"\n", recorder.Body.String())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockDrugService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/v1/drugs/1", strings.NewReader(body))
+			if tc.IfMatch != "" {
+				request.Header.Set("If-Match", tc.IfMatch)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			h.UpdateDrugHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_ListDrugsHandler_NotModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+	uc.EXPECT().
+		GetListDrugs(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return([]*models.Drug{{ID: 1, Name: "medicament 1", Approved: true, MinDose: 1, MaxDose: 5, AvailableAt: time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)}}, 1, nil)
+
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: models.NewValidator(),
+	}
+
+	recorder := httptest.NewRecorder()
+	h.ListDrugsHandler(recorder, httptest.NewRequest(http.MethodGet, "/v1/drugs", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/drugs", nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	h.ListDrugsHandler(recorder, request)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestHandler_UpdateDrugHandler_Validation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+
+	// PUT replaces the drug, every field is required
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/v1/drugs/1", strings.NewReader(`{"name":"Aspirina"}`))
+	request.Header.Set("If-Match", `"1"`)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: models.NewValidator(),
+	}
+	h.UpdateDrugHandler(recorder, request)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `{"field":"approved","message":"This field is required","rule":"required"}`)
+	assert.Contains(t, recorder.Body.String(), `{"field":"available_at","message":"This field is required","rule":"required"}`)
+}
+
+func TestHandler_PatchDrugHandler(t *testing.T) {
+	t.Parallel()
+	var current = &models.Drug{ID: 1, Name: "Aspirina", Approved: true, MinDose: 1, MaxDose: 5, AvailableAt: time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), Version: 3}
+	testCases := map[string]struct {
+		ContentType   string
+		IfMatch       string
+		Body          string
+		buildStubs    func(uc *mocks.MockDrugService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Merge patch": {
+			ContentType: "application/merge-patch+json",
+			IfMatch:     `"3"`,
+			Body:        `{"approved":false,"max_dose":4}`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+				uc.EXPECT().UpdateDrug(gomock.Any(), 1, 3, gomock.Any()).Times(1).DoAndReturn(func(_ context.Context, _ int, _ int, form *models.DrugForm) error {
+					assert.Equal(t, "Aspirina", *form.Name)
+					assert.False(t, *form.Approved)
+					assert.Equal(t, 1, *form.MinDose)
+					assert.Equal(t, 4, *form.MaxDose)
+					assert.True(t, current.AvailableAt.Equal(form.AvailableAt.Time))
+					return nil
+				})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+			},
+		},
+		"JSON patch on any version": {
+			ContentType: "application/json-patch+json",
+			IfMatch:     "*",
+			Body:        `[{"op":"test","path":"/name","value":"Aspirina"},{"op":"replace","path":"/name","value":"Cafiaspirina"}]`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+				uc.EXPECT().UpdateDrug(gomock.Any(), 1, 3, gomock.Any()).Times(1).DoAndReturn(func(_ context.Context, _ int, _ int, form *models.DrugForm) error {
+					assert.Equal(t, "Cafiaspirina", *form.Name)
+					return nil
+				})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+			},
+		},
+		"Null removes a required field": {
+			ContentType: "application/merge-patch+json",
+			IfMatch:     `"3"`,
+			Body:        `{"name":null,"min_dose":6}`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `{"field":"name","message":"This field is required","rule":"required"}`)
+				assert.Contains(t, recorder.Body.String(), `"field":"max_dose"`)
+			},
+		},
+		"Unknown field": {
+			ContentType: "application/merge-patch+json",
+			IfMatch:     `"3"`,
+			Body:        `{"color":"red"}`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"code":"invalid_body"`)
+			},
+		},
+		"Test failed": {
+			ContentType: "application/json-patch+json",
+			IfMatch:     `"3"`,
+			Body:        `[{"op":"test","path":"/name","value":"Cafiaspirina"}]`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusConflict, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"code":"patch_test_failed"`)
+			},
+		},
+		"Stale version": {
+			ContentType: "application/merge-patch+json",
+			IfMatch:     `"2"`,
+			Body:        `{"max_dose":4}`,
+			buildStubs: func(uc *mocks.MockDrugService) {
+				uc.EXPECT().GetDrug(gomock.Any(), 1).Times(1).Return(current, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"code":"drug_modified"`)
+			},
+		},
+		"Unsupported media type": {
+			ContentType: "application/json",
+			IfMatch:     `"3"`,
+			Body:        `{"max_dose":4}`,
+			buildStubs:  func(uc *mocks.MockDrugService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+				assert.Equal(t, "application/merge-patch+json, application/json-patch+json", recorder.Header().Get("Accept-Patch"))
+				assert.Equal(t, `{"type":"/problems/unsupported_media_type","title":"Tipo de contenido no soportado","status":415,"detail":"El tipo de contenido no es un documento de parche soportado: application/json","instance":"/v1/drugs/1","code":"unsupported_media_type"}`+"\n", recorder.Body.String())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockDrugService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPatch, "/v1/drugs/1", strings.NewReader(tc.Body))
+			request.Header.Set("Content-Type", tc.ContentType)
+			request.Header.Set("If-Match", tc.IfMatch)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			h.PatchDrugHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_ImportDrugsHandler(t *testing.T) {
+	const file = "name,approved,min_dose,max_dose,available_at\n" +
+		"Aspirina,true,1,5,2024-05-05T00:00:00Z\n" +
+		"Cafiaspirina,true,0,5,2024-05-05T00:00:00Z\n" +
+		"Paracetamol,false,1,2,2024-05-05T00:00:00Z\n"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+	denylist := mocks.NewMockTokenDenylist(ctrl)
+	denylist.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+	uc.EXPECT().
+		ImportDrugs(gomock.Any(), gomock.Len(2), models.ImportOptions{Mode: models.ImportBestEffort}).
+		Times(1).
+		Return([]error{nil, ErrDuplicateDrug}, nil)
+
+	router := chi.NewRouter()
+	NewDrugHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), denylist, testSigner)
+
+	token, err := testSigner.Sign(&models.User{ID: 1, Role: models.RoleAdmin})
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/v1/drugs:import?mode=best_effort", strings.NewReader(file))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "text/csv")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"data":{"dry_run":false,"mode":"best_effort","committed":true,"total":3,"succeeded":1,"failed":2,"errors":[`+
+		`{"row":3,"code":"validation_failed","detail":"min_dose: The value needs to be more than 0 and non negative","errors":[{"field":"min_dose","message":"The value needs to be more than 0 and non negative","rule":"gt","param":"0"}]},`+
+		`{"row":4,"code":"drug_exists","detail":"Este medicamento ya existe"}]}}`, recorder.Body.String())
+
+	// the other formats are rejected before reading the file
+	request = httptest.NewRequest(http.MethodPost, "/v1/drugs:import", strings.NewReader(file))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+}
+
+func TestHandler_ExportDrugsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+	denylist := mocks.NewMockTokenDenylist(ctrl)
+	denylist.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+
+	router := chi.NewRouter()
+	NewDrugHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), denylist, testSigner)
+
+	token, err := testSigner.Sign(&models.User{ID: 1, Role: models.RoleReadOnly})
+	assert.NoError(t, err)
+	var drugs = []*models.Drug{
+		{ID: 1, Name: "Aspirina", Approved: true, MinDose: 1, MaxDose: 5, AvailableAt: time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Name: "Cafiaspirina, forte", Approved: true, MinDose: 2, MaxDose: 5, AvailableAt: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	var stream = func(_ context.Context, _ *models.DrugQuery, fn func(*models.Drug) error) error {
+		for _, drug := range drugs {
+			if err := fn(drug); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var newRequest = func(url string, accept string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		return request
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var approved = true
+		uc.EXPECT().
+			ExportDrugs(gomock.Any(), &models.DrugQuery{Pagination: models.NewPagination(), Approved: &approved, Sort: "id"}, gomock.Any()).
+			Times(1).
+			DoAndReturn(stream)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, newRequest("/v1/drugs:export?approved=true", "text/csv"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=drugs.csv", recorder.Header().Get("Content-Disposition"))
+		assert.Equal(t, "id,name,approved,min_dose,max_dose,available_at\n"+
+			"1,Aspirina,true,1,5,2024-05-05T00:00:00Z\n"+
+			"2,\"Cafiaspirina, forte\",true,2,5,2024-05-06T00:00:00Z\n", recorder.Body.String())
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		uc.EXPECT().ExportDrugs(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).DoAndReturn(stream)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, newRequest("/v1/drugs:export?format=ndjson", ""))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `{"id":1,"name":"Aspirina","approved":true,"min_dose":1,"max_dose":5,"available_at":"2024-05-05T00:00:00Z"}`+"\n"+
+			`{"id":2,"name":"Cafiaspirina, forte","approved":true,"min_dose":2,"max_dose":5,"available_at":"2024-05-06T00:00:00Z"}`+"\n", recorder.Body.String())
+	})
+
+	t.Run("Not acceptable", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, newRequest("/v1/drugs:export", "application/pdf"))
+
+		assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"not_acceptable"`)
+	})
+
+	t.Run("Error before the first row", func(t *testing.T) {
+		uc.EXPECT().ExportDrugs(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(ErrExecuteStatement)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, newRequest("/v1/drugs:export", ""))
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"internal_error"`)
+	})
+
+	t.Run("Error after the first row", func(t *testing.T) {
+		uc.EXPECT().
+			ExportDrugs(gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(ctx context.Context, q *models.DrugQuery, fn func(*models.Drug) error) error {
+				assert.NoError(t, fn(drugs[0]))
+				return ErrExecuteStatement
+			})
+
+		recorder := httptest.NewRecorder()
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			router.ServeHTTP(recorder, newRequest("/v1/drugs:export", ""))
+		})
+	})
+}
+
+func TestHandler_ExportDrugsHandler_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockDrugService(ctrl)
+	denylist := mocks.NewMockTokenDenylist(ctrl)
+	denylist.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+
+	// the timeout of the API is shorter than the export
+	router := chi.NewRouter()
+	router.Use(utils.Timeout(20 * time.Millisecond))
+	NewDrugHandlers(router, zap.NewNop(), uc, render.New(), models.NewValidator(), denylist, testSigner)
+
+	uc.EXPECT().
+		ExportDrugs(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, _ *models.DrugQuery, fn func(*models.Drug) error) error {
+			time.Sleep(60 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(&models.Drug{ID: 1, Name: "Aspirina", Approved: true, MinDose: 1, MaxDose: 5, AvailableAt: time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)})
+		})
+	uc.EXPECT().
+		GetDrug(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, _ int) (*models.Drug, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	token, err := testSigner.Sign(&models.User{ID: 1, Role: models.RoleReadOnly})
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodGet, "/v1/drugs:export", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "text/csv")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "id,name,approved,min_dose,max_dose,available_at\n"+
+		"1,Aspirina,true,1,5,2024-05-05T00:00:00Z\n", recorder.Body.String())
+
+	// the other routes are still cut off
+	request = httptest.NewRequest(http.MethodGet, "/v1/drugs/1", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+}
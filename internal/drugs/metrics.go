@@ -0,0 +1,102 @@
+package drugs
+
+import (
+	"context"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/metrics"
+	"time"
+)
+
+const repositoryName = "drugs"
+
+// errorNames labels of the sentinel errors in the metrics
+var errorNames = metrics.ErrorNames{
+	ErrTimeout:           "timeout",
+	ErrPrepapareQuery:    "prepare_query",
+	ErrDrugNotFound:      "drug_not_found",
+	ErrExecuteStatement:  "execute_statement",
+	ErrBeginTransaction:  "begin_transaction",
+	ErrDuplicateDrug:     "duplicate_drug",
+	ErrCommitTransaction: "commit_transaction",
+	ErrInsertFailed:      "insert_failed",
+	ErrUpdatingRecord:    "updating_record",
+	ErrDeletingRecord:    "deleting_record",
+	ErrScheduleNotFound:  "schedule_not_found",
+	ErrVersionConflict:   "version_conflict",
+}
+
+var _ interfaces.DrugRepository = (*instrumentedRepository)(nil)
+
+// instrumentedRepository records the duration and errors of each method of the repository
+type instrumentedRepository struct {
+	next interfaces.DrugRepository
+}
+
+// NewInstrumentedDrugRepository wraps a drug repository with metrics
+func NewInstrumentedDrugRepository(next interfaces.DrugRepository) *instrumentedRepository {
+	return &instrumentedRepository{next: next}
+}
+
+func (r instrumentedRepository) GetDrugsData(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error) {
+	var start = time.Now()
+	list, total, err := r.next.GetDrugsData(ctx, query)
+	metrics.ObserveQuery(repositoryName, "GetDrugsData", start, err, errorNames)
+	return list, total, err
+}
+
+func (r instrumentedRepository) ExportDrugItems(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error {
+	var start = time.Now()
+	err := r.next.ExportDrugItems(ctx, query, fn)
+	metrics.ObserveQuery(repositoryName, "ExportDrugItems", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) CreateNewDrugItem(ctx context.Context, form *models.DrugForm) error {
+	var start = time.Now()
+	err := r.next.CreateNewDrugItem(ctx, form)
+	metrics.ObserveQuery(repositoryName, "CreateNewDrugItem", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) ImportDrugItems(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	var start = time.Now()
+	errs, err := r.next.ImportDrugItems(ctx, forms, opts)
+	metrics.ObserveQuery(repositoryName, "ImportDrugItems", start, err, errorNames)
+	return errs, err
+}
+
+func (r instrumentedRepository) GetDrugItemByID(ctx context.Context, drugId int) (*models.Drug, error) {
+	var start = time.Now()
+	item, err := r.next.GetDrugItemByID(ctx, drugId)
+	metrics.ObserveQuery(repositoryName, "GetDrugItemByID", start, err, errorNames)
+	return item, err
+}
+
+func (r instrumentedRepository) UpdateDrugItem(ctx context.Context, drugId int, version int, form *models.Drug) error {
+	var start = time.Now()
+	err := r.next.UpdateDrugItem(ctx, drugId, version, form)
+	metrics.ObserveQuery(repositoryName, "UpdateDrugItem", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) DeleteDrugItem(ctx context.Context, drugId int, version int) error {
+	var start = time.Now()
+	err := r.next.DeleteDrugItem(ctx, drugId, version)
+	metrics.ObserveQuery(repositoryName, "DeleteDrugItem", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) GetDrugScheduleByID(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	var start = time.Now()
+	item, err := r.next.GetDrugScheduleByID(ctx, drugId)
+	metrics.ObserveQuery(repositoryName, "GetDrugScheduleByID", start, err, errorNames)
+	return item, err
+}
+
+func (r instrumentedRepository) SaveDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	var start = time.Now()
+	err := r.next.SaveDrugSchedule(ctx, drugId, form)
+	metrics.ObserveQuery(repositoryName, "SaveDrugSchedule", start, err, errorNames)
+	return err
+}
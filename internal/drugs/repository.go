@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/database"
+	"kiramishima/ionix/internal/pkg/tracing"
 )
 
 // implement drug repository
@@ -29,40 +33,48 @@ func NewDrugRepository(conn *sqlx.DB, logger *zap.Logger) *repository {
 	}
 }
 
-// GetDrugsData gets data from drugs table
-func (repo repository) GetDrugsData(ctx context.Context) ([]*models.Drug, error) {
-	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE deleted_at IS NULL`
+// GetDrugsData gets a page of data from drugs table
+func (repo repository) GetDrugsData(ctx context.Context, q *models.DrugQuery) ([]*models.Drug, int, error) {
+	var where, args = q.Where()
+	var list = make([]*models.Drug, 0)
+
+	total, err := repo.countDrugs(ctx, where, args)
+	if err != nil {
+		return list, 0, err
+	}
+
+	var query = fmt.Sprintf(`SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE %s ORDER BY %s LIMIT %d OFFSET %d`,
+		where, q.OrderBy(), q.Limit, q.Offset())
 
 	stmt, err := repo.db.PreparexContext(ctx, query)
 	if err != nil {
-		return nil, ErrPrepapareQuery
+		return nil, 0, ErrPrepapareQuery
 	}
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 
 		}
 	}(stmt)
 
-	var list = make([]*models.Drug, 0)
-
-	rows, err := stmt.QueryxContext(ctx)
+	rows, err := stmt.QueryxContext(ctx, args...)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return list, nil
+			return list, total, nil
 		} else {
-			return list, ErrExecuteStatement
+			return list, total, ErrExecuteStatement
 		}
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var availableAt sql.NullTime
 		var item = &models.Drug{}
-		err = rows.Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt)
-		repo.log.Info("[INFO]", zap.Any("Item", item))
-		if errors.Is(err, sql.ErrNoRows) {
-			break
+		if err := rows.Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt); err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+			return nil, 0, ErrExecuteStatement
 		}
 
 		if availableAt.Valid {
@@ -70,12 +82,90 @@ func (repo repository) GetDrugsData(ctx context.Context) ([]*models.Drug, error)
 		}
 		list = append(list, item)
 	}
+	if err := rows.Err(); err != nil {
+		tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		return nil, 0, ErrExecuteStatement
+	}
+
+	return list, total, nil
+}
+
+// ExportDrugItems streams the drugs matching the filters of the query to fn without pagination,
+// the rows are read from the cursor one at a time and fn must not keep the item
+func (repo repository) ExportDrugItems(ctx context.Context, q *models.DrugQuery, fn func(*models.Drug) error) error {
+	var where, args = q.Where()
+	var query = fmt.Sprintf(`SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE %s ORDER BY %s`,
+		where, q.OrderBy())
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+
+		}
+	}(stmt)
+
+	rows, err := stmt.QueryxContext(ctx, args...)
+	if err != nil {
+		return ErrExecuteStatement
+	}
+	defer rows.Close()
+
+	var availableAt sql.NullTime
+	var item = &models.Drug{}
+	for rows.Next() {
+		*item = models.Drug{}
+		if err := rows.Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt); err != nil {
+			return ErrExecuteStatement
+		}
+		if availableAt.Valid {
+			item.AvailableAt = availableAt.Time
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ErrExecuteStatement
+	}
 
-	return list, nil
+	return nil
+}
+
+// countDrugs counts the rows of drugs table matching the filters
+func (repo repository) countDrugs(ctx context.Context, where string, args []any) (int, error) {
+	var query = fmt.Sprintf(`SELECT COUNT(*) FROM drugs WHERE %s`, where)
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return 0, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+
+		}
+	}(stmt)
+
+	var total int
+	err = stmt.QueryRowContext(ctx, args...).Scan(&total)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, ErrExecuteStatement
+	}
+
+	return total, nil
 }
 
 func (repo repository) GetDrugItemByID(ctx context.Context, drugId int) (*models.Drug, error) {
-	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs 
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at, version FROM drugs 
     WHERE deleted_at IS NULL AND id = $1`
 
 	stmt, err := repo.db.PreparexContext(ctx, query)
@@ -85,7 +175,7 @@ func (repo repository) GetDrugItemByID(ctx context.Context, drugId int) (*models
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 
 		}
 	}(stmt)
@@ -94,10 +184,12 @@ func (repo repository) GetDrugItemByID(ctx context.Context, drugId int) (*models
 
 	var availableAt sql.NullTime
 	var item = &models.Drug{}
-	err = rows.Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt)
-	repo.log.Info("[INFO]", zap.Any("Item", item))
+	err = rows.Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt, &item.Version)
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("Item", item))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrDrugNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
 	}
 
 	if availableAt.Valid {
@@ -108,21 +200,74 @@ func (repo repository) GetDrugItemByID(ctx context.Context, drugId int) (*models
 }
 
 func (repo repository) CreateNewDrugItem(ctx context.Context, form *models.DrugForm) error {
-	repo.log.Info("[INFO]", zap.Any("Form", form))
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("Form", form))
 	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
-		repo.log.Info(err.Error())
+		tracing.Logger(ctx, repo.log).Info(err.Error())
 		return ErrBeginTransaction
 	}
 	defer func(tx *sqlx.Tx) {
 		err := tx.Rollback()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 		}
 	}(tx)
 
+	if err = repo.insertDrug(ctx, tx, form); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+
+	return nil
+}
+
+// ImportDrugItems inserts the drugs in one transaction, each drug in a savepoint so a failed row does not
+// abort the others, the transaction is committed when opts accepts the failed rows
+func (repo repository) ImportDrugItems(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return nil, ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		}
+	}(tx)
+
+	var errs = make([]error, len(forms))
+	var failed int
+	for i, form := range forms {
+		errs[i] = database.Savepoint(ctx, tx, "import_row", func() error {
+			return repo.insertDrug(ctx, tx, form)
+		})
+		if errors.Is(errs[i], database.ErrSavepoint) {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(errs[i]))
+			return nil, ErrExecuteStatement
+		}
+		if errs[i] != nil {
+			failed++
+		}
+	}
+	tracing.Logger(ctx, repo.log).Info("imported drugs", zap.Int("rows", len(forms)), zap.Int("failed", failed), zap.Bool("commit", opts.Commit(failed)))
+
+	if !opts.Commit(failed) {
+		return errs, nil
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, ErrCommitTransaction
+	}
+	return errs, nil
+}
+
+// insertDrug inserts the drug and records it in the audit log
+func (repo repository) insertDrug(ctx context.Context, tx *sqlx.Tx, form *models.DrugForm) error {
 	var query = `INSERT INTO drugs (name, approved, min_dose, max_dose, available_at)
-	VALUES ($1, $2, $3, $4, CAST($5 AS TIMESTAMP))`
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
 	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
@@ -130,36 +275,34 @@ func (repo repository) CreateNewDrugItem(ctx context.Context, form *models.DrugF
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 
 		}
 	}(stmt)
 
-	_, err = stmt.ExecContext(ctx, form.Name, form.Approved, form.MinDose, form.MaxDose, form.AvailableAt)
+	var drugId int
+	err = stmt.QueryRowxContext(ctx, form.Name, form.Approved, form.MinDose, form.MaxDose, form.AvailableAt).Scan(&drugId)
 
 	if err != nil {
-		repo.log.Info(err.Error())
+		tracing.Logger(ctx, repo.log).Info(err.Error())
 		// log.Println("Code 2 ", errors.Is(err, my.ErrDupeKey))
 		var pgErr *pgconn.PgError
 		ok := errors.As(err, &pgErr)
 		if ok {
-			repo.log.Info(pgErr.Code)
+			tracing.Logger(ctx, repo.log).Info(pgErr.Code)
 			if pgErr.Code == "23505" {
 				return ErrDuplicateDrug
-			} else {
-				return ErrInsertFailed
 			}
 		}
-
-	}
-	if err = tx.Commit(); err != nil {
-		return ErrCommitTransaction
+		return ErrInsertFailed
 	}
-	return nil
+
+	return audit.Record(ctx, tx, audit.ActionCreate, audit.EntityDrug, drugId, nil, form)
 }
 
-func (repo repository) UpdateDrugItem(ctx context.Context, drugId int, form *models.Drug) error {
-	repo.log.Info("[INFO]", zap.Any("Form", form))
+// UpdateDrugItem updates the drug when it is still in version, 0 updates any version
+func (repo repository) UpdateDrugItem(ctx context.Context, drugId int, version int, form *models.Drug) error {
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("Form", form))
 	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return ErrBeginTransaction
@@ -167,11 +310,16 @@ func (repo repository) UpdateDrugItem(ctx context.Context, drugId int, form *mod
 	defer func(tx *sqlx.Tx) {
 		err := tx.Rollback()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 		}
 	}(tx)
 
-	var query = `UPDATE drugs SET name = $1, approved = $2, min_dose = $3, max_dose = $4, available_at = $5 WHERE id = $6`
+	before, err := repo.lockDrug(ctx, tx, drugId, version)
+	if err != nil {
+		return err
+	}
+
+	var query = `UPDATE drugs SET name = $1, approved = $2, min_dose = $3, max_dose = $4, available_at = $5, version = version + 1 WHERE id = $6`
 	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
@@ -179,7 +327,7 @@ func (repo repository) UpdateDrugItem(ctx context.Context, drugId int, form *mod
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 
 		}
 	}(stmt)
@@ -195,13 +343,21 @@ func (repo repository) UpdateDrugItem(ctx context.Context, drugId int, form *mod
 		}
 	}
 
+	var after = *form
+	after.ID = before.ID
+	after.Version = before.Version + 1
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityDrug, drugId, before, &after); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrCommitTransaction
 	}
 	return nil
 }
 
-func (repo repository) DeleteDrugItem(ctx context.Context, drugId int) error {
+// DeleteDrugItem deletes the drug when it is still in version, 0 deletes any version
+func (repo repository) DeleteDrugItem(ctx context.Context, drugId int, version int) error {
 	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return ErrBeginTransaction
@@ -209,11 +365,16 @@ func (repo repository) DeleteDrugItem(ctx context.Context, drugId int) error {
 	defer func(tx *sqlx.Tx) {
 		err := tx.Rollback()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 		}
 	}(tx)
 
-	var query = `UPDATE drugs SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	before, err := repo.lockDrug(ctx, tx, drugId, version)
+	if err != nil {
+		return err
+	}
+
+	var query = `UPDATE drugs SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
 	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
@@ -221,7 +382,7 @@ func (repo repository) DeleteDrugItem(ctx context.Context, drugId int) error {
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("[ERROR]", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
 
 		}
 	}(stmt)
@@ -237,8 +398,141 @@ func (repo repository) DeleteDrugItem(ctx context.Context, drugId int) error {
 		}
 	}
 
+	if err = audit.Record(ctx, tx, audit.ActionDelete, audit.EntityDrug, drugId, before, nil); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrCommitTransaction
 	}
 	return nil
 }
+
+func (repo repository) GetDrugScheduleByID(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	var query = `SELECT drug_id, doses, min_interval_days, recommended_interval_days, booster_interval_days
+	FROM drug_schedules WHERE drug_id = $1`
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		}
+	}(stmt)
+
+	var item = &models.DrugSchedule{}
+	err = stmt.QueryRowContext(ctx, drugId).Scan(&item.DrugID, &item.Doses, &item.MinIntervalDays, &item.RecommendedIntervalDays, &item.BoosterIntervalDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+
+	return item, nil
+}
+
+func (repo repository) SaveDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.lockDrugSchedule(ctx, tx, drugId)
+	if err != nil {
+		return err
+	}
+
+	var query = `INSERT INTO drug_schedules (drug_id, doses, min_interval_days, recommended_interval_days, booster_interval_days)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (drug_id) DO UPDATE SET doses = EXCLUDED.doses, min_interval_days = EXCLUDED.min_interval_days,
+	recommended_interval_days = EXCLUDED.recommended_interval_days, booster_interval_days = EXCLUDED.booster_interval_days, updated_at = NOW()`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		}
+	}(stmt)
+
+	_, err = stmt.ExecContext(ctx, drugId, form.Doses, form.MinIntervalDays, form.RecommendedIntervalDays, form.BoosterIntervalDays)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrDrugNotFound
+		}
+		return ErrUpdatingRecord
+	}
+
+	var action = audit.ActionUpdate
+	if before == nil {
+		action = audit.ActionCreate
+	}
+	var after = &models.DrugSchedule{
+		DrugID:                  int32(drugId),
+		Doses:                   *form.Doses,
+		MinIntervalDays:         *form.MinIntervalDays,
+		RecommendedIntervalDays: *form.RecommendedIntervalDays,
+		BoosterIntervalDays:     form.BoosterIntervalDays,
+	}
+	if err = audit.Record(ctx, tx, action, audit.EntityDrugSchedule, drugId, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// lockDrug reads the current state of the drug and locks the row until the end of the transaction,
+// ErrVersionConflict when the drug is not in version, 0 accepts any version
+func (repo repository) lockDrug(ctx context.Context, tx *sqlx.Tx, drugId int, version int) (*models.Drug, error) {
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at, version FROM drugs
+	WHERE deleted_at IS NULL AND id = $1 FOR UPDATE`
+
+	var availableAt sql.NullTime
+	var item = &models.Drug{}
+	err := tx.QueryRowxContext(ctx, query, drugId).Scan(&item.ID, &item.Name, &item.Approved, &item.MinDose, &item.MaxDose, &availableAt, &item.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDrugNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		return nil, ErrExecuteStatement
+	}
+	if version != 0 && item.Version != version {
+		return nil, ErrVersionConflict
+	}
+	if availableAt.Valid {
+		item.AvailableAt = availableAt.Time
+	}
+	return item, nil
+}
+
+// lockDrugSchedule reads the current schedule of the drug, nil when the drug has no schedule yet
+func (repo repository) lockDrugSchedule(ctx context.Context, tx *sqlx.Tx, drugId int) (*models.DrugSchedule, error) {
+	var query = `SELECT drug_id, doses, min_interval_days, recommended_interval_days, booster_interval_days
+	FROM drug_schedules WHERE drug_id = $1 FOR UPDATE`
+
+	var item = &models.DrugSchedule{}
+	err := tx.QueryRowxContext(ctx, query, drugId).Scan(&item.DrugID, &item.Doses, &item.MinIntervalDays, &item.RecommendedIntervalDays, &item.BoosterIntervalDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		return nil, ErrExecuteStatement
+	}
+	return item, nil
+}
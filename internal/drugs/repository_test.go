@@ -3,12 +3,17 @@ package drugs
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/models"
+	"math"
+	"net/url"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -31,23 +36,32 @@ func TestRepository_GetDrugsData(t *testing.T) {
 
 	repo := NewDrugRepository(sqlxDB, logger)
 
-	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE deleted_at IS NULL`
+	var countQuery = `SELECT COUNT(*) FROM drugs WHERE deleted_at IS NULL`
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE deleted_at IS NULL ORDER BY id ASC LIMIT 20 OFFSET 0`
 
-	var rows = sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).FromCSVString("1,aspirina,true,1,5,2024-05-05 00:00:00\n2,cafiaspirina,true,2,5,2024-05-05 00:00:00")
+	var availableAt = time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)
+	var rows = sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).
+		AddRow(1, "aspirina", true, 1, 5, availableAt).
+		AddRow(2, "cafiaspirina", true, 2, 5, availableAt)
 
 	t.Run("OK", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
 		defer cancel()
 
+		mock.ExpectPrepare(countQuery).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 		mock.ExpectPrepare(query).
 			ExpectQuery().
 			WillReturnRows(rows)
 
-		data, err := repo.GetDrugsData(ctx)
+		data, total, err := repo.GetDrugsData(ctx, models.NewDrugQuery())
 		t.Log(len(data), err)
 		assert.NoError(t, err)
 		assert.Equal(t, len(data), 2)
+		assert.Equal(t, total, 2)
 		assert.Equal(t, data[0].Name, "aspirina")
+		assert.Equal(t, availableAt, data[0].AvailableAt)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -55,16 +69,101 @@ func TestRepository_GetDrugsData(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
 		defer cancel()
 
+		mock.ExpectPrepare(countQuery).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 		mock.ExpectPrepare(query).
 			ExpectQuery().
 			WillReturnError(sql.ErrNoRows)
 
-		data, err := repo.GetDrugsData(ctx)
+		data, total, err := repo.GetDrugsData(ctx, models.NewDrugQuery())
 		t.Log(len(data), err)
 		assert.NoError(t, err)
 		assert.Equal(t, len(data), 0)
+		assert.Equal(t, total, 0)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Scan and rows errors", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		// a row that can not be scanned is not returned
+		mock.ExpectPrepare(countQuery).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).
+				AddRow(1, "aspirina", true, 1, 5, time.Now()).
+				AddRow("two", "cafiaspirina", true, 2, 5, time.Now()))
+
+		data, _, err := repo.GetDrugsData(ctx, models.NewDrugQuery())
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.Nil(t, data)
+
+		// the error that ends the cursor is not a short page
+		mock.ExpectPrepare(countQuery).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).
+				AddRow(1, "aspirina", true, 1, 5, time.Now()).
+				AddRow(2, "cafiaspirina", true, 2, 5, time.Now()).
+				RowError(1, errors.New("connection reset")))
+
+		data, _, err = repo.GetDrugsData(ctx, models.NewDrugQuery())
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.Nil(t, data)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Filters, sort and page", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		q, err := models.ParseDrugQuery(url.Values{
+			"name":     {"asp"},
+			"approved": {"true"},
+			"min_dose": {"1"},
+			"sort":     {"-name"},
+			"page":     {"2"},
+			"limit":    {"1"},
+		})
+		assert.NoError(t, err)
+
+		var where = `deleted_at IS NULL AND name ILIKE $1 AND approved = $2 AND min_dose >= $3`
+		mock.ExpectPrepare(`SELECT COUNT(*) FROM drugs WHERE `+where).
+			ExpectQuery().
+			WithArgs("asp%", true, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectPrepare(`SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE `+where+` ORDER BY name DESC, id DESC LIMIT 1 OFFSET 1`).
+			ExpectQuery().
+			WithArgs("asp%", true, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).AddRow(1, "aspirina", true, 1, 5, availableAt))
+
+		data, total, err := repo.GetDrugsData(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), 1)
+		assert.Equal(t, total, 2)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("Invalid sort column", func(t *testing.T) {
+		_, err := models.ParseDrugQuery(url.Values{"sort": {"password"}})
+		assert.ErrorIs(t, err, models.ErrInvalidQuery)
+	})
+
+	t.Run("Page out of range", func(t *testing.T) {
+		_, err := models.ParseDrugQuery(url.Values{"page": {strconv.Itoa(math.MaxInt)}, "limit": {"100"}})
+		assert.ErrorIs(t, err, models.ErrInvalidQuery)
+
+		// the last page whose offset fits
+		q, err := models.ParseDrugQuery(url.Values{"page": {strconv.Itoa(math.MaxInt/100 + 1)}, "limit": {"100"}})
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, q.Offset(), 0)
+	})
 }
 
 func TestRepository_GetDrugItemByID(t *testing.T) {
@@ -85,10 +184,10 @@ func TestRepository_GetDrugItemByID(t *testing.T) {
 
 	repo := NewDrugRepository(sqlxDB, logger)
 
-	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs 
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at, version FROM drugs 
     WHERE deleted_at IS NULL AND id = $1`
 
-	var rows = sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).FromCSVString("1,aspirina,true,1,5,2024-05-05 00:00:00")
+	var rows = sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at", "version"}).AddRow(1, "aspirina", true, 1, 5, time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), 3)
 
 	t.Run("OK", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
@@ -102,6 +201,7 @@ func TestRepository_GetDrugItemByID(t *testing.T) {
 		t.Log(data, err)
 		assert.NoError(t, err)
 		assert.Equal(t, data.Name, "aspirina")
+		assert.Equal(t, 3, data.Version)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -139,13 +239,13 @@ func TestRepository_CreateNewDrugItem(t *testing.T) {
 	repo := NewDrugRepository(sqlxDB, logger)
 
 	var query = `INSERT INTO drugs (name, approved, min_dose, max_dose, available_at)
-	VALUES ($1, $2, $3, $4, CAST($5 AS TIMESTAMP))`
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
 
 	var name = "Aspirina"
 	var approved = true
 	var minDose = 1
 	var maxDose = 2
-	var availableAt = time.Now().String()
+	var availableAt = models.DateTime{Time: time.Now()}
 	var item = &models.DrugForm{
 		Name:        &name,
 		Approved:    &approved,
@@ -161,8 +261,12 @@ func TestRepository_CreateNewDrugItem(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WithArgs(item.Name, item.Approved, item.MinDose, item.MaxDose, item.AvailableAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityDrug, "1", sqlmock.AnyArg(), nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectCommit()
@@ -179,7 +283,7 @@ func TestRepository_CreateNewDrugItem(t *testing.T) {
 		mock.ExpectBegin()
 
 		mock.ExpectPrepare(query).
-			ExpectExec().
+			ExpectQuery().
 			WillReturnError(&pgconn.PgError{
 				Code: "23505", // Duplicate key error code
 			})
@@ -232,7 +336,10 @@ func TestRepository_UpdateDrugItem(t *testing.T) {
 
 	repo := NewDrugRepository(sqlxDB, logger)
 
-	var query = `UPDATE drugs SET name = $1, approved = $2, min_dose = $3, max_dose = $4, available_at = $5 WHERE id = $6`
+	var query = `UPDATE drugs SET name = $1, approved = $2, min_dose = $3, max_dose = $4, available_at = $5, version = version + 1 WHERE id = $6`
+	var lockQuery = `SELECT id, name, approved, min_dose, max_dose, available_at, version FROM drugs
+	WHERE deleted_at IS NULL AND id = $1 FOR UPDATE`
+	var columns = []string{"id", "name", "approved", "min_dose", "max_dose", "available_at", "version"}
 
 	var name = "Aspirina"
 	var approved = true
@@ -254,32 +361,56 @@ func TestRepository_UpdateDrugItem(t *testing.T) {
 
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, name, approved, minDose, 5, availableAt, 1))
+
 		mock.ExpectPrepare(query).
 			ExpectExec().
 			WithArgs(item.Name, item.Approved, item.MinDose, item.MaxDose, item.AvailableAt, item.ID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionUpdate, audit.EntityDrug, "1", `{"before":{"max_dose":5},"after":{"max_dose":2}}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
 		mock.ExpectCommit()
 
-		err := repo.UpdateDrugItem(ctx, 1, item)
+		err := repo.UpdateDrugItem(ctx, 1, 1, item)
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Stale version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, name, approved, minDose, 5, availableAt, 2))
+
+		mock.ExpectRollback()
+
+		err := repo.UpdateDrugItem(ctx, 1, 1, item)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("Updated Not Existing Item", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
 		defer cancel()
 
 		mock.ExpectBegin()
 
-		mock.ExpectPrepare(query).
-			ExpectExec().
-			WithArgs(item.Name, item.Approved, item.MinDose, item.MaxDose, item.AvailableAt, item.ID).
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
 			WillReturnError(sql.ErrNoRows)
 
 		mock.ExpectRollback()
 
-		err := repo.UpdateDrugItem(ctx, 1, item)
+		err := repo.UpdateDrugItem(ctx, 1, 0, item)
 		t.Log(err)
 		assert.Error(t, err)
 		assert.EqualError(t, err, ErrDrugNotFound.Error())
@@ -305,7 +436,10 @@ func TestRepository_DeleteDrugItem(t *testing.T) {
 
 	repo := NewDrugRepository(sqlxDB, logger)
 
-	var query = `UPDATE drugs SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	var query = `UPDATE drugs SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
+	var lockQuery = `SELECT id, name, approved, min_dose, max_dose, available_at, version FROM drugs
+	WHERE deleted_at IS NULL AND id = $1 FOR UPDATE`
+	var columns = []string{"id", "name", "approved", "min_dose", "max_dose", "available_at", "version"}
 
 	t.Run("Deleted is OK", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
@@ -313,14 +447,22 @@ func TestRepository_DeleteDrugItem(t *testing.T) {
 
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Aspirina", true, 1, 2, time.Now(), 1))
+
 		mock.ExpectPrepare(query).
 			ExpectExec().
 			WithArgs(1).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionDelete, audit.EntityDrug, "1", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
 		mock.ExpectCommit()
 
-		err := repo.DeleteDrugItem(ctx, 1)
+		err := repo.DeleteDrugItem(ctx, 1, 0)
 		t.Log(err)
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -332,17 +474,194 @@ func TestRepository_DeleteDrugItem(t *testing.T) {
 
 		mock.ExpectBegin()
 
-		mock.ExpectPrepare(query).
-			ExpectExec().
+		mock.ExpectQuery(lockQuery).
 			WithArgs(1).
 			WillReturnError(sql.ErrNoRows)
 
 		mock.ExpectRollback()
 
-		err := repo.DeleteDrugItem(ctx, 1)
+		err := repo.DeleteDrugItem(ctx, 1, 1)
 		t.Log(err)
 		assert.Error(t, err)
 		assert.EqualError(t, err, ErrDrugNotFound.Error())
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestRepository_ImportDrugItems(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewDrugRepository(sqlxDB, logger)
+
+	var query = `INSERT INTO drugs (name, approved, min_dose, max_dose, available_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	var newForm = func(name string) *models.DrugForm {
+		var approved, minDose, maxDose = true, 1, 2
+		return &models.DrugForm{Name: &name, Approved: &approved, MinDose: &minDose, MaxDose: &maxDose, AvailableAt: &models.DateTime{Time: time.Now()}}
+	}
+	var forms = []*models.DrugForm{newForm("Aspirina"), newForm("Aspirina")}
+
+	// the first row is inserted, the second one is a duplicate rolled back to its savepoint
+	var expectRows = func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(forms[0].Name, forms[0].Approved, forms[0].MinDose, forms[0].MaxDose, forms[0].AvailableAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityDrug, "1", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	t.Run("Best effort commits the valid rows", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		expectRows()
+		mock.ExpectCommit()
+
+		errs, err := repo.ImportDrugItems(ctx, forms, models.ImportOptions{Mode: models.ImportBestEffort})
+		assert.NoError(t, err)
+		assert.NoError(t, errs[0])
+		assert.ErrorIs(t, errs[1], ErrDuplicateDrug)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Atomic rolls back", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		expectRows()
+		mock.ExpectRollback()
+
+		errs, err := repo.ImportDrugItems(ctx, forms, models.ImportOptions{Mode: models.ImportAtomic})
+		assert.NoError(t, err)
+		assert.ErrorIs(t, errs[1], ErrDuplicateDrug)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dry run rolls back", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(audit.InsertQuery).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		errs, err := repo.ImportDrugItems(ctx, forms[:1], models.ImportOptions{DryRun: true, Mode: models.ImportBestEffort})
+		assert.NoError(t, err)
+		assert.NoError(t, errs[0])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_ExportDrugItems(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("close db", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewDrugRepository(sqlxDB, logger)
+
+	// the export has the filters and sorting of the list without the pagination
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs WHERE deleted_at IS NULL AND approved = $1 ORDER BY name DESC, id DESC`
+	var approved = true
+	var q = models.NewDrugQuery()
+	q.Approved, q.Sort, q.Desc = &approved, "name", true
+	var newRows = func() *sqlmock.Rows {
+		var availableAt = time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)
+		return sqlmock.NewRows([]string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}).
+			AddRow(2, "cafiaspirina", true, 2, 5, availableAt).
+			AddRow(1, "aspirina", true, 1, 5, availableAt)
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(true).
+			WillReturnRows(newRows())
+
+		var names []string
+		err := repo.ExportDrugItems(ctx, q, func(drug *models.Drug) error {
+			names = append(names, drug.Name)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cafiaspirina", "aspirina"}, names)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error of fn", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(true).
+			WillReturnRows(newRows())
+
+		var errWrite = errors.New("write")
+		var calls int
+		err := repo.ExportDrugItems(ctx, q, func(drug *models.Drug) error {
+			calls++
+			return errWrite
+		})
+		assert.ErrorIs(t, err, errWrite)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(true).
+			WillReturnError(errors.New("connection reset"))
+
+		err := repo.ExportDrugItems(ctx, q, func(drug *models.Drug) error { return nil })
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,11 +14,12 @@ import (
 var _ impl.DrugService = (*service)(nil)
 
 // NewDrugService creates a new drug service
-func NewDrugService(repo impl.DrugRepository, logger *zap.Logger, timeout time.Duration) *service {
+func NewDrugService(repo impl.DrugRepository, logger *zap.Logger, timeout time.Duration, importTimeout time.Duration) *service {
 	return &service{
 		logger:         logger,
 		repository:     repo,
 		contextTimeOut: timeout,
+		importTimeOut:  importTimeout,
 	}
 }
 
@@ -25,23 +27,81 @@ type service struct {
 	logger         *zap.Logger
 	repository     impl.DrugRepository
 	contextTimeOut time.Duration
+	// importTimeOut bounds the imports instead of contextTimeOut, every row of a file runs in one transaction
+	importTimeOut time.Duration
 }
 
-func (svc service) GetListDrugs(ctx context.Context) ([]*models.Drug, error) {
+func (svc service) GetListDrugs(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error) {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 
-	data, err := svc.repository.GetDrugsData(cxt)
+	if query == nil {
+		query = models.NewDrugQuery()
+	}
+
+	data, total, err := svc.repository.GetDrugsData(cxt, query)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
-			return nil, ErrTimeout
+			return nil, 0, ErrTimeout
 		default:
 			if errors.Is(err, ErrNoRecords) {
-				return nil, ErrNoRecords
+				return nil, 0, ErrNoRecords
+			} else if errors.Is(err, ErrExecuteStatement) {
+				return nil, 0, ErrExecuteStatement
+			} else {
+				return nil, 0, ErrServiceDrugs
+			}
+		}
+	}
+	// tracing.Logger(ctx, svc.logger).Info(data)
+	return data, total, nil
+}
+
+// ExportDrugs streams the drugs matching the query to fn, the export is bound to the request instead of the timeout
+// of the service because it can take much longer than a page
+func (svc service) ExportDrugs(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error {
+	if query == nil {
+		query = models.NewDrugQuery()
+	}
+
+	err := svc.repository.ExportDrugItems(ctx, query, fn)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrPrepapareQuery) || errors.Is(err, ErrExecuteStatement) {
+				return ErrExecuteStatement
+			}
+			// the error of fn writing the item
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc service) GetDrug(ctx context.Context, drugId int) (*models.Drug, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	drug, err := svc.repository.GetDrugItemByID(cxt, drugId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		default:
+			if errors.Is(err, ErrDrugNotFound) {
+				return nil, ErrDrugNotFound
 			} else if errors.Is(err, ErrExecuteStatement) {
 				return nil, ErrExecuteStatement
 			} else {
@@ -49,8 +109,8 @@ func (svc service) GetListDrugs(ctx context.Context) ([]*models.Drug, error) {
 			}
 		}
 	}
-	// svc.logger.Info(data)
-	return data, nil
+
+	return drug, nil
 }
 
 func (svc service) NewDrug(ctx context.Context, form *models.DrugForm) error {
@@ -58,13 +118,13 @@ func (svc service) NewDrug(ctx context.Context, form *models.DrugForm) error {
 	defer cancel()
 
 	err := svc.repository.CreateNewDrugItem(ctx, form)
-	svc.logger.Error("", zap.Error(err))
+	tracing.Logger(ctx, svc.logger).Error("", zap.Error(err))
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-cxt.Done():
-			svc.logger.Error("[ERROR]", zap.Error(ctx.Err()))
+			tracing.Logger(ctx, svc.logger).Error("[ERROR]", zap.Error(ctx.Err()))
 			return ErrTimeout
 		default:
 			if errors.Is(err, ErrDuplicateDrug) {
@@ -78,42 +138,50 @@ func (svc service) NewDrug(ctx context.Context, form *models.DrugForm) error {
 	return nil
 }
 
-func (svc service) UpdateDrug(ctx context.Context, drugId int, form *models.DrugForm) error {
+// ImportDrugs imports the validated drugs, the error of each row is returned in the order of forms
+func (svc service) ImportDrugs(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.importTimeOut)
+	defer cancel()
+
+	errs, err := svc.repository.ImportDrugItems(cxt, forms, opts)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrTimeout
+		default:
+			return nil, ErrExecuteStatement
+		}
+	}
+
+	return errs, nil
+}
+
+func (svc service) UpdateDrug(ctx context.Context, drugId int, version int, form *models.DrugForm) error {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 	// Retrieve the data
 	drug, err := svc.repository.GetDrugItemByID(cxt, drugId)
 	if errors.Is(err, ErrDrugNotFound) {
 		return ErrDrugNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
 	}
-	svc.logger.Info("[INFO]", zap.Any("Drug", drug))
-	//
-	if form.Name != nil {
-		drug.Name = *form.Name
-	}
-	if form.Approved != nil {
-		drug.Approved = *form.Approved
-	}
-	if form.MinDose != nil {
-		drug.MinDose = *form.MinDose
-	}
-	if form.MaxDose != nil {
-		drug.MaxDose = *form.MaxDose
-	}
-	if form.AvailableAt != nil {
-		var dt = *form.AvailableAt
-		layout := "2006-01-02 15:04:05"
-		tm, _ := time.Parse(layout, dt)
-		svc.logger.Info("[INFO]", zap.Any("time", tm))
-		drug.AvailableAt = tm
-	}
-	svc.logger.Info("[INFO]", zap.Any("drug_form", form))
+	tracing.Logger(ctx, svc.logger).Info("[INFO]", zap.Any("Drug", drug))
+	// the form replaces the drug, it was validated by the handler
+	drug.Name = *form.Name
+	drug.Approved = *form.Approved
+	drug.MinDose = *form.MinDose
+	drug.MaxDose = *form.MaxDose
+	drug.AvailableAt = form.AvailableAt.Time
+	tracing.Logger(ctx, svc.logger).Info("[INFO]", zap.Any("drug_form", form))
 
 	// Call repository
-	err = svc.repository.UpdateDrugItem(cxt, drugId, drug)
+	err = svc.repository.UpdateDrugItem(cxt, drugId, version, drug)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
@@ -123,6 +191,8 @@ func (svc service) UpdateDrug(ctx context.Context, drugId int, form *models.Drug
 				return ErrExecuteStatement
 			} else if errors.Is(err, ErrDrugNotFound) {
 				return ErrDrugNotFound
+			} else if errors.Is(err, ErrVersionConflict) {
+				return ErrVersionConflict
 			} else {
 				return ErrUpdatingRecord
 			}
@@ -132,20 +202,22 @@ func (svc service) UpdateDrug(ctx context.Context, drugId int, form *models.Drug
 	return nil
 }
 
-func (svc service) DeleteDrug(ctx context.Context, drugId int) error {
+func (svc service) DeleteDrug(ctx context.Context, drugId int, version int) error {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 
 	_, err := svc.repository.GetDrugItemByID(cxt, drugId)
 	if errors.Is(err, ErrDrugNotFound) {
 		return ErrDrugNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
 	}
 
 	// Call repository
-	err = svc.repository.DeleteDrugItem(cxt, drugId)
+	err = svc.repository.DeleteDrugItem(cxt, drugId, version)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
@@ -155,6 +227,8 @@ func (svc service) DeleteDrug(ctx context.Context, drugId int) error {
 				return ErrExecuteStatement
 			} else if errors.Is(err, ErrDrugNotFound) {
 				return ErrDrugNotFound
+			} else if errors.Is(err, ErrVersionConflict) {
+				return ErrVersionConflict
 			} else {
 				return ErrDeletingRecord
 			}
@@ -163,3 +237,68 @@ func (svc service) DeleteDrug(ctx context.Context, drugId int) error {
 
 	return nil
 }
+
+func (svc service) GetDrugSchedule(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	_, err := svc.repository.GetDrugItemByID(cxt, drugId)
+	if errors.Is(err, ErrDrugNotFound) {
+		return nil, ErrDrugNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+
+	schedule, err := svc.repository.GetDrugScheduleByID(cxt, drugId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		default:
+			if errors.Is(err, ErrScheduleNotFound) {
+				return nil, ErrScheduleNotFound
+			} else if errors.Is(err, ErrExecuteStatement) {
+				return nil, ErrExecuteStatement
+			} else {
+				return nil, ErrServiceDrugs
+			}
+		}
+	}
+
+	return schedule, nil
+}
+
+func (svc service) SetDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	_, err := svc.repository.GetDrugItemByID(cxt, drugId)
+	if errors.Is(err, ErrDrugNotFound) {
+		return ErrDrugNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
+	}
+
+	// Call repository
+	err = svc.repository.SaveDrugSchedule(cxt, drugId, form)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrDrugNotFound) {
+				return ErrDrugNotFound
+			} else {
+				return ErrUpdatingRecord
+			}
+		}
+	}
+
+	return nil
+}
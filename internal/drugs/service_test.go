@@ -4,6 +4,7 @@ import (
 	"context"
 	"kiramishima/ionix/internal/mocks"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/importer"
 	"testing"
 	"time"
 
@@ -42,23 +43,24 @@ func TestService_GetListDrugs(t *testing.T) {
 	}
 	// t.Log(good.ValidateBcryptPassword(user.Password, good.Password))
 
-	repo.EXPECT().GetDrugsData(gomock.Any()).Times(1).Return(drugs, nil)
-	repo.EXPECT().GetDrugsData(gomock.Any()).Times(1).Return(nil, ErrNoRecords)
+	repo.EXPECT().GetDrugsData(gomock.Any(), gomock.Any()).Times(1).Return(drugs, len(drugs), nil)
+	repo.EXPECT().GetDrugsData(gomock.Any(), gomock.Any()).Times(1).Return(nil, 0, ErrNoRecords)
 	//repo.EXPECT().FindUserByCredentials(gomock.Any(), notExist).Times(1).Return(nil, ErrUserNotFound)
 
-	svc := NewDrugService(repo, logger, 5)
+	svc := NewDrugService(repo, logger, 5, time.Minute)
 
 	t.Run("Ok- Getting Data", func(t *testing.T) {
 		ctx := context.Background()
-		var item, err = svc.GetListDrugs(ctx)
+		var item, total, err = svc.GetListDrugs(ctx, models.NewDrugQuery())
 		t.Log(item, err)
 		assert.NoError(t, err)
 		assert.Equal(t, len(item) > 0, true)
+		assert.Equal(t, 2, total)
 	})
 
 	t.Run("Ok - No Rows", func(t *testing.T) {
 		ctx := context.Background()
-		var item, err = svc.GetListDrugs(ctx)
+		var item, _, err = svc.GetListDrugs(ctx, nil)
 		t.Log(item, err)
 		assert.Error(t, err)
 		assert.Equal(t, len(item) == 0, true)
@@ -79,7 +81,7 @@ func TestService_NewDrug(t *testing.T) {
 	var approved = true
 	var minDose = 1
 	var maxDose = 2
-	var availableAt = time.Now().String()
+	var availableAt = models.DateTime{Time: time.Now()}
 	var item = &models.DrugForm{
 		Name:        &name,
 		Approved:    &approved,
@@ -91,7 +93,7 @@ func TestService_NewDrug(t *testing.T) {
 
 	//repo.EXPECT().FindUserByCredentials(gomock.Any(), notExist).Times(1).Return(nil, ErrUserNotFound)
 
-	svc := NewDrugService(repo, logger, 5)
+	svc := NewDrugService(repo, logger, 5, time.Minute)
 
 	t.Run("Ok- Getting Data", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10)*time.Second)
@@ -131,13 +133,13 @@ func TestService_UpdateDrug(t *testing.T) {
 	var approved = true
 	var minDose = 1
 	var maxDose = 2
-	// var availableAt = time.Now().String()
+	var availableAt = models.DateTime{Time: time.Now()}
 	var item = &models.DrugForm{
 		Name:        &name,
 		Approved:    &approved,
 		MinDose:     &minDose,
 		MaxDose:     &maxDose,
-		AvailableAt: nil,
+		AvailableAt: &availableAt,
 	}
 	// Record
 	var drug = &models.Drug{
@@ -149,28 +151,39 @@ func TestService_UpdateDrug(t *testing.T) {
 		AvailableAt: time.Now(),
 	}
 
-	svc := NewDrugService(repo, logger, 10)
+	svc := NewDrugService(repo, logger, 10, time.Minute)
 
 	t.Run("Ok- Updating Data", func(t *testing.T) {
 		ctx := context.Background()
 		id := 1
 
 		repo.EXPECT().GetDrugItemByID(gomock.Any(), id).Times(1).Return(drug, nil)
-		repo.EXPECT().UpdateDrugItem(gomock.Any(), id, gomock.Any()).Times(1).Return(nil)
+		repo.EXPECT().UpdateDrugItem(gomock.Any(), id, 1, gomock.Any()).Times(1).Return(nil)
 
-		var err = svc.UpdateDrug(ctx, id, item)
+		var err = svc.UpdateDrug(ctx, id, 1, item)
 		t.Log(err)
 		assert.NoError(t, err)
 		// assert.Equal(t, len(item) > 0, true)
 	})
 
+	t.Run("Stale version", func(t *testing.T) {
+		ctx := context.Background()
+		id := 1
+
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), id).Times(1).Return(drug, nil)
+		repo.EXPECT().UpdateDrugItem(gomock.Any(), id, 1, gomock.Any()).Times(1).Return(ErrVersionConflict)
+
+		var err = svc.UpdateDrug(ctx, id, 1, item)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
 	t.Run("No existing record", func(t *testing.T) {
 		ctx := context.Background()
 
 		id := 2
 		repo.EXPECT().GetDrugItemByID(gomock.Any(), gomock.Any()).Times(1).Return(nil, ErrDrugNotFound)
 
-		var err = svc.UpdateDrug(ctx, id, item)
+		var err = svc.UpdateDrug(ctx, id, 1, item)
 		t.Log(err)
 		assert.Error(t, err)
 		assert.EqualError(t, err, ErrDrugNotFound.Error())
@@ -191,7 +204,7 @@ func TestService_DeleteDrug(t *testing.T) {
 	var approved = true
 	var minDose = 1
 	var maxDose = 2
-	// var availableAt = time.Now().String()
+	// var availableAt = models.DateTime{Time: time.Now()}
 	/*var item = &models.DrugForm{
 		Name:        &name,
 		Approved:    &approved,
@@ -216,7 +229,7 @@ func TestService_DeleteDrug(t *testing.T) {
 	// repo.EXPECT().DeleteDrugItem(gomock.Any(), gomock.Eq(1)).Times(1).Return(nil)
 	//repo.EXPECT().FindUserByCredentials(gomock.Any(), notExist).Times(1).Return(nil, ErrUserNotFound)
 
-	svc := NewDrugService(repo, logger, 5)
+	svc := NewDrugService(repo, logger, 5, time.Minute)
 
 	t.Run("Ok- Deleting Data", func(t *testing.T) {
 		ctx := context.Background()
@@ -224,8 +237,8 @@ func TestService_DeleteDrug(t *testing.T) {
 		id := 1
 
 		repo.EXPECT().GetDrugItemByID(gomock.Any(), id).Times(1).Return(drug, nil)
-		repo.EXPECT().DeleteDrugItem(gomock.Any(), id).Times(1).Return(nil)
-		var err = svc.DeleteDrug(ctx, id)
+		repo.EXPECT().DeleteDrugItem(gomock.Any(), id, 1).Times(1).Return(nil)
+		var err = svc.DeleteDrug(ctx, id, 1)
 		t.Log(err)
 		assert.NoError(t, err)
 		// assert.Equal(t, len(item) > 0, true)
@@ -236,9 +249,118 @@ func TestService_DeleteDrug(t *testing.T) {
 		id := 1
 		repo.EXPECT().GetDrugItemByID(gomock.Any(), gomock.Any()).Times(1).Return(nil, ErrDrugNotFound)
 
-		var err = svc.DeleteDrug(ctx, id)
+		var err = svc.DeleteDrug(ctx, id, 1)
 		t.Log(err)
 		assert.Error(t, err)
 		assert.EqualError(t, err, ErrDrugNotFound.Error())
 	})
 }
+
+func TestService_GetDrug(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockDrugRepository(mockCtrl)
+
+	var drug = &models.Drug{
+		ID:          1,
+		Name:        "medicament 1",
+		Approved:    true,
+		MinDose:     1,
+		MaxDose:     5,
+		AvailableAt: time.Now(),
+	}
+
+	svc := NewDrugService(repo, logger, 5*time.Second, time.Minute)
+
+	t.Run("Ok- Getting Data", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), 1).Times(1).Return(drug, nil)
+
+		var item, err = svc.GetDrug(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, drug.Name, item.Name)
+	})
+
+	t.Run("No existing record", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), 2).Times(1).Return(nil, ErrDrugNotFound)
+
+		var item, err = svc.GetDrug(ctx, 2)
+		assert.Nil(t, item)
+		assert.EqualError(t, err, ErrDrugNotFound.Error())
+	})
+}
+
+func TestService_SetDrugSchedule(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockDrugRepository(mockCtrl)
+
+	var doses, minInterval, recommended = 2, 21, 28
+	var form = &models.DrugScheduleForm{Doses: &doses, MinIntervalDays: &minInterval, RecommendedIntervalDays: &recommended}
+
+	svc := NewDrugService(repo, logger, 5*time.Second, time.Minute)
+
+	t.Run("Ok- Saving schedule", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), 1).Times(1).Return(&models.Drug{ID: 1}, nil)
+		repo.EXPECT().SaveDrugSchedule(gomock.Any(), 1, form).Times(1).Return(nil)
+
+		assert.NoError(t, svc.SetDrugSchedule(ctx, 1, form))
+	})
+
+	t.Run("No existing drug", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), 2).Times(1).Return(nil, ErrDrugNotFound)
+
+		assert.ErrorIs(t, svc.SetDrugSchedule(ctx, 2, form), ErrDrugNotFound)
+	})
+
+	t.Run("Drug without schedule", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetDrugItemByID(gomock.Any(), 3).Times(1).Return(&models.Drug{ID: 3}, nil)
+		repo.EXPECT().GetDrugScheduleByID(gomock.Any(), 3).Times(1).Return(nil, ErrScheduleNotFound)
+
+		_, err := svc.GetDrugSchedule(ctx, 3)
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+	})
+}
+
+func TestService_ImportDrugs_Timeout(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockDrugRepository(mockCtrl)
+
+	// a file at the row limit takes longer than the timeout of the other requests
+	var forms = make([]*models.DrugForm, importer.MaxRows)
+	repo.EXPECT().
+		ImportDrugItems(gomock.Any(), gomock.Len(importer.MaxRows), models.ImportOptions{Mode: models.ImportAtomic}).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, forms []*models.DrugForm, _ models.ImportOptions) ([]error, error) {
+			time.Sleep(20 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.Greater(t, time.Until(deadline), 30*time.Second)
+			return make([]error, len(forms)), nil
+		})
+
+	svc := NewDrugService(repo, logger, time.Millisecond, time.Minute)
+	errs, err := svc.ImportDrugs(context.Background(), forms, models.ImportOptions{Mode: models.ImportAtomic})
+	assert.NoError(t, err)
+	assert.Len(t, errs, importer.MaxRows)
+}
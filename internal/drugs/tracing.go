@@ -0,0 +1,89 @@
+package drugs
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+)
+
+var _ interfaces.DrugService = (*tracedService)(nil)
+
+// tracedService starts a span per method of the service, the statements of the repository are its children
+type tracedService struct {
+	next interfaces.DrugService
+}
+
+// NewTracedDrugService wraps a drug service with spans
+func NewTracedDrugService(next interfaces.DrugService) *tracedService {
+	return &tracedService{next: next}
+}
+
+func (s tracedService) GetListDrugs(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error) {
+	ctx, span := tracing.Start(ctx, "DrugService.GetListDrugs")
+	list, total, err := s.next.GetListDrugs(ctx, query)
+	tracing.End(span, err)
+	return list, total, err
+}
+
+func (s tracedService) ExportDrugs(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error {
+	ctx, span := tracing.Start(ctx, "DrugService.ExportDrugs")
+	var rows int
+	err := s.next.ExportDrugs(ctx, query, func(drug *models.Drug) error {
+		rows++
+		return fn(drug)
+	})
+	span.SetAttributes(attribute.Int("export.rows", rows))
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) GetDrug(ctx context.Context, drugId int) (*models.Drug, error) {
+	ctx, span := tracing.Start(ctx, "DrugService.GetDrug", attribute.Int("drug.id", drugId))
+	item, err := s.next.GetDrug(ctx, drugId)
+	tracing.End(span, err)
+	return item, err
+}
+
+func (s tracedService) NewDrug(ctx context.Context, form *models.DrugForm) error {
+	ctx, span := tracing.Start(ctx, "DrugService.NewDrug")
+	err := s.next.NewDrug(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ImportDrugs(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "DrugService.ImportDrugs", attribute.Int("import.rows", len(forms)), attribute.Bool("import.dry_run", opts.DryRun))
+	errs, err := s.next.ImportDrugs(ctx, forms, opts)
+	tracing.End(span, err)
+	return errs, err
+}
+
+func (s tracedService) UpdateDrug(ctx context.Context, drugId int, version int, form *models.DrugForm) error {
+	ctx, span := tracing.Start(ctx, "DrugService.UpdateDrug", attribute.Int("drug.id", drugId))
+	err := s.next.UpdateDrug(ctx, drugId, version, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) DeleteDrug(ctx context.Context, drugId int, version int) error {
+	ctx, span := tracing.Start(ctx, "DrugService.DeleteDrug", attribute.Int("drug.id", drugId))
+	err := s.next.DeleteDrug(ctx, drugId, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) GetDrugSchedule(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	ctx, span := tracing.Start(ctx, "DrugService.GetDrugSchedule", attribute.Int("drug.id", drugId))
+	item, err := s.next.GetDrugSchedule(ctx, drugId)
+	tracing.End(span, err)
+	return item, err
+}
+
+func (s tracedService) SetDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	ctx, span := tracing.Start(ctx, "DrugService.SetDrugSchedule", attribute.Int("drug.id", drugId))
+	err := s.next.SetDrugSchedule(ctx, drugId, form)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,11 @@
+package health
+
+import "errors"
+
+var (
+	ErrShuttingDown     = errors.New("El servicio se está deteniendo")
+	ErrDatabaseDown     = errors.New("No hay conexión con la base de datos")
+	ErrMigrationVersion = errors.New("No se pudo obtener la versión de las migraciones")
+	ErrMigrationDirty   = errors.New("La última migración falló")
+	ErrMigrationPending = errors.New("La base de datos está en la versión")
+)
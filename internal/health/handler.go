@@ -0,0 +1,59 @@
+package health
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+)
+
+var _ impl.HealthHandlers = (*handler)(nil)
+
+// NewHealthHandlers creates an instance of the health handlers, the probes are not behind jwt
+func NewHealthHandlers(r *chi.Mux, logger *zap.Logger, s impl.HealthService, render *render.Render) {
+	handler := &handler{
+		logger:   logger,
+		service:  s,
+		response: render,
+	}
+
+	r.Get("/healthz", handler.LivenessHandler)
+	r.Get("/readyz", handler.ReadinessHandler)
+	r.Get("/v1/status", handler.StatusHandler)
+}
+
+type handler struct {
+	logger   *zap.Logger
+	service  impl.HealthService
+	response *render.Render
+}
+
+// LivenessHandler the process is alive and serving requests
+func (h handler) LivenessHandler(w http.ResponseWriter, req *http.Request) {
+	_ = h.response.JSON(w, http.StatusOK, models.Message{Message: models.StatusOK})
+}
+
+// ReadinessHandler the service can receive traffic
+func (h handler) ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	var readiness = h.service.Ready(req.Context())
+
+	var status = http.StatusOK
+	if readiness.Status != models.StatusOK {
+		h.logger.Info("[INFO] not ready", zap.Any("checks", readiness.Checks))
+		status = http.StatusServiceUnavailable
+	}
+	_ = h.response.JSON(w, status, readiness)
+}
+
+// StatusHandler detailed state of the service
+func (h handler) StatusHandler(w http.ResponseWriter, req *http.Request) {
+	var body = h.service.Status(req.Context())
+
+	var status = http.StatusOK
+	if body.Status != models.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	_ = h.response.JSON(w, status, body)
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unrolled/render"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/mocks"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_ReadinessHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		buildStubs    func(uc *mocks.MockHealthService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Ready": {
+			buildStubs: func(uc *mocks.MockHealthService) {
+				uc.EXPECT().Ready(gomock.Any()).Times(1).Return(&models.Readiness{Status: models.StatusOK})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		"Not ready": {
+			buildStubs: func(uc *mocks.MockHealthService) {
+				uc.EXPECT().Ready(gomock.Any()).Times(1).Return(&models.Readiness{
+					Status: models.StatusFail,
+					Checks: []models.HealthCheck{{Name: "shutdown", Status: models.StatusFail, Error: ErrShuttingDown.Error()}},
+				})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"name":"shutdown"`)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockHealthService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+			}
+			h.ReadinessHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_LivenessHandler(t *testing.T) {
+	t.Parallel()
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	h := handler{logger: zap.NewNop(), response: render.New()}
+	h.LivenessHandler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
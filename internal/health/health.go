@@ -0,0 +1,24 @@
+package health
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/unrolled/render"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/migrate"
+	"time"
+)
+
+// Module health
+var Module = fx.Module("health",
+	// the server marks the service as shutting down, so it is shared
+	fx.Provide(func(conn *sqlx.DB, migrator *migrate.Migrator, logger *zap.Logger, cfg *models.Configuration) impl.HealthService {
+		return NewHealthService(conn, migrator, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+	}),
+	fx.Invoke(func(r *chi.Mux, logger *zap.Logger, render *render.Render, svc impl.HealthService) {
+		NewHealthHandlers(r, logger, svc, render)
+	}),
+)
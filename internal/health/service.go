@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"sync/atomic"
+	"time"
+)
+
+// Version build version, set at build time with -ldflags "-X kiramishima/ionix/internal/health.Version=..."
+var Version = "dev"
+
+// Migrations current and expected version of the schema
+type Migrations interface {
+	Version(ctx context.Context) (uint, bool, error)
+	Latest() uint
+}
+
+var _ impl.HealthService = (*service)(nil)
+
+// NewHealthService creates a new health service
+func NewHealthService(db *sqlx.DB, migrations Migrations, logger *zap.Logger, timeout time.Duration) *service {
+	return &service{
+		db:             db,
+		migrations:     migrations,
+		logger:         logger,
+		startedAt:      time.Now(),
+		contextTimeOut: timeout,
+	}
+}
+
+type service struct {
+	db             *sqlx.DB
+	migrations     Migrations
+	logger         *zap.Logger
+	startedAt      time.Time
+	shuttingDown   atomic.Bool
+	contextTimeOut time.Duration
+}
+
+// ShuttingDown marks the service as not ready, called when the graceful shutdown begins
+func (svc *service) ShuttingDown() {
+	svc.shuttingDown.Store(true)
+}
+
+func (svc *service) Ready(ctx context.Context) *models.Readiness {
+	var readiness = &models.Readiness{Status: models.StatusOK, Checks: make([]models.HealthCheck, 0, 3)}
+	add := func(name string, err error) {
+		var check = models.HealthCheck{Name: name, Status: models.StatusOK}
+		if err != nil {
+			check.Status = models.StatusFail
+			check.Error = err.Error()
+			readiness.Status = models.StatusFail
+		}
+		readiness.Checks = append(readiness.Checks, check)
+	}
+
+	if svc.shuttingDown.Load() {
+		add("shutdown", ErrShuttingDown)
+	} else {
+		add("shutdown", nil)
+	}
+
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	if err := svc.db.PingContext(cxt); err != nil {
+		svc.logger.Error(err.Error())
+		add("database", ErrDatabaseDown)
+	} else {
+		add("database", nil)
+	}
+
+	add("migrations", svc.checkMigrations(cxt))
+
+	return readiness
+}
+
+func (svc *service) Status(ctx context.Context) *models.ServiceStatus {
+	var uptime = time.Since(svc.startedAt).Truncate(time.Second)
+	var stats = svc.db.Stats()
+	var status = &models.ServiceStatus{
+		Status:        models.StatusOK,
+		Version:       Version,
+		StartedAt:     svc.startedAt,
+		Uptime:        uptime.String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		ShuttingDown:  svc.shuttingDown.Load(),
+		Database: models.DatabaseStatus{
+			Status:          models.StatusOK,
+			ExpectedVersion: svc.migrations.Latest(),
+			Pool: models.PoolStats{
+				MaxOpenConnections: stats.MaxOpenConnections,
+				OpenConnections:    stats.OpenConnections,
+				InUse:              stats.InUse,
+				Idle:               stats.Idle,
+				WaitCount:          stats.WaitCount,
+				WaitDuration:       stats.WaitDuration.String(),
+				MaxIdleClosed:      stats.MaxIdleClosed,
+				MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+				MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+			},
+		},
+	}
+
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	if err := svc.db.PingContext(cxt); err != nil {
+		svc.logger.Error(err.Error())
+		status.Database.Status = models.StatusFail
+		status.Database.Error = ErrDatabaseDown.Error()
+	} else if current, dirty, err := svc.migrations.Version(cxt); err != nil {
+		svc.logger.Error(err.Error())
+		status.Database.Status = models.StatusFail
+		status.Database.Error = ErrMigrationVersion.Error()
+	} else {
+		status.Database.MigrationVersion = current
+		status.Database.MigrationDirty = dirty
+	}
+
+	if status.ShuttingDown || status.Database.Status != models.StatusOK {
+		status.Status = models.StatusFail
+	}
+	return status
+}
+
+// checkMigrations the schema must be at the version of the embedded migrations
+func (svc *service) checkMigrations(ctx context.Context) error {
+	current, dirty, err := svc.migrations.Version(ctx)
+	if err != nil {
+		svc.logger.Error(err.Error())
+		return ErrMigrationVersion
+	}
+	if dirty {
+		return ErrMigrationDirty
+	}
+	if expected := svc.migrations.Latest(); current != expected {
+		return fmt.Errorf("%w: %d, se esperaba %d", ErrMigrationPending, current, expected)
+	}
+	return nil
+}
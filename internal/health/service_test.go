@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/models"
+	"testing"
+	"time"
+)
+
+type migrations struct {
+	current uint
+	dirty   bool
+	err     error
+}
+
+func (m migrations) Version(context.Context) (uint, bool, error) {
+	return m.current, m.dirty, m.err
+}
+
+func (m migrations) Latest() uint {
+	return 8
+}
+
+func newService(t *testing.T, m Migrations) (*service, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+	return NewHealthService(sqlx.NewDb(db, "sqlmock"), m, zap.NewNop(), 5*time.Second), mock, func() {
+		_ = db.Close()
+	}
+}
+
+func TestService_Ready(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Ready", func(t *testing.T) {
+		svc, mock, closeDB := newService(t, migrations{current: 8})
+		defer closeDB()
+		mock.ExpectPing()
+
+		var readiness = svc.Ready(context.Background())
+		assert.Equal(t, models.StatusOK, readiness.Status)
+		assert.Equal(t, 3, len(readiness.Checks))
+	})
+
+	t.Run("Pending migrations", func(t *testing.T) {
+		svc, mock, closeDB := newService(t, migrations{current: 7})
+		defer closeDB()
+		mock.ExpectPing()
+
+		var readiness = svc.Ready(context.Background())
+		assert.Equal(t, models.StatusFail, readiness.Status)
+		assert.Equal(t, "migrations", readiness.Checks[2].Name)
+		assert.Contains(t, readiness.Checks[2].Error, "se esperaba 8")
+	})
+
+	t.Run("Database down", func(t *testing.T) {
+		svc, mock, closeDB := newService(t, migrations{err: sql.ErrConnDone})
+		defer closeDB()
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		var readiness = svc.Ready(context.Background())
+		assert.Equal(t, models.StatusFail, readiness.Status)
+		assert.Equal(t, ErrDatabaseDown.Error(), readiness.Checks[1].Error)
+	})
+
+	t.Run("Shutting down", func(t *testing.T) {
+		svc, mock, closeDB := newService(t, migrations{current: 8})
+		defer closeDB()
+		mock.ExpectPing()
+
+		svc.ShuttingDown()
+		var readiness = svc.Ready(context.Background())
+		assert.Equal(t, models.StatusFail, readiness.Status)
+		assert.Equal(t, ErrShuttingDown.Error(), readiness.Checks[0].Error)
+	})
+}
+
+func TestService_Status(t *testing.T) {
+	t.Parallel()
+	svc, mock, closeDB := newService(t, migrations{current: 8})
+	defer closeDB()
+	mock.ExpectPing()
+
+	var status = svc.Status(context.Background())
+	assert.Equal(t, models.StatusOK, status.Status)
+	assert.Equal(t, Version, status.Version)
+	assert.Equal(t, uint(8), status.Database.MigrationVersion)
+	assert.Equal(t, uint(8), status.Database.ExpectedVersion)
+}
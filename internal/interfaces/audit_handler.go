@@ -0,0 +1,8 @@
+package interfaces
+
+import "net/http"
+
+// AuditHandlers interface
+type AuditHandlers interface {
+	ListAuditHandler(w http.ResponseWriter, req *http.Request)
+}
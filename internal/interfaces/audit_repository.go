@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"kiramishima/ionix/internal/models"
+)
+
+// AuditRepository interface
+type AuditRepository interface {
+	GetAuditData(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, int, error)
+}
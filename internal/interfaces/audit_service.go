@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	models "kiramishima/ionix/internal/models"
+)
+
+// AuditService interface
+type AuditService interface {
+	GetAuditLog(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, int, error)
+}
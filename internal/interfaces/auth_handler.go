@@ -5,4 +5,12 @@ import "net/http"
 type AuthHandlers interface {
 	SignUpHandler(w http.ResponseWriter, req *http.Request)
 	LoginHandler(w http.ResponseWriter, req *http.Request)
+	RefreshHandler(w http.ResponseWriter, req *http.Request)
+	LogoutHandler(w http.ResponseWriter, req *http.Request)
+	UpdateRoleHandler(w http.ResponseWriter, req *http.Request)
+	ForgotPasswordHandler(w http.ResponseWriter, req *http.Request)
+	ResetPasswordHandler(w http.ResponseWriter, req *http.Request)
+	VerifyEmailHandler(w http.ResponseWriter, req *http.Request)
+	ResendVerificationHandler(w http.ResponseWriter, req *http.Request)
+	UnlockHandler(w http.ResponseWriter, req *http.Request)
 }
@@ -3,10 +3,36 @@ package interfaces
 import (
 	"context"
 	"kiramishima/ionix/internal/models"
+	"time"
 )
 
 // AuthRepository interface
 type AuthRepository interface {
 	FindUserByCredentials(ctx context.Context, form *models.AuthForm) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	FindUserByID(ctx context.Context, userId int) (*models.User, error)
+	UpdateUserRole(ctx context.Context, userId int, role models.Role) error
 	CreateAccount(ctx context.Context, form *models.RegisterForm) error
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) error
+	RevokeRefreshToken(ctx context.Context, tokenHash string, userId int) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	ResetPassword(ctx context.Context, tokenHash string, password string) error
+	VerifyEmail(ctx context.Context, tokenHash string) error
+	FindSignInFailures(ctx context.Context, email string, ip string) ([]*models.SignInFailure, error)
+	RecordSignInFailure(ctx context.Context, email string, ip string, window time.Duration) error
+	ClearSignInFailures(ctx context.Context, email string) error
+	UnlockAccount(ctx context.Context, userId int, email string) error
+	UpdatePassword(ctx context.Context, userId int, password string) error
+	FindUserToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	UseUserToken(ctx context.Context, tokenId int64) error
+	FindMFA(ctx context.Context, userId int) (*models.UserMFA, error)
+	SaveMFAEnrollment(ctx context.Context, userId int, secret string, codeHashes []string) error
+	EnableMFA(ctx context.Context, userId int, step int64) error
+	UseMFAStep(ctx context.Context, userId int, step int64) error
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) error
+	DeleteMFA(ctx context.Context, userId int) error
+	UpdateRoleMFA(ctx context.Context, role models.Role, required bool) error
 }
@@ -3,10 +3,25 @@ package interfaces
 import (
 	"context"
 	models "kiramishima/ionix/internal/models"
+	"time"
 )
 
 // AuthService interface
 type AuthService interface {
-	SignIn(ctx context.Context, form *models.AuthForm) (*models.AuthResponse, error)
+	SignIn(ctx context.Context, form *models.AuthForm, ip string) (*models.AuthResponse, error)
 	SignUp(ctx context.Context, form *models.RegisterForm) error
+	Refresh(ctx context.Context, form *models.RefreshTokenForm) (*models.AuthResponse, error)
+	UpdateRole(ctx context.Context, userId int, form *models.RoleForm) error
+	Logout(ctx context.Context, userId int, jti string, expiresAt time.Time, form *models.RefreshTokenForm) error
+	ForgotPassword(ctx context.Context, form *models.EmailForm) error
+	ResetPassword(ctx context.Context, form *models.ResetPasswordForm) error
+	VerifyEmail(ctx context.Context, form *models.VerifyEmailForm) error
+	ResendVerification(ctx context.Context, form *models.EmailForm) error
+	Unlock(ctx context.Context, userId int) error
+	VerifyMFA(ctx context.Context, form *models.MFAVerifyForm, ip string) (*models.AuthResponse, error)
+	EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, userId int, form *models.MFACodeForm) error
+	DisableMFA(ctx context.Context, userId int, form *models.MFACodeForm) error
+	ResetMFA(ctx context.Context, userId int) error
+	UpdateRoleMFA(ctx context.Context, role string, form *models.RoleMFAForm) error
 }
@@ -5,7 +5,13 @@ import "net/http"
 // DrugsHandlers interface
 type DrugsHandlers interface {
 	ListDrugsHandler(w http.ResponseWriter, req *http.Request)
+	ExportDrugsHandler(w http.ResponseWriter, req *http.Request)
+	GetDrugHandler(w http.ResponseWriter, req *http.Request)
 	CreateDrugHandler(w http.ResponseWriter, req *http.Request)
+	ImportDrugsHandler(w http.ResponseWriter, req *http.Request)
 	UpdateDrugHandler(w http.ResponseWriter, req *http.Request)
+	PatchDrugHandler(w http.ResponseWriter, req *http.Request)
 	DeleteDrugHandler(w http.ResponseWriter, req *http.Request)
+	GetDrugScheduleHandler(w http.ResponseWriter, req *http.Request)
+	SetDrugScheduleHandler(w http.ResponseWriter, req *http.Request)
 }
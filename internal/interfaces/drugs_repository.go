@@ -7,9 +7,13 @@ import (
 
 // DrugRepository interface
 type DrugRepository interface {
-	GetDrugsData(ctx context.Context) ([]*models.Drug, error)
+	GetDrugsData(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error)
+	ExportDrugItems(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error
 	CreateNewDrugItem(ctx context.Context, form *models.DrugForm) error
+	ImportDrugItems(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error)
 	GetDrugItemByID(ctx context.Context, drugId int) (*models.Drug, error)
-	UpdateDrugItem(ctx context.Context, drugId int, form *models.Drug) error
-	DeleteDrugItem(ctx context.Context, drugId int) error
+	UpdateDrugItem(ctx context.Context, drugId int, version int, form *models.Drug) error
+	DeleteDrugItem(ctx context.Context, drugId int, version int) error
+	GetDrugScheduleByID(ctx context.Context, drugId int) (*models.DrugSchedule, error)
+	SaveDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error
 }
@@ -7,8 +7,13 @@ import (
 
 // DrugService interface
 type DrugService interface {
-	GetListDrugs(ctx context.Context) ([]*models.Drug, error)
+	GetListDrugs(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error)
+	ExportDrugs(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error
+	GetDrug(ctx context.Context, drugId int) (*models.Drug, error)
 	NewDrug(ctx context.Context, form *models.DrugForm) error
-	UpdateDrug(ctx context.Context, drugId int, form *models.DrugForm) error
-	DeleteDrug(ctx context.Context, drugId int) error
+	ImportDrugs(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error)
+	UpdateDrug(ctx context.Context, drugId int, version int, form *models.DrugForm) error
+	DeleteDrug(ctx context.Context, drugId int, version int) error
+	GetDrugSchedule(ctx context.Context, drugId int) (*models.DrugSchedule, error)
+	SetDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error
 }
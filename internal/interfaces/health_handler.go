@@ -0,0 +1,10 @@
+package interfaces
+
+import "net/http"
+
+// HealthHandlers interface
+type HealthHandlers interface {
+	LivenessHandler(w http.ResponseWriter, req *http.Request)
+	ReadinessHandler(w http.ResponseWriter, req *http.Request)
+	StatusHandler(w http.ResponseWriter, req *http.Request)
+}
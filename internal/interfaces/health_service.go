@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"kiramishima/ionix/internal/models"
+)
+
+// HealthService reports the liveness, readiness and status of the service
+type HealthService interface {
+	Ready(ctx context.Context) *models.Readiness
+	Status(ctx context.Context) *models.ServiceStatus
+	ShuttingDown()
+}
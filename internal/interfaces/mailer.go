@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"kiramishima/ionix/internal/models"
+)
+
+// Mailer sends the mails of the API, the implementation is chosen with MAILER_DRIVER
+type Mailer interface {
+	Send(ctx context.Context, mail *models.Mail) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+// PasswordHasher hashes the passwords of the accounts, the implementation is chosen with PASSWORD_ALGORITHM
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify compares the password with a stored hash, rehash reports that the hash should be replaced
+	// because it uses another scheme or older parameters
+	Verify(password string, hash string) (ok bool, rehash bool, err error)
+}
+
+// PasswordPolicy checks the new passwords, the errors are models.ValidationErrors
+type PasswordPolicy interface {
+	Check(password string) error
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import "net/http"
+
+// PatientsHandlers interface
+type PatientsHandlers interface {
+	ListPatientsHandler(w http.ResponseWriter, req *http.Request)
+	GetPatientHandler(w http.ResponseWriter, req *http.Request)
+	CreatePatientHandler(w http.ResponseWriter, req *http.Request)
+	UpdatePatientHandler(w http.ResponseWriter, req *http.Request)
+	DeletePatientHandler(w http.ResponseWriter, req *http.Request)
+	GetPatientScheduleHandler(w http.ResponseWriter, req *http.Request)
+}
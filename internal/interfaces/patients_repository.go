@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"kiramishima/ionix/internal/models"
+)
+
+// PatientRepository interface
+type PatientRepository interface {
+	GetPatientsData(ctx context.Context, query *models.PatientQuery) ([]*models.Patient, int, error)
+	CreateNewPatientItem(ctx context.Context, form *models.PatientForm) error
+	GetPatientItemByID(ctx context.Context, patientId int) (*models.Patient, error)
+	UpdatePatientItem(ctx context.Context, patientId int, form *models.Patient) error
+	DeletePatientItem(ctx context.Context, patientId int) error
+	GetPatientSeries(ctx context.Context, patientId int) ([]*models.PatientSeries, error)
+}
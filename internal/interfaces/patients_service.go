@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	models "kiramishima/ionix/internal/models"
+)
+
+// PatientService interface
+type PatientService interface {
+	GetListPatients(ctx context.Context, query *models.PatientQuery) ([]*models.Patient, int, error)
+	GetPatient(ctx context.Context, patientId int) (*models.Patient, error)
+	NewPatient(ctx context.Context, form *models.PatientForm) error
+	UpdatePatient(ctx context.Context, patientId int, form *models.PatientForm) error
+	DeletePatient(ctx context.Context, patientId int) error
+	GetPatientSchedule(ctx context.Context, patientId int) ([]*models.SeriesProgress, error)
+}
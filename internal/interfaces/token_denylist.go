@@ -0,0 +1,8 @@
+package interfaces
+
+import "context"
+
+// TokenDenylist reports if an access token was revoked before its expiration
+type TokenDenylist interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"time"
+)
+
+// TokenSigner signs the access tokens and verifies them in the requests, the keys are chosen with
+// JWT_KEY_FILES or JWT_PRIVATE_KEY
+type TokenSigner interface {
+	Sign(user *models.User) (string, error)
+	// TTL lifetime of the signed tokens
+	TTL() time.Duration
+	// Verifier stores the token of the request and its error for jwtauth.FromContext, it goes before
+	// utils.Authenticator
+	Verifier(next http.Handler) http.Handler
+}
@@ -5,7 +5,11 @@ import "net/http"
 // VaccinationsHandlers interface
 type VaccinationsHandlers interface {
 	ListVaccinationsHandler(w http.ResponseWriter, req *http.Request)
+	ExportVaccinationsHandler(w http.ResponseWriter, req *http.Request)
+	GetVaccinationHandler(w http.ResponseWriter, req *http.Request)
 	CreateVaccinationHandler(w http.ResponseWriter, req *http.Request)
+	ImportVaccinationsHandler(w http.ResponseWriter, req *http.Request)
 	UpdateVaccinationHandler(w http.ResponseWriter, req *http.Request)
+	PatchVaccinationHandler(w http.ResponseWriter, req *http.Request)
 	DeleteVaccinationHandler(w http.ResponseWriter, req *http.Request)
 }
@@ -8,8 +8,10 @@ import (
 // VaccinationRepository interface
 type VaccinationRepository interface {
 	GetVaccinationsData(ctx context.Context) ([]*models.Vaccination, error)
+	ExportVaccinationItems(ctx context.Context, fn func(*models.Vaccination) error) error
 	CreateNewVaccinationItem(ctx context.Context, form *models.VaccinationForm) error
+	ImportVaccinationItems(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error)
 	GetVaccinationItemByID(ctx context.Context, vaccinationId int) (*models.Vaccination, error)
-	UpdateVaccinationItem(ctx context.Context, vaccinationId int, form *models.Vaccination) error
-	DeleteVaccinationItem(ctx context.Context, vaccinationId int) error
+	UpdateVaccinationItem(ctx context.Context, vaccinationId int, version int, form *models.Vaccination) error
+	DeleteVaccinationItem(ctx context.Context, vaccinationId int, version int) error
 }
@@ -8,7 +8,10 @@ import (
 // VaccinationService interface
 type VaccinationService interface {
 	GetListVaccinations(ctx context.Context) ([]*models.Vaccination, error)
+	ExportVaccinations(ctx context.Context, fn func(*models.Vaccination) error) error
+	GetVaccination(ctx context.Context, vaccinationId int) (*models.Vaccination, error)
 	NewVaccination(ctx context.Context, form *models.VaccinationForm) error
-	UpdateVaccination(ctx context.Context, vaccinationId int, form *models.VaccinationForm) error
-	DeleteVaccination(ctx context.Context, vaccinationId int) error
+	ImportVaccinations(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error)
+	UpdateVaccination(ctx context.Context, vaccinationId int, version int, form *models.VaccinationForm) error
+	DeleteVaccination(ctx context.Context, vaccinationId int, version int) error
 }
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: .\internal\interfaces\audit_repository.go
+//
+// Generated by this command:
+//
+//	mockgen -source .\internal\interfaces\audit_repository.go -destination .\internal\mocks\audit_repository.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "kiramishima/ionix/internal/models"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAuditData mocks base method.
+func (m *MockAuditRepository) GetAuditData(ctx context.Context, query *models.AuditQuery) ([]*models.AuditEntry, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditData", ctx, query)
+	ret0, _ := ret[0].([]*models.AuditEntry)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAuditData indicates an expected call of GetAuditData.
+func (mr *MockAuditRepositoryMockRecorder) GetAuditData(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditData", reflect.TypeOf((*MockAuditRepository)(nil).GetAuditData), ctx, query)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	models "kiramishima/ionix/internal/models"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +41,20 @@ func (m *MockAuthRepository) EXPECT() *MockAuthRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClearSignInFailures mocks base method.
+func (m *MockAuthRepository) ClearSignInFailures(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearSignInFailures", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearSignInFailures indicates an expected call of ClearSignInFailures.
+func (mr *MockAuthRepositoryMockRecorder) ClearSignInFailures(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearSignInFailures", reflect.TypeOf((*MockAuthRepository)(nil).ClearSignInFailures), ctx, email)
+}
+
 // CreateAccount mocks base method.
 func (m *MockAuthRepository) CreateAccount(ctx context.Context, form *models.RegisterForm) error {
 	m.ctrl.T.Helper()
@@ -54,6 +69,92 @@ func (mr *MockAuthRepositoryMockRecorder) CreateAccount(ctx, form any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAuthRepository)(nil).CreateAccount), ctx, form)
 }
 
+// CreateRefreshToken mocks base method.
+func (m *MockAuthRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) CreateRefreshToken(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).CreateRefreshToken), ctx, token)
+}
+
+// CreateUserToken mocks base method.
+func (m *MockAuthRepository) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUserToken indicates an expected call of CreateUserToken.
+func (mr *MockAuthRepositoryMockRecorder) CreateUserToken(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserToken", reflect.TypeOf((*MockAuthRepository)(nil).CreateUserToken), ctx, token)
+}
+
+// DeleteMFA mocks base method.
+func (m *MockAuthRepository) DeleteMFA(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMFA", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMFA indicates an expected call of DeleteMFA.
+func (mr *MockAuthRepositoryMockRecorder) DeleteMFA(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMFA", reflect.TypeOf((*MockAuthRepository)(nil).DeleteMFA), ctx, userId)
+}
+
+// EnableMFA mocks base method.
+func (m *MockAuthRepository) EnableMFA(ctx context.Context, userId int, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableMFA", ctx, userId, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableMFA indicates an expected call of EnableMFA.
+func (mr *MockAuthRepositoryMockRecorder) EnableMFA(ctx, userId, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableMFA", reflect.TypeOf((*MockAuthRepository)(nil).EnableMFA), ctx, userId, step)
+}
+
+// FindMFA mocks base method.
+func (m *MockAuthRepository) FindMFA(ctx context.Context, userId int) (*models.UserMFA, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindMFA", ctx, userId)
+	ret0, _ := ret[0].(*models.UserMFA)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindMFA indicates an expected call of FindMFA.
+func (mr *MockAuthRepositoryMockRecorder) FindMFA(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindMFA", reflect.TypeOf((*MockAuthRepository)(nil).FindMFA), ctx, userId)
+}
+
+// FindSignInFailures mocks base method.
+func (m *MockAuthRepository) FindSignInFailures(ctx context.Context, email, ip string) ([]*models.SignInFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSignInFailures", ctx, email, ip)
+	ret0, _ := ret[0].([]*models.SignInFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSignInFailures indicates an expected call of FindSignInFailures.
+func (mr *MockAuthRepositoryMockRecorder) FindSignInFailures(ctx, email, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSignInFailures", reflect.TypeOf((*MockAuthRepository)(nil).FindSignInFailures), ctx, email, ip)
+}
+
 // FindUserByCredentials mocks base method.
 func (m *MockAuthRepository) FindUserByCredentials(ctx context.Context, form *models.AuthForm) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -68,3 +169,259 @@ func (mr *MockAuthRepositoryMockRecorder) FindUserByCredentials(ctx, form any) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByCredentials", reflect.TypeOf((*MockAuthRepository)(nil).FindUserByCredentials), ctx, form)
 }
+
+// FindUserByEmail mocks base method.
+func (m *MockAuthRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByEmail", ctx, email)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByEmail indicates an expected call of FindUserByEmail.
+func (mr *MockAuthRepositoryMockRecorder) FindUserByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByEmail", reflect.TypeOf((*MockAuthRepository)(nil).FindUserByEmail), ctx, email)
+}
+
+// FindUserByID mocks base method.
+func (m *MockAuthRepository) FindUserByID(ctx context.Context, userId int) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByID", ctx, userId)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByID indicates an expected call of FindUserByID.
+func (mr *MockAuthRepositoryMockRecorder) FindUserByID(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByID", reflect.TypeOf((*MockAuthRepository)(nil).FindUserByID), ctx, userId)
+}
+
+// FindUserToken mocks base method.
+func (m *MockAuthRepository) FindUserToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserToken", ctx, tokenHash, purpose)
+	ret0, _ := ret[0].(*models.UserToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserToken indicates an expected call of FindUserToken.
+func (mr *MockAuthRepositoryMockRecorder) FindUserToken(ctx, tokenHash, purpose any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserToken", reflect.TypeOf((*MockAuthRepository)(nil).FindUserToken), ctx, tokenHash, purpose)
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockAuthRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockAuthRepositoryMockRecorder) IsTokenRevoked(ctx, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockAuthRepository)(nil).IsTokenRevoked), ctx, jti)
+}
+
+// RecordSignInFailure mocks base method.
+func (m *MockAuthRepository) RecordSignInFailure(ctx context.Context, email, ip string, window time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordSignInFailure", ctx, email, ip, window)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordSignInFailure indicates an expected call of RecordSignInFailure.
+func (mr *MockAuthRepositoryMockRecorder) RecordSignInFailure(ctx, email, ip, window any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSignInFailure", reflect.TypeOf((*MockAuthRepository)(nil).RecordSignInFailure), ctx, email, ip, window)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuthRepository) ResetPassword(ctx context.Context, tokenHash, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, tokenHash, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthRepositoryMockRecorder) ResetPassword(ctx, tokenHash, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuthRepository)(nil).ResetPassword), ctx, tokenHash, password)
+}
+
+// RevokeAccessToken mocks base method.
+func (m *MockAuthRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessToken", ctx, jti, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessToken indicates an expected call of RevokeAccessToken.
+func (mr *MockAuthRepositoryMockRecorder) RevokeAccessToken(ctx, jti, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessToken", reflect.TypeOf((*MockAuthRepository)(nil).RevokeAccessToken), ctx, jti, expiresAt)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockAuthRepository) RevokeRefreshToken(ctx context.Context, tokenHash string, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, tokenHash, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) RevokeRefreshToken(ctx, tokenHash, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).RevokeRefreshToken), ctx, tokenHash, userId)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockAuthRepository) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, tokenHash, next)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockAuthRepositoryMockRecorder) RotateRefreshToken(ctx, tokenHash, next any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockAuthRepository)(nil).RotateRefreshToken), ctx, tokenHash, next)
+}
+
+// SaveMFAEnrollment mocks base method.
+func (m *MockAuthRepository) SaveMFAEnrollment(ctx context.Context, userId int, secret string, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMFAEnrollment", ctx, userId, secret, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMFAEnrollment indicates an expected call of SaveMFAEnrollment.
+func (mr *MockAuthRepositoryMockRecorder) SaveMFAEnrollment(ctx, userId, secret, codeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMFAEnrollment", reflect.TypeOf((*MockAuthRepository)(nil).SaveMFAEnrollment), ctx, userId, secret, codeHashes)
+}
+
+// UnlockAccount mocks base method.
+func (m *MockAuthRepository) UnlockAccount(ctx context.Context, userId int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockAccount", ctx, userId, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockAccount indicates an expected call of UnlockAccount.
+func (mr *MockAuthRepositoryMockRecorder) UnlockAccount(ctx, userId, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockAccount", reflect.TypeOf((*MockAuthRepository)(nil).UnlockAccount), ctx, userId, email)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockAuthRepository) UpdatePassword(ctx context.Context, userId int, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, userId, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockAuthRepositoryMockRecorder) UpdatePassword(ctx, userId, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockAuthRepository)(nil).UpdatePassword), ctx, userId, password)
+}
+
+// UpdateRoleMFA mocks base method.
+func (m *MockAuthRepository) UpdateRoleMFA(ctx context.Context, role models.Role, required bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoleMFA", ctx, role, required)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRoleMFA indicates an expected call of UpdateRoleMFA.
+func (mr *MockAuthRepositoryMockRecorder) UpdateRoleMFA(ctx, role, required any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoleMFA", reflect.TypeOf((*MockAuthRepository)(nil).UpdateRoleMFA), ctx, role, required)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockAuthRepository) UpdateUserRole(ctx context.Context, userId int, role models.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", ctx, userId, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockAuthRepositoryMockRecorder) UpdateUserRole(ctx, userId, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockAuthRepository)(nil).UpdateUserRole), ctx, userId, role)
+}
+
+// UseMFAStep mocks base method.
+func (m *MockAuthRepository) UseMFAStep(ctx context.Context, userId int, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMFAStep", ctx, userId, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseMFAStep indicates an expected call of UseMFAStep.
+func (mr *MockAuthRepositoryMockRecorder) UseMFAStep(ctx, userId, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMFAStep", reflect.TypeOf((*MockAuthRepository)(nil).UseMFAStep), ctx, userId, step)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockAuthRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockAuthRepositoryMockRecorder) UseRecoveryCode(ctx, userId, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockAuthRepository)(nil).UseRecoveryCode), ctx, userId, codeHash)
+}
+
+// UseUserToken mocks base method.
+func (m *MockAuthRepository) UseUserToken(ctx context.Context, tokenId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserToken", ctx, tokenId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseUserToken indicates an expected call of UseUserToken.
+func (mr *MockAuthRepositoryMockRecorder) UseUserToken(ctx, tokenId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserToken", reflect.TypeOf((*MockAuthRepository)(nil).UseUserToken), ctx, tokenId)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuthRepository) VerifyEmail(ctx context.Context, tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, tokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthRepositoryMockRecorder) VerifyEmail(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuthRepository)(nil).VerifyEmail), ctx, tokenHash)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	models "kiramishima/ionix/internal/models"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,19 +41,147 @@ func (m *MockAuthService) EXPECT() *MockAuthServiceMockRecorder {
 	return m.recorder
 }
 
+// ConfirmMFA mocks base method.
+func (m *MockAuthService) ConfirmMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmMFA", ctx, userId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmMFA indicates an expected call of ConfirmMFA.
+func (mr *MockAuthServiceMockRecorder) ConfirmMFA(ctx, userId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFA", reflect.TypeOf((*MockAuthService)(nil).ConfirmMFA), ctx, userId, form)
+}
+
+// DisableMFA mocks base method.
+func (m *MockAuthService) DisableMFA(ctx context.Context, userId int, form *models.MFACodeForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableMFA", ctx, userId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableMFA indicates an expected call of DisableMFA.
+func (mr *MockAuthServiceMockRecorder) DisableMFA(ctx, userId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockAuthService)(nil).DisableMFA), ctx, userId, form)
+}
+
+// EnrollMFA mocks base method.
+func (m *MockAuthService) EnrollMFA(ctx context.Context, userId int) (*models.MFAEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollMFA", ctx, userId)
+	ret0, _ := ret[0].(*models.MFAEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollMFA indicates an expected call of EnrollMFA.
+func (mr *MockAuthServiceMockRecorder) EnrollMFA(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollMFA", reflect.TypeOf((*MockAuthService)(nil).EnrollMFA), ctx, userId)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockAuthService) ForgotPassword(ctx context.Context, form *models.EmailForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockAuthServiceMockRecorder) ForgotPassword(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockAuthService)(nil).ForgotPassword), ctx, form)
+}
+
+// Logout mocks base method.
+func (m *MockAuthService) Logout(ctx context.Context, userId int, jti string, expiresAt time.Time, form *models.RefreshTokenForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, userId, jti, expiresAt, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockAuthServiceMockRecorder) Logout(ctx, userId, jti, expiresAt, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockAuthService)(nil).Logout), ctx, userId, jti, expiresAt, form)
+}
+
+// Refresh mocks base method.
+func (m *MockAuthService) Refresh(ctx context.Context, form *models.RefreshTokenForm) (*models.AuthResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, form)
+	ret0, _ := ret[0].(*models.AuthResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockAuthServiceMockRecorder) Refresh(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockAuthService)(nil).Refresh), ctx, form)
+}
+
+// ResendVerification mocks base method.
+func (m *MockAuthService) ResendVerification(ctx context.Context, form *models.EmailForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockAuthServiceMockRecorder) ResendVerification(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockAuthService)(nil).ResendVerification), ctx, form)
+}
+
+// ResetMFA mocks base method.
+func (m *MockAuthService) ResetMFA(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetMFA", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetMFA indicates an expected call of ResetMFA.
+func (mr *MockAuthServiceMockRecorder) ResetMFA(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetMFA", reflect.TypeOf((*MockAuthService)(nil).ResetMFA), ctx, userId)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuthService) ResetPassword(ctx context.Context, form *models.ResetPasswordForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthServiceMockRecorder) ResetPassword(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuthService)(nil).ResetPassword), ctx, form)
+}
+
 // SignIn mocks base method.
-func (m *MockAuthService) SignIn(ctx context.Context, form *models.AuthForm) (*models.AuthResponse, error) {
+func (m *MockAuthService) SignIn(ctx context.Context, form *models.AuthForm, ip string) (*models.AuthResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignIn", ctx, form)
+	ret := m.ctrl.Call(m, "SignIn", ctx, form, ip)
 	ret0, _ := ret[0].(*models.AuthResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignIn indicates an expected call of SignIn.
-func (mr *MockAuthServiceMockRecorder) SignIn(ctx, form any) *gomock.Call {
+func (mr *MockAuthServiceMockRecorder) SignIn(ctx, form, ip any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuthService)(nil).SignIn), ctx, form)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuthService)(nil).SignIn), ctx, form, ip)
 }
 
 // SignUp mocks base method.
@@ -68,3 +197,74 @@ func (mr *MockAuthServiceMockRecorder) SignUp(ctx, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockAuthService)(nil).SignUp), ctx, form)
 }
+
+// Unlock mocks base method.
+func (m *MockAuthService) Unlock(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockAuthServiceMockRecorder) Unlock(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockAuthService)(nil).Unlock), ctx, userId)
+}
+
+// UpdateRole mocks base method.
+func (m *MockAuthService) UpdateRole(ctx context.Context, userId int, form *models.RoleForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, userId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockAuthServiceMockRecorder) UpdateRole(ctx, userId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockAuthService)(nil).UpdateRole), ctx, userId, form)
+}
+
+// UpdateRoleMFA mocks base method.
+func (m *MockAuthService) UpdateRoleMFA(ctx context.Context, role string, form *models.RoleMFAForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoleMFA", ctx, role, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRoleMFA indicates an expected call of UpdateRoleMFA.
+func (mr *MockAuthServiceMockRecorder) UpdateRoleMFA(ctx, role, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoleMFA", reflect.TypeOf((*MockAuthService)(nil).UpdateRoleMFA), ctx, role, form)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuthService) VerifyEmail(ctx context.Context, form *models.VerifyEmailForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthServiceMockRecorder) VerifyEmail(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuthService)(nil).VerifyEmail), ctx, form)
+}
+
+// VerifyMFA mocks base method.
+func (m *MockAuthService) VerifyMFA(ctx context.Context, form *models.MFAVerifyForm, ip string) (*models.AuthResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFA", ctx, form, ip)
+	ret0, _ := ret[0].(*models.AuthResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyMFA indicates an expected call of VerifyMFA.
+func (mr *MockAuthServiceMockRecorder) VerifyMFA(ctx, form, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFA", reflect.TypeOf((*MockAuthService)(nil).VerifyMFA), ctx, form, ip)
+}
@@ -55,17 +55,31 @@ func (mr *MockDrugRepositoryMockRecorder) CreateNewDrugItem(ctx, form any) *gomo
 }
 
 // DeleteDrugItem mocks base method.
-func (m *MockDrugRepository) DeleteDrugItem(ctx context.Context, drugId int) error {
+func (m *MockDrugRepository) DeleteDrugItem(ctx context.Context, drugId, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteDrugItem", ctx, drugId)
+	ret := m.ctrl.Call(m, "DeleteDrugItem", ctx, drugId, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteDrugItem indicates an expected call of DeleteDrugItem.
-func (mr *MockDrugRepositoryMockRecorder) DeleteDrugItem(ctx, drugId any) *gomock.Call {
+func (mr *MockDrugRepositoryMockRecorder) DeleteDrugItem(ctx, drugId, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDrugItem", reflect.TypeOf((*MockDrugRepository)(nil).DeleteDrugItem), ctx, drugId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDrugItem", reflect.TypeOf((*MockDrugRepository)(nil).DeleteDrugItem), ctx, drugId, version)
+}
+
+// ExportDrugItems mocks base method.
+func (m *MockDrugRepository) ExportDrugItems(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportDrugItems", ctx, query, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportDrugItems indicates an expected call of ExportDrugItems.
+func (mr *MockDrugRepositoryMockRecorder) ExportDrugItems(ctx, query, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportDrugItems", reflect.TypeOf((*MockDrugRepository)(nil).ExportDrugItems), ctx, query, fn)
 }
 
 // GetDrugItemByID mocks base method.
@@ -83,31 +97,76 @@ func (mr *MockDrugRepositoryMockRecorder) GetDrugItemByID(ctx, drugId any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrugItemByID", reflect.TypeOf((*MockDrugRepository)(nil).GetDrugItemByID), ctx, drugId)
 }
 
+// GetDrugScheduleByID mocks base method.
+func (m *MockDrugRepository) GetDrugScheduleByID(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDrugScheduleByID", ctx, drugId)
+	ret0, _ := ret[0].(*models.DrugSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDrugScheduleByID indicates an expected call of GetDrugScheduleByID.
+func (mr *MockDrugRepositoryMockRecorder) GetDrugScheduleByID(ctx, drugId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrugScheduleByID", reflect.TypeOf((*MockDrugRepository)(nil).GetDrugScheduleByID), ctx, drugId)
+}
+
 // GetDrugsData mocks base method.
-func (m *MockDrugRepository) GetDrugsData(ctx context.Context) ([]*models.Drug, error) {
+func (m *MockDrugRepository) GetDrugsData(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDrugsData", ctx)
+	ret := m.ctrl.Call(m, "GetDrugsData", ctx, query)
 	ret0, _ := ret[0].([]*models.Drug)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetDrugsData indicates an expected call of GetDrugsData.
+func (mr *MockDrugRepositoryMockRecorder) GetDrugsData(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrugsData", reflect.TypeOf((*MockDrugRepository)(nil).GetDrugsData), ctx, query)
+}
+
+// ImportDrugItems mocks base method.
+func (m *MockDrugRepository) ImportDrugItems(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportDrugItems", ctx, forms, opts)
+	ret0, _ := ret[0].([]error)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetDrugsData indicates an expected call of GetDrugsData.
-func (mr *MockDrugRepositoryMockRecorder) GetDrugsData(ctx any) *gomock.Call {
+// ImportDrugItems indicates an expected call of ImportDrugItems.
+func (mr *MockDrugRepositoryMockRecorder) ImportDrugItems(ctx, forms, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportDrugItems", reflect.TypeOf((*MockDrugRepository)(nil).ImportDrugItems), ctx, forms, opts)
+}
+
+// SaveDrugSchedule mocks base method.
+func (m *MockDrugRepository) SaveDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDrugSchedule", ctx, drugId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDrugSchedule indicates an expected call of SaveDrugSchedule.
+func (mr *MockDrugRepositoryMockRecorder) SaveDrugSchedule(ctx, drugId, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrugsData", reflect.TypeOf((*MockDrugRepository)(nil).GetDrugsData), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDrugSchedule", reflect.TypeOf((*MockDrugRepository)(nil).SaveDrugSchedule), ctx, drugId, form)
 }
 
 // UpdateDrugItem mocks base method.
-func (m *MockDrugRepository) UpdateDrugItem(ctx context.Context, drugId int, form *models.Drug) error {
+func (m *MockDrugRepository) UpdateDrugItem(ctx context.Context, drugId, version int, form *models.Drug) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateDrugItem", ctx, drugId, form)
+	ret := m.ctrl.Call(m, "UpdateDrugItem", ctx, drugId, version, form)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateDrugItem indicates an expected call of UpdateDrugItem.
-func (mr *MockDrugRepositoryMockRecorder) UpdateDrugItem(ctx, drugId, form any) *gomock.Call {
+func (mr *MockDrugRepositoryMockRecorder) UpdateDrugItem(ctx, drugId, version, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDrugItem", reflect.TypeOf((*MockDrugRepository)(nil).UpdateDrugItem), ctx, drugId, form)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDrugItem", reflect.TypeOf((*MockDrugRepository)(nil).UpdateDrugItem), ctx, drugId, version, form)
 }
@@ -41,32 +41,92 @@ func (m *MockDrugService) EXPECT() *MockDrugServiceMockRecorder {
 }
 
 // DeleteDrug mocks base method.
-func (m *MockDrugService) DeleteDrug(ctx context.Context, drugId int) error {
+func (m *MockDrugService) DeleteDrug(ctx context.Context, drugId, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteDrug", ctx, drugId)
+	ret := m.ctrl.Call(m, "DeleteDrug", ctx, drugId, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteDrug indicates an expected call of DeleteDrug.
-func (mr *MockDrugServiceMockRecorder) DeleteDrug(ctx, drugId any) *gomock.Call {
+func (mr *MockDrugServiceMockRecorder) DeleteDrug(ctx, drugId, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDrug", reflect.TypeOf((*MockDrugService)(nil).DeleteDrug), ctx, drugId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDrug", reflect.TypeOf((*MockDrugService)(nil).DeleteDrug), ctx, drugId, version)
+}
+
+// ExportDrugs mocks base method.
+func (m *MockDrugService) ExportDrugs(ctx context.Context, query *models.DrugQuery, fn func(*models.Drug) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportDrugs", ctx, query, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportDrugs indicates an expected call of ExportDrugs.
+func (mr *MockDrugServiceMockRecorder) ExportDrugs(ctx, query, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportDrugs", reflect.TypeOf((*MockDrugService)(nil).ExportDrugs), ctx, query, fn)
+}
+
+// GetDrug mocks base method.
+func (m *MockDrugService) GetDrug(ctx context.Context, drugId int) (*models.Drug, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDrug", ctx, drugId)
+	ret0, _ := ret[0].(*models.Drug)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDrug indicates an expected call of GetDrug.
+func (mr *MockDrugServiceMockRecorder) GetDrug(ctx, drugId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrug", reflect.TypeOf((*MockDrugService)(nil).GetDrug), ctx, drugId)
+}
+
+// GetDrugSchedule mocks base method.
+func (m *MockDrugService) GetDrugSchedule(ctx context.Context, drugId int) (*models.DrugSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDrugSchedule", ctx, drugId)
+	ret0, _ := ret[0].(*models.DrugSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDrugSchedule indicates an expected call of GetDrugSchedule.
+func (mr *MockDrugServiceMockRecorder) GetDrugSchedule(ctx, drugId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrugSchedule", reflect.TypeOf((*MockDrugService)(nil).GetDrugSchedule), ctx, drugId)
 }
 
 // GetListDrugs mocks base method.
-func (m *MockDrugService) GetListDrugs(ctx context.Context) ([]*models.Drug, error) {
+func (m *MockDrugService) GetListDrugs(ctx context.Context, query *models.DrugQuery) ([]*models.Drug, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetListDrugs", ctx)
+	ret := m.ctrl.Call(m, "GetListDrugs", ctx, query)
 	ret0, _ := ret[0].([]*models.Drug)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetListDrugs indicates an expected call of GetListDrugs.
+func (mr *MockDrugServiceMockRecorder) GetListDrugs(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListDrugs", reflect.TypeOf((*MockDrugService)(nil).GetListDrugs), ctx, query)
+}
+
+// ImportDrugs mocks base method.
+func (m *MockDrugService) ImportDrugs(ctx context.Context, forms []*models.DrugForm, opts models.ImportOptions) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportDrugs", ctx, forms, opts)
+	ret0, _ := ret[0].([]error)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetListDrugs indicates an expected call of GetListDrugs.
-func (mr *MockDrugServiceMockRecorder) GetListDrugs(ctx any) *gomock.Call {
+// ImportDrugs indicates an expected call of ImportDrugs.
+func (mr *MockDrugServiceMockRecorder) ImportDrugs(ctx, forms, opts any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListDrugs", reflect.TypeOf((*MockDrugService)(nil).GetListDrugs), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportDrugs", reflect.TypeOf((*MockDrugService)(nil).ImportDrugs), ctx, forms, opts)
 }
 
 // NewDrug mocks base method.
@@ -83,16 +143,30 @@ func (mr *MockDrugServiceMockRecorder) NewDrug(ctx, form any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewDrug", reflect.TypeOf((*MockDrugService)(nil).NewDrug), ctx, form)
 }
 
+// SetDrugSchedule mocks base method.
+func (m *MockDrugService) SetDrugSchedule(ctx context.Context, drugId int, form *models.DrugScheduleForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDrugSchedule", ctx, drugId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDrugSchedule indicates an expected call of SetDrugSchedule.
+func (mr *MockDrugServiceMockRecorder) SetDrugSchedule(ctx, drugId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDrugSchedule", reflect.TypeOf((*MockDrugService)(nil).SetDrugSchedule), ctx, drugId, form)
+}
+
 // UpdateDrug mocks base method.
-func (m *MockDrugService) UpdateDrug(ctx context.Context, drugId int, form *models.DrugForm) error {
+func (m *MockDrugService) UpdateDrug(ctx context.Context, drugId, version int, form *models.DrugForm) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateDrug", ctx, drugId, form)
+	ret := m.ctrl.Call(m, "UpdateDrug", ctx, drugId, version, form)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateDrug indicates an expected call of UpdateDrug.
-func (mr *MockDrugServiceMockRecorder) UpdateDrug(ctx, drugId, form any) *gomock.Call {
+func (mr *MockDrugServiceMockRecorder) UpdateDrug(ctx, drugId, version, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDrug", reflect.TypeOf((*MockDrugService)(nil).UpdateDrug), ctx, drugId, form)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDrug", reflect.TypeOf((*MockDrugService)(nil).UpdateDrug), ctx, drugId, version, form)
 }
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: .\internal\interfaces\health_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source .\internal\interfaces\health_service.go -destination .\internal\mocks\health_service.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "kiramishima/ionix/internal/models"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockHealthService is a mock of HealthService interface.
+type MockHealthService struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthServiceMockRecorder
+}
+
+// MockHealthServiceMockRecorder is the mock recorder for MockHealthService.
+type MockHealthServiceMockRecorder struct {
+	mock *MockHealthService
+}
+
+// NewMockHealthService creates a new mock instance.
+func NewMockHealthService(ctrl *gomock.Controller) *MockHealthService {
+	mock := &MockHealthService{ctrl: ctrl}
+	mock.recorder = &MockHealthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHealthService) EXPECT() *MockHealthServiceMockRecorder {
+	return m.recorder
+}
+
+// Ready mocks base method.
+func (m *MockHealthService) Ready(ctx context.Context) *models.Readiness {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ready", ctx)
+	ret0, _ := ret[0].(*models.Readiness)
+	return ret0
+}
+
+// Ready indicates an expected call of Ready.
+func (mr *MockHealthServiceMockRecorder) Ready(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ready", reflect.TypeOf((*MockHealthService)(nil).Ready), ctx)
+}
+
+// ShuttingDown mocks base method.
+func (m *MockHealthService) ShuttingDown() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ShuttingDown")
+}
+
+// ShuttingDown indicates an expected call of ShuttingDown.
+func (mr *MockHealthServiceMockRecorder) ShuttingDown() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShuttingDown", reflect.TypeOf((*MockHealthService)(nil).ShuttingDown))
+}
+
+// Status mocks base method.
+func (m *MockHealthService) Status(ctx context.Context) *models.ServiceStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", ctx)
+	ret0, _ := ret[0].(*models.ServiceStatus)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockHealthServiceMockRecorder) Status(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockHealthService)(nil).Status), ctx)
+}
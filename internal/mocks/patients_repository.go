@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: .\internal\interfaces\patients_repository.go
+//
+// Generated by this command:
+//
+//	mockgen -source .\internal\interfaces\patients_repository.go -destination .\internal\mocks\patients_repository.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "kiramishima/ionix/internal/models"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPatientRepository is a mock of PatientRepository interface.
+type MockPatientRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPatientRepositoryMockRecorder
+}
+
+// MockPatientRepositoryMockRecorder is the mock recorder for MockPatientRepository.
+type MockPatientRepositoryMockRecorder struct {
+	mock *MockPatientRepository
+}
+
+// NewMockPatientRepository creates a new mock instance.
+func NewMockPatientRepository(ctrl *gomock.Controller) *MockPatientRepository {
+	mock := &MockPatientRepository{ctrl: ctrl}
+	mock.recorder = &MockPatientRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPatientRepository) EXPECT() *MockPatientRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateNewPatientItem mocks base method.
+func (m *MockPatientRepository) CreateNewPatientItem(ctx context.Context, form *models.PatientForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNewPatientItem", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateNewPatientItem indicates an expected call of CreateNewPatientItem.
+func (mr *MockPatientRepositoryMockRecorder) CreateNewPatientItem(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNewPatientItem", reflect.TypeOf((*MockPatientRepository)(nil).CreateNewPatientItem), ctx, form)
+}
+
+// DeletePatientItem mocks base method.
+func (m *MockPatientRepository) DeletePatientItem(ctx context.Context, patientId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePatientItem", ctx, patientId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePatientItem indicates an expected call of DeletePatientItem.
+func (mr *MockPatientRepositoryMockRecorder) DeletePatientItem(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePatientItem", reflect.TypeOf((*MockPatientRepository)(nil).DeletePatientItem), ctx, patientId)
+}
+
+// GetPatientItemByID mocks base method.
+func (m *MockPatientRepository) GetPatientItemByID(ctx context.Context, patientId int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPatientItemByID", ctx, patientId)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPatientItemByID indicates an expected call of GetPatientItemByID.
+func (mr *MockPatientRepositoryMockRecorder) GetPatientItemByID(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPatientItemByID", reflect.TypeOf((*MockPatientRepository)(nil).GetPatientItemByID), ctx, patientId)
+}
+
+// GetPatientSeries mocks base method.
+func (m *MockPatientRepository) GetPatientSeries(ctx context.Context, patientId int) ([]*models.PatientSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPatientSeries", ctx, patientId)
+	ret0, _ := ret[0].([]*models.PatientSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPatientSeries indicates an expected call of GetPatientSeries.
+func (mr *MockPatientRepositoryMockRecorder) GetPatientSeries(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPatientSeries", reflect.TypeOf((*MockPatientRepository)(nil).GetPatientSeries), ctx, patientId)
+}
+
+// GetPatientsData mocks base method.
+func (m *MockPatientRepository) GetPatientsData(ctx context.Context, query *models.PatientQuery) ([]*models.Patient, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPatientsData", ctx, query)
+	ret0, _ := ret[0].([]*models.Patient)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPatientsData indicates an expected call of GetPatientsData.
+func (mr *MockPatientRepositoryMockRecorder) GetPatientsData(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPatientsData", reflect.TypeOf((*MockPatientRepository)(nil).GetPatientsData), ctx, query)
+}
+
+// UpdatePatientItem mocks base method.
+func (m *MockPatientRepository) UpdatePatientItem(ctx context.Context, patientId int, form *models.Patient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePatientItem", ctx, patientId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePatientItem indicates an expected call of UpdatePatientItem.
+func (mr *MockPatientRepositoryMockRecorder) UpdatePatientItem(ctx, patientId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePatientItem", reflect.TypeOf((*MockPatientRepository)(nil).UpdatePatientItem), ctx, patientId, form)
+}
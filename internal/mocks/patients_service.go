@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: .\internal\interfaces\patients_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source .\internal\interfaces\patients_service.go -destination .\internal\mocks\patients_service.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	models "kiramishima/ionix/internal/models"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPatientService is a mock of PatientService interface.
+type MockPatientService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPatientServiceMockRecorder
+}
+
+// MockPatientServiceMockRecorder is the mock recorder for MockPatientService.
+type MockPatientServiceMockRecorder struct {
+	mock *MockPatientService
+}
+
+// NewMockPatientService creates a new mock instance.
+func NewMockPatientService(ctrl *gomock.Controller) *MockPatientService {
+	mock := &MockPatientService{ctrl: ctrl}
+	mock.recorder = &MockPatientServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPatientService) EXPECT() *MockPatientServiceMockRecorder {
+	return m.recorder
+}
+
+// DeletePatient mocks base method.
+func (m *MockPatientService) DeletePatient(ctx context.Context, patientId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePatient", ctx, patientId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePatient indicates an expected call of DeletePatient.
+func (mr *MockPatientServiceMockRecorder) DeletePatient(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePatient", reflect.TypeOf((*MockPatientService)(nil).DeletePatient), ctx, patientId)
+}
+
+// GetListPatients mocks base method.
+func (m *MockPatientService) GetListPatients(ctx context.Context, query *models.PatientQuery) ([]*models.Patient, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListPatients", ctx, query)
+	ret0, _ := ret[0].([]*models.Patient)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetListPatients indicates an expected call of GetListPatients.
+func (mr *MockPatientServiceMockRecorder) GetListPatients(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListPatients", reflect.TypeOf((*MockPatientService)(nil).GetListPatients), ctx, query)
+}
+
+// GetPatient mocks base method.
+func (m *MockPatientService) GetPatient(ctx context.Context, patientId int) (*models.Patient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPatient", ctx, patientId)
+	ret0, _ := ret[0].(*models.Patient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPatient indicates an expected call of GetPatient.
+func (mr *MockPatientServiceMockRecorder) GetPatient(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPatient", reflect.TypeOf((*MockPatientService)(nil).GetPatient), ctx, patientId)
+}
+
+// GetPatientSchedule mocks base method.
+func (m *MockPatientService) GetPatientSchedule(ctx context.Context, patientId int) ([]*models.SeriesProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPatientSchedule", ctx, patientId)
+	ret0, _ := ret[0].([]*models.SeriesProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPatientSchedule indicates an expected call of GetPatientSchedule.
+func (mr *MockPatientServiceMockRecorder) GetPatientSchedule(ctx, patientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPatientSchedule", reflect.TypeOf((*MockPatientService)(nil).GetPatientSchedule), ctx, patientId)
+}
+
+// NewPatient mocks base method.
+func (m *MockPatientService) NewPatient(ctx context.Context, form *models.PatientForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewPatient", ctx, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NewPatient indicates an expected call of NewPatient.
+func (mr *MockPatientServiceMockRecorder) NewPatient(ctx, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPatient", reflect.TypeOf((*MockPatientService)(nil).NewPatient), ctx, form)
+}
+
+// UpdatePatient mocks base method.
+func (m *MockPatientService) UpdatePatient(ctx context.Context, patientId int, form *models.PatientForm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePatient", ctx, patientId, form)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePatient indicates an expected call of UpdatePatient.
+func (mr *MockPatientServiceMockRecorder) UpdatePatient(ctx, patientId, form any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePatient", reflect.TypeOf((*MockPatientService)(nil).UpdatePatient), ctx, patientId, form)
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: .\internal\interfaces\token_denylist.go
+//
+// Generated by this command:
+//
+//	mockgen -source .\internal\interfaces\token_denylist.go -destination .\internal\mocks\token_denylist.go -package mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenDenylist is a mock of TokenDenylist interface.
+type MockTokenDenylist struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenDenylistMockRecorder
+}
+
+// MockTokenDenylistMockRecorder is the mock recorder for MockTokenDenylist.
+type MockTokenDenylistMockRecorder struct {
+	mock *MockTokenDenylist
+}
+
+// NewMockTokenDenylist creates a new mock instance.
+func NewMockTokenDenylist(ctrl *gomock.Controller) *MockTokenDenylist {
+	mock := &MockTokenDenylist{ctrl: ctrl}
+	mock.recorder = &MockTokenDenylistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenDenylist) EXPECT() *MockTokenDenylistMockRecorder {
+	return m.recorder
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockTokenDenylist) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockTokenDenylistMockRecorder) IsTokenRevoked(ctx, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockTokenDenylist)(nil).IsTokenRevoked), ctx, jti)
+}
@@ -55,17 +55,31 @@ func (mr *MockVaccinationRepositoryMockRecorder) CreateNewVaccinationItem(ctx, f
 }
 
 // DeleteVaccinationItem mocks base method.
-func (m *MockVaccinationRepository) DeleteVaccinationItem(ctx context.Context, vaccinationId int) error {
+func (m *MockVaccinationRepository) DeleteVaccinationItem(ctx context.Context, vaccinationId, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteVaccinationItem", ctx, vaccinationId)
+	ret := m.ctrl.Call(m, "DeleteVaccinationItem", ctx, vaccinationId, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteVaccinationItem indicates an expected call of DeleteVaccinationItem.
-func (mr *MockVaccinationRepositoryMockRecorder) DeleteVaccinationItem(ctx, vaccinationId any) *gomock.Call {
+func (mr *MockVaccinationRepositoryMockRecorder) DeleteVaccinationItem(ctx, vaccinationId, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVaccinationItem", reflect.TypeOf((*MockVaccinationRepository)(nil).DeleteVaccinationItem), ctx, vaccinationId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVaccinationItem", reflect.TypeOf((*MockVaccinationRepository)(nil).DeleteVaccinationItem), ctx, vaccinationId, version)
+}
+
+// ExportVaccinationItems mocks base method.
+func (m *MockVaccinationRepository) ExportVaccinationItems(ctx context.Context, fn func(*models.Vaccination) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportVaccinationItems", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportVaccinationItems indicates an expected call of ExportVaccinationItems.
+func (mr *MockVaccinationRepositoryMockRecorder) ExportVaccinationItems(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportVaccinationItems", reflect.TypeOf((*MockVaccinationRepository)(nil).ExportVaccinationItems), ctx, fn)
 }
 
 // GetVaccinationItemByID mocks base method.
@@ -98,16 +112,31 @@ func (mr *MockVaccinationRepositoryMockRecorder) GetVaccinationsData(ctx any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVaccinationsData", reflect.TypeOf((*MockVaccinationRepository)(nil).GetVaccinationsData), ctx)
 }
 
+// ImportVaccinationItems mocks base method.
+func (m *MockVaccinationRepository) ImportVaccinationItems(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportVaccinationItems", ctx, forms, opts)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportVaccinationItems indicates an expected call of ImportVaccinationItems.
+func (mr *MockVaccinationRepositoryMockRecorder) ImportVaccinationItems(ctx, forms, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportVaccinationItems", reflect.TypeOf((*MockVaccinationRepository)(nil).ImportVaccinationItems), ctx, forms, opts)
+}
+
 // UpdateVaccinationItem mocks base method.
-func (m *MockVaccinationRepository) UpdateVaccinationItem(ctx context.Context, vaccinationId int, form *models.Vaccination) error {
+func (m *MockVaccinationRepository) UpdateVaccinationItem(ctx context.Context, vaccinationId, version int, form *models.Vaccination) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateVaccinationItem", ctx, vaccinationId, form)
+	ret := m.ctrl.Call(m, "UpdateVaccinationItem", ctx, vaccinationId, version, form)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateVaccinationItem indicates an expected call of UpdateVaccinationItem.
-func (mr *MockVaccinationRepositoryMockRecorder) UpdateVaccinationItem(ctx, vaccinationId, form any) *gomock.Call {
+func (mr *MockVaccinationRepositoryMockRecorder) UpdateVaccinationItem(ctx, vaccinationId, version, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVaccinationItem", reflect.TypeOf((*MockVaccinationRepository)(nil).UpdateVaccinationItem), ctx, vaccinationId, form)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVaccinationItem", reflect.TypeOf((*MockVaccinationRepository)(nil).UpdateVaccinationItem), ctx, vaccinationId, version, form)
 }
@@ -41,17 +41,31 @@ func (m *MockVaccinationService) EXPECT() *MockVaccinationServiceMockRecorder {
 }
 
 // DeleteVaccination mocks base method.
-func (m *MockVaccinationService) DeleteVaccination(ctx context.Context, vaccinationId int) error {
+func (m *MockVaccinationService) DeleteVaccination(ctx context.Context, vaccinationId, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteVaccination", ctx, vaccinationId)
+	ret := m.ctrl.Call(m, "DeleteVaccination", ctx, vaccinationId, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteVaccination indicates an expected call of DeleteVaccination.
-func (mr *MockVaccinationServiceMockRecorder) DeleteVaccination(ctx, vaccinationId any) *gomock.Call {
+func (mr *MockVaccinationServiceMockRecorder) DeleteVaccination(ctx, vaccinationId, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVaccination", reflect.TypeOf((*MockVaccinationService)(nil).DeleteVaccination), ctx, vaccinationId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVaccination", reflect.TypeOf((*MockVaccinationService)(nil).DeleteVaccination), ctx, vaccinationId, version)
+}
+
+// ExportVaccinations mocks base method.
+func (m *MockVaccinationService) ExportVaccinations(ctx context.Context, fn func(*models.Vaccination) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportVaccinations", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportVaccinations indicates an expected call of ExportVaccinations.
+func (mr *MockVaccinationServiceMockRecorder) ExportVaccinations(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportVaccinations", reflect.TypeOf((*MockVaccinationService)(nil).ExportVaccinations), ctx, fn)
 }
 
 // GetListVaccinations mocks base method.
@@ -69,6 +83,36 @@ func (mr *MockVaccinationServiceMockRecorder) GetListVaccinations(ctx any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListVaccinations", reflect.TypeOf((*MockVaccinationService)(nil).GetListVaccinations), ctx)
 }
 
+// GetVaccination mocks base method.
+func (m *MockVaccinationService) GetVaccination(ctx context.Context, vaccinationId int) (*models.Vaccination, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVaccination", ctx, vaccinationId)
+	ret0, _ := ret[0].(*models.Vaccination)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVaccination indicates an expected call of GetVaccination.
+func (mr *MockVaccinationServiceMockRecorder) GetVaccination(ctx, vaccinationId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVaccination", reflect.TypeOf((*MockVaccinationService)(nil).GetVaccination), ctx, vaccinationId)
+}
+
+// ImportVaccinations mocks base method.
+func (m *MockVaccinationService) ImportVaccinations(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportVaccinations", ctx, forms, opts)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportVaccinations indicates an expected call of ImportVaccinations.
+func (mr *MockVaccinationServiceMockRecorder) ImportVaccinations(ctx, forms, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportVaccinations", reflect.TypeOf((*MockVaccinationService)(nil).ImportVaccinations), ctx, forms, opts)
+}
+
 // NewVaccination mocks base method.
 func (m *MockVaccinationService) NewVaccination(ctx context.Context, form *models.VaccinationForm) error {
 	m.ctrl.T.Helper()
@@ -84,15 +128,15 @@ func (mr *MockVaccinationServiceMockRecorder) NewVaccination(ctx, form any) *gom
 }
 
 // UpdateVaccination mocks base method.
-func (m *MockVaccinationService) UpdateVaccination(ctx context.Context, vaccinationId int, form *models.VaccinationForm) error {
+func (m *MockVaccinationService) UpdateVaccination(ctx context.Context, vaccinationId, version int, form *models.VaccinationForm) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateVaccination", ctx, vaccinationId, form)
+	ret := m.ctrl.Call(m, "UpdateVaccination", ctx, vaccinationId, version, form)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateVaccination indicates an expected call of UpdateVaccination.
-func (mr *MockVaccinationServiceMockRecorder) UpdateVaccination(ctx, vaccinationId, form any) *gomock.Call {
+func (mr *MockVaccinationServiceMockRecorder) UpdateVaccination(ctx, vaccinationId, version, form any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVaccination", reflect.TypeOf((*MockVaccinationService)(nil).UpdateVaccination), ctx, vaccinationId, form)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVaccination", reflect.TypeOf((*MockVaccinationService)(nil).UpdateVaccination), ctx, vaccinationId, version, form)
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry a mutation recorded in the audit log
+type AuditEntry struct {
+	ID        int64           `json:"id" db:"id"`
+	ActorID   *int64          `json:"actor_id" db:"actor_id"`
+	Action    string          `json:"action" db:"action"`
+	Entity    string          `json:"entity" db:"entity"`
+	EntityID  *string         `json:"entity_id" db:"entity_id"`
+	Changes   json.RawMessage `json:"changes" db:"changes"`
+	RequestID *string         `json:"request_id" db:"request_id"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// In renders the dates of the entry in loc
+func (e *AuditEntry) In(loc *time.Location) *AuditEntry {
+	e.CreatedAt = e.CreatedAt.In(loc)
+	return e
+}
+
+// AuditChanges before and after values of the changed fields
+type AuditChanges struct {
+	Before map[string]any `json:"before"`
+	After  map[string]any `json:"after"`
+}
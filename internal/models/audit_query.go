@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditQuery filters and pagination for the audit log
+type AuditQuery struct {
+	Pagination
+	Entity   *string
+	EntityID *string
+	Action   *string
+	ActorID  *int
+	From     *time.Time
+	To       *time.Time
+}
+
+// NewAuditQuery creates an audit query with the default pagination
+func NewAuditQuery() *AuditQuery {
+	return &AuditQuery{Pagination: NewPagination()}
+}
+
+// ParseAuditQuery reads the audit query from the url values
+func ParseAuditQuery(values url.Values) (*AuditQuery, error) {
+	var q = NewAuditQuery()
+	var err error
+
+	if q.Pagination, err = ParsePagination(values); err != nil {
+		return nil, err
+	}
+	if v := strings.TrimSpace(values.Get("entity")); v != "" {
+		q.Entity = &v
+	}
+	if v := strings.TrimSpace(values.Get("entity_id")); v != "" {
+		q.EntityID = &v
+	}
+	if v := strings.TrimSpace(values.Get("action")); v != "" {
+		q.Action = &v
+	}
+	if v := values.Get("actor_id"); v != "" {
+		actor, err := strconv.Atoi(v)
+		if err != nil || actor < 1 {
+			return nil, fmt.Errorf("%w: actor_id", ErrInvalidQuery)
+		}
+		q.ActorID = &actor
+	}
+	if q.From, err = parseQueryTime(values, "from"); err != nil {
+		return nil, err
+	}
+	if q.To, err = parseQueryTime(values, "to"); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Where returns the WHERE clause and its arguments
+func (q *AuditQuery) Where() (string, []any) {
+	var conditions = []string{"1 = 1"}
+	var args = make([]any, 0)
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Entity != nil {
+		add("entity = $%d", *q.Entity)
+	}
+	if q.EntityID != nil {
+		add("entity_id = $%d", *q.EntityID)
+	}
+	if q.Action != nil {
+		add("action = $%d", *q.Action)
+	}
+	if q.ActorID != nil {
+		add("actor_id = $%d", *q.ActorID)
+	}
+	if q.From != nil {
+		add("created_at >= $%d", *q.From)
+	}
+	if q.To != nil {
+		add("created_at <= $%d", *q.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
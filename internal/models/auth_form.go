@@ -2,8 +2,6 @@ package models
 
 import (
 	"encoding/hex"
-	"errors"
-	"fmt"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/sha3"
@@ -25,7 +23,7 @@ func (u *AuthForm) BcryptPassword(password string) (string, error) {
 	buf := []byte(password)
 	hash, err := bcrypt.GenerateFromPassword(buf, bcrypt.DefaultCost)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	return string(hash), nil
 }
@@ -41,19 +39,5 @@ func (u *AuthForm) ValidateBcryptPassword(password, password2 string) bool {
 }
 
 func (u *AuthForm) Validate(v *validator.Validate) error {
-	err := v.Struct(u)
-	if err != nil {
-
-		var ve validator.ValidationErrors
-
-		if errors.As(err, &ve) {
-			var out error
-			err = err.(validator.ValidationErrors)
-			for _, fe := range ve {
-				out = errors.New(fmt.Sprintf("%s. %s", fe.StructField(), msgForTag(fe.Tag())))
-			}
-			return out
-		}
-	}
-	return nil
+	return NewValidationErrors(v.Struct(u)).Err()
 }
@@ -1,6 +1,11 @@
 package models
 
-// AuthResponse struct
+// AuthResponse struct, the sign in of the users with a second factor only has the MFA token, and the
+// enrollment when the role requires a second factor that the user did not add yet
 type AuthResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken   string         `json:"access_token,omitempty"`
+	RefreshToken  string         `json:"refresh_token,omitempty"`
+	ExpiresIn     int            `json:"expires_in,omitempty"`
+	MFAToken      string         `json:"mfa_token,omitempty"`
+	MFAEnrollment *MFAEnrollment `json:"mfa_enrollment,omitempty"`
 }
@@ -4,5 +4,31 @@ package models
 type Configuration struct {
 	HTTPServer
 	Database
-	ContextTimeout int `envconfig:"CONTEXT_TIMEOUT" default:"2"`
+	Tracing
+	Mailer
+	Password
+	MFA
+	JWT
+	ContextTimeout  int  `envconfig:"CONTEXT_TIMEOUT" default:"2"`
+	RefreshTokenTTL int  `envconfig:"REFRESH_TOKEN_TTL" default:"604800"`
+	MigrateOnStart  bool `envconfig:"MIGRATE_ON_START" default:"false"`
+	// ImportTimeout seconds that an import may take instead of CONTEXT_TIMEOUT, the rows of a file are
+	// inserted in one transaction
+	ImportTimeout int `envconfig:"IMPORT_TIMEOUT" default:"300"`
+	// PasswordResetTTL and EmailVerificationTTL seconds until the tokens sent by mail expire
+	PasswordResetTTL     int `envconfig:"PASSWORD_RESET_TTL" default:"3600"`
+	EmailVerificationTTL int `envconfig:"EMAIL_VERIFICATION_TTL" default:"86400"`
+	// RequireEmailVerification rejects the sign in of the accounts that did not verify their email
+	RequireEmailVerification bool `envconfig:"REQUIRE_EMAIL_VERIFICATION" default:"false"`
+	// AppURL base of the links of the mails, the pages that read the token and call the API
+	AppURL string `envconfig:"APP_URL" default:"http://localhost:8080"`
+	// SignInMaxFailures failed sign ins of an email until it is locked, SignInMaxIPFailures of an IP, 0 disables them
+	SignInMaxFailures   int `envconfig:"SIGN_IN_MAX_FAILURES" default:"5"`
+	SignInMaxIPFailures int `envconfig:"SIGN_IN_MAX_IP_FAILURES" default:"50"`
+	// SignInFailureDelay seconds to wait after the first failed sign in of an email, it doubles with every failure
+	SignInFailureDelay int `envconfig:"SIGN_IN_FAILURE_DELAY" default:"1"`
+	// SignInLockout seconds that an email or IP stays locked, the failures older than it are forgotten
+	SignInLockout int `envconfig:"SIGN_IN_LOCKOUT" default:"900"`
+	// TimeZone IANA time zone of the dates of the responses, the Time-Zone header overrides it
+	TimeZone string `envconfig:"TIME_ZONE" default:"UTC"`
 }
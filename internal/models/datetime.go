@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LegacyDateTimeLayout layout of the dates before RFC 3339, without offset it is read as UTC
+const LegacyDateTimeLayout = "2006-01-02 15:04:05"
+
+// DateTime date-time of the forms, decodes RFC 3339 and the legacy layout and is encoded as RFC 3339
+type DateTime struct {
+	time.Time
+}
+
+// ParseDateTime reads an RFC 3339 date-time or one in the legacy layout
+func ParseDateTime(value string) (time.Time, error) {
+	if tm, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return tm, nil
+	}
+	tm, err := time.ParseInLocation(LegacyDateTimeLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date-time %q, use RFC 3339", value)
+	}
+	return tm, nil
+}
+
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Time.Format(time.RFC3339))
+}
+
+func (d *DateTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid date-time %s, use RFC 3339", data)
+	}
+	tm, err := ParseDateTime(value)
+	if err != nil {
+		return err
+	}
+	d.Time = tm
+	return nil
+}
+
+// Value stores the instant, the columns are TIMESTAMPTZ
+func (d DateTime) Value() (driver.Value, error) {
+	return d.Time, nil
+}
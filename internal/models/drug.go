@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
 
 type Drug struct {
 	ID          int32     `json:"id"`
@@ -9,4 +13,45 @@ type Drug struct {
 	MinDose     int       `json:"min_dose"`
 	MaxDose     int       `json:"max_dose"`
 	AvailableAt time.Time `json:"available_at"`
+	// Version incremented by every update, the ETag of the drug
+	Version int `json:"-"`
+}
+
+// In renders the dates of the drug in loc
+func (d *Drug) In(loc *time.Location) *Drug {
+	d.AvailableAt = d.AvailableAt.In(loc)
+	return d
+}
+
+// ValidateApplication checks a vaccination dose and date against the drug definition
+func (d *Drug) ValidateApplication(dose int, appliedAt time.Time) error {
+	var errs = make(ValidationErrors, 0)
+
+	if !d.Approved {
+		errs = append(errs, NewFieldError("drug_id", "drug_not_approved", "The drug is not approved"))
+	}
+	if dose < d.MinDose || dose > d.MaxDose {
+		errs = append(errs, NewFieldError("dose", "dose_range", fmt.Sprintf("The dose must be between %d and %d", d.MinDose, d.MaxDose), strconv.Itoa(d.MinDose), strconv.Itoa(d.MaxDose)))
+	}
+	if appliedAt.Before(d.AvailableAt) {
+		var availableAt = d.AvailableAt.Format(time.RFC3339)
+		errs = append(errs, NewFieldError("applied_at", "drug_not_available", fmt.Sprintf("The drug is not available before %s", availableAt), availableAt))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Form the drug as the body of a PUT, the document patched by PATCH
+func (d *Drug) Form() *DrugForm {
+	var name, approved, minDose, maxDose = d.Name, d.Approved, d.MinDose, d.MaxDose
+	return &DrugForm{
+		Name:        &name,
+		Approved:    &approved,
+		MinDose:     &minDose,
+		MaxDose:     &maxDose,
+		AvailableAt: &DateTime{Time: d.AvailableAt},
+	}
 }
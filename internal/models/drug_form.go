@@ -1,33 +1,21 @@
 package models
 
 import (
-	"errors"
-	"fmt"
 	"github.com/go-playground/validator/v10"
 )
 
 type DrugForm struct {
-	Name        *string `json:"name" db:"name" validate:"required"`
-	Approved    *bool   `json:"approved" db:"approved" validate:"required"`
-	MinDose     *int    `json:"min_dose" db:"min_dose" validate:"required"`
-	MaxDose     *int    `json:"max_dose" db:"max_dose" validate:"required"`
-	AvailableAt *string `json:"available_at" db:"available_at" validate:"required"`
+	Name        *string   `json:"name" db:"name" validate:"required"`
+	Approved    *bool     `json:"approved" db:"approved" validate:"required"`
+	MinDose     *int      `json:"min_dose" db:"min_dose" validate:"required,gt=0"`
+	MaxDose     *int      `json:"max_dose" db:"max_dose" validate:"required,gt=0"`
+	AvailableAt *DateTime `json:"available_at" db:"available_at" validate:"required"`
 }
 
 func (u *DrugForm) Validate(v *validator.Validate) error {
-	err := v.Struct(u)
-	if err != nil {
-
-		var ve validator.ValidationErrors
-
-		if errors.As(err, &ve) {
-			var out error
-			err = err.(validator.ValidationErrors)
-			for _, fe := range ve {
-				out = errors.New(fmt.Sprintf("%s: %s", fe.Field(), msgForTag(fe.Tag())))
-			}
-			return out
-		}
+	var errs = NewValidationErrors(v.Struct(u))
+	if u.MinDose != nil && u.MaxDose != nil && *u.MaxDose < *u.MinDose {
+		errs = append(errs, NewFieldError("max_dose", "gtefield", "The value can not be less than min_dose", "min_dose"))
 	}
-	return nil
+	return errs.Err()
 }
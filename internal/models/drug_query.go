@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery represents an invalid query string
+var ErrInvalidQuery = errors.New("Los parámetros de consulta son invalidos")
+
+// likeEscaper escapes the LIKE wildcards of the user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// drugSortColumns whitelist of the columns that can be used to sort drugs
+var drugSortColumns = map[string]string{
+	"id":           "id",
+	"name":         "name",
+	"approved":     "approved",
+	"min_dose":     "min_dose",
+	"max_dose":     "max_dose",
+	"available_at": "available_at",
+}
+
+// DrugQuery filters, sorting and pagination for the drugs list
+type DrugQuery struct {
+	Pagination
+	Name            *string
+	Approved        *bool
+	AvailableBefore *time.Time
+	AvailableAfter  *time.Time
+	MinDose         *int
+	MaxDose         *int
+	Sort            string
+	Desc            bool
+}
+
+// NewDrugQuery creates a drug query with the default pagination
+func NewDrugQuery() *DrugQuery {
+	return &DrugQuery{
+		Pagination: NewPagination(),
+		Sort:       "id",
+	}
+}
+
+// ParseDrugQuery reads the drug query from the url values
+func ParseDrugQuery(values url.Values) (*DrugQuery, error) {
+	var q = NewDrugQuery()
+	var err error
+
+	if q.Pagination, err = ParsePagination(values); err != nil {
+		return nil, err
+	}
+
+	if v := strings.TrimSpace(values.Get("name")); v != "" {
+		q.Name = &v
+	}
+	if v := values.Get("approved"); v != "" {
+		approved, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: approved", ErrInvalidQuery)
+		}
+		q.Approved = &approved
+	}
+	if q.AvailableBefore, err = parseQueryTime(values, "available_before"); err != nil {
+		return nil, err
+	}
+	if q.AvailableAfter, err = parseQueryTime(values, "available_after"); err != nil {
+		return nil, err
+	}
+	if q.MinDose, err = parseOptionalInt(values, "min_dose"); err != nil {
+		return nil, err
+	}
+	if q.MaxDose, err = parseOptionalInt(values, "max_dose"); err != nil {
+		return nil, err
+	}
+
+	if v := values.Get("sort"); v != "" {
+		q.Desc = strings.HasPrefix(v, "-")
+		column, ok := drugSortColumns[strings.TrimPrefix(v, "-")]
+		if !ok {
+			return nil, fmt.Errorf("%w: sort", ErrInvalidQuery)
+		}
+		q.Sort = column
+	}
+
+	return q, nil
+}
+
+// OrderBy returns the ORDER BY clause, the column is always taken from the whitelist
+func (q *DrugQuery) OrderBy() string {
+	var column, ok = drugSortColumns[q.Sort]
+	if !ok {
+		column = "id"
+	}
+	var direction = "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+	// id as tie-breaker keeps the pages stable
+	if column == "id" {
+		return fmt.Sprintf("id %s", direction)
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// Where returns the WHERE clause and its arguments
+func (q *DrugQuery) Where() (string, []any) {
+	var conditions = []string{"deleted_at IS NULL"}
+	var args = make([]any, 0)
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Name != nil {
+		add("name ILIKE $%d", likeEscaper.Replace(*q.Name)+"%")
+	}
+	if q.Approved != nil {
+		add("approved = $%d", *q.Approved)
+	}
+	if q.AvailableBefore != nil {
+		add("available_at <= $%d", *q.AvailableBefore)
+	}
+	if q.AvailableAfter != nil {
+		add("available_at >= $%d", *q.AvailableAfter)
+	}
+	if q.MinDose != nil {
+		add("min_dose >= $%d", *q.MinDose)
+	}
+	if q.MaxDose != nil {
+		add("max_dose <= $%d", *q.MaxDose)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func parsePositiveInt(values url.Values, key string, def int) (int, error) {
+	var v = values.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidQuery, key)
+	}
+	return n, nil
+}
+
+func parseOptionalInt(values url.Values, key string) (*int, error) {
+	var v = values.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, key)
+	}
+	return &n, nil
+}
+
+func parseQueryTime(values url.Values, key string) (*time.Time, error) {
+	var v = values.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	if tm, err := ParseDateTime(v); err == nil {
+		return &tm, nil
+	}
+	if tm, err := time.Parse(time.DateOnly, v); err == nil {
+		return &tm, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, key)
+}
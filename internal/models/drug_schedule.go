@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	DoseCompleted = "completed"
+	DoseDue       = "due"
+	DoseOverdue   = "overdue"
+	DoseScheduled = "scheduled"
+
+	SeriesNotStarted = "not_started"
+	SeriesInProgress = "in_progress"
+	SeriesCompleted  = "completed"
+)
+
+// DrugSchedule dosing schedule of a drug
+type DrugSchedule struct {
+	DrugID                  int32 `json:"drug_id"`
+	Doses                   int   `json:"doses"`
+	MinIntervalDays         int   `json:"min_interval_days"`
+	RecommendedIntervalDays int   `json:"recommended_interval_days"`
+	BoosterIntervalDays     *int  `json:"booster_interval_days"`
+}
+
+// ScheduledDose a dose of the series
+type ScheduledDose struct {
+	Number    int        `json:"number"`
+	Booster   bool       `json:"booster"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+}
+
+// SeriesProgress progress of a patient on the schedule of a drug
+type SeriesProgress struct {
+	DrugID         int32           `json:"drug_id"`
+	Drug           string          `json:"drug"`
+	Doses          int             `json:"doses"`
+	CompletedDoses int             `json:"completed_doses"`
+	Status         string          `json:"status"`
+	NextDueAt      *time.Time      `json:"next_due_at"`
+	Schedule       []ScheduledDose `json:"schedule"`
+}
+
+// In renders the dates of the progress in loc
+func (p *SeriesProgress) In(loc *time.Location) *SeriesProgress {
+	if p.NextDueAt != nil {
+		var next = p.NextDueAt.In(loc)
+		p.NextDueAt = &next
+	}
+	for i, dose := range p.Schedule {
+		if dose.AppliedAt != nil {
+			var applied = dose.AppliedAt.In(loc)
+			p.Schedule[i].AppliedAt = &applied
+		}
+		if dose.DueAt != nil {
+			var due = dose.DueAt.In(loc)
+			p.Schedule[i].DueAt = &due
+		}
+	}
+	return p
+}
+
+// PatientSeries applications of a patient for a drug with schedule
+type PatientSeries struct {
+	Drug      string
+	Schedule  DrugSchedule
+	AppliedAt []time.Time
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// ValidateNextDose checks that a new application respects the minimum interval from the previous
+// dose and to the next one when it is backdated, applied must be sorted in ascending order
+func (s *DrugSchedule) ValidateNextDose(applied []time.Time, appliedAt time.Time) error {
+	// place of the new dose in the series, the later doses move one place
+	var position = 0
+	for position < len(applied) && applied[position].Before(appliedAt) {
+		position++
+	}
+	var number = position + 1
+	if len(applied) >= s.Doses && s.BoosterIntervalDays == nil {
+		return ValidationErrors{NewFieldError("drug_id", "series_completed", fmt.Sprintf("The series of %d doses is already completed", s.Doses), strconv.Itoa(s.Doses))}
+	}
+
+	if position > 0 {
+		var earliest = applied[position-1].Add(days(s.minInterval(number)))
+		if appliedAt.Before(earliest) {
+			var date = earliest.Format(time.RFC3339)
+			return ValidationErrors{NewFieldError("applied_at", "dose_too_early", fmt.Sprintf("The dose %d can not be applied before %s", number, date), strconv.Itoa(number), date)}
+		}
+	}
+	if position < len(applied) {
+		var latest = applied[position].Add(-days(s.minInterval(number + 1)))
+		if appliedAt.After(latest) {
+			var date = latest.Format(time.RFC3339)
+			return ValidationErrors{NewFieldError("applied_at", "dose_too_late", fmt.Sprintf("The dose %d can not be applied after %s", number, date), strconv.Itoa(number), date)}
+		}
+	}
+	return nil
+}
+
+// minInterval days from the previous dose to the dose number, the booster interval after the series
+func (s *DrugSchedule) minInterval(number int) int {
+	if number > s.Doses && s.BoosterIntervalDays != nil {
+		return *s.BoosterIntervalDays
+	}
+	return s.MinIntervalDays
+}
+
+// Progress computes the completed, due and overdue doses of the series at now
+func (s *DrugSchedule) Progress(drug string, applied []time.Time, now time.Time) *SeriesProgress {
+	var progress = &SeriesProgress{
+		DrugID:         s.DrugID,
+		Drug:           drug,
+		Doses:          s.Doses,
+		CompletedDoses: min(len(applied), s.Doses),
+		Status:         SeriesInProgress,
+		Schedule:       make([]ScheduledDose, 0, s.Doses+1),
+	}
+
+	for i, tm := range applied {
+		var appliedAt = tm
+		progress.Schedule = append(progress.Schedule, ScheduledDose{
+			Number:    i + 1,
+			Booster:   i+1 > s.Doses,
+			Status:    DoseCompleted,
+			AppliedAt: &appliedAt,
+		})
+	}
+
+	switch {
+	case len(applied) == 0:
+		progress.Status = SeriesNotStarted
+	case len(applied) >= s.Doses:
+		progress.Status = SeriesCompleted
+	}
+
+	// pending doses of the series
+	var last time.Time
+	if len(applied) > 0 {
+		last = applied[len(applied)-1]
+	}
+	for number := len(applied) + 1; number <= s.Doses; number++ {
+		var dose = ScheduledDose{Number: number, Status: DoseScheduled}
+		var dueAt time.Time
+		if number == 1 {
+			dueAt = now
+			dose.Status = DoseDue
+		} else if number == len(applied)+1 {
+			dueAt = last.Add(days(s.RecommendedIntervalDays))
+			switch {
+			case now.After(dueAt):
+				dose.Status = DoseOverdue
+			case !now.Before(last.Add(days(s.MinIntervalDays))):
+				dose.Status = DoseDue
+			}
+		} else {
+			dueAt = progress.Schedule[len(progress.Schedule)-1].DueAt.Add(days(s.RecommendedIntervalDays))
+		}
+		dose.DueAt = &dueAt
+		progress.Schedule = append(progress.Schedule, dose)
+	}
+
+	// booster after the series is completed
+	if len(applied) >= s.Doses && len(applied) > 0 && s.BoosterIntervalDays != nil {
+		var dueAt = last.Add(days(*s.BoosterIntervalDays))
+		var dose = ScheduledDose{Number: len(applied) + 1, Booster: true, Status: DoseScheduled, DueAt: &dueAt}
+		if !now.Before(dueAt) {
+			dose.Status = DoseDue
+		}
+		progress.Schedule = append(progress.Schedule, dose)
+	}
+
+	for _, dose := range progress.Schedule {
+		if dose.Status != DoseCompleted {
+			progress.NextDueAt = dose.DueAt
+			break
+		}
+	}
+
+	return progress
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type DrugScheduleForm struct {
+	Doses                   *int `json:"doses" db:"doses" validate:"required,gt=0"`
+	MinIntervalDays         *int `json:"min_interval_days" db:"min_interval_days" validate:"required,gte=0"`
+	RecommendedIntervalDays *int `json:"recommended_interval_days" db:"recommended_interval_days" validate:"required,gte=0"`
+	BoosterIntervalDays     *int `json:"booster_interval_days" db:"booster_interval_days" validate:"omitempty,gt=0"`
+}
+
+func (u *DrugScheduleForm) Validate(v *validator.Validate) error {
+	var errs = NewValidationErrors(v.Struct(u))
+	if u.MinIntervalDays != nil && u.RecommendedIntervalDays != nil && *u.RecommendedIntervalDays < *u.MinIntervalDays {
+		errs = append(errs, NewFieldError("recommended_interval_days", "gtefield", "The value can not be less than min_interval_days", "min_interval_days"))
+	}
+	return errs.Err()
+}
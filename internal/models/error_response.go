@@ -1,5 +0,0 @@
-package models
-
-type ErrorResponse struct {
-	ErrorMessage string `json:"error"`
-}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// HealthCheck result of a readiness check
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness result of all the readiness checks
+type Readiness struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// PoolStats connection pool statistics of the database
+type PoolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// DatabaseStatus state of the database
+type DatabaseStatus struct {
+	Status           string    `json:"status"`
+	Error            string    `json:"error,omitempty"`
+	MigrationVersion uint      `json:"migration_version"`
+	ExpectedVersion  uint      `json:"expected_version"`
+	MigrationDirty   bool      `json:"migration_dirty"`
+	Pool             PoolStats `json:"pool"`
+}
+
+// ServiceStatus detailed state of the service
+type ServiceStatus struct {
+	Status        string         `json:"status"`
+	Version       string         `json:"version"`
+	StartedAt     time.Time      `json:"started_at"`
+	Uptime        string         `json:"uptime"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	ShuttingDown  bool           `json:"shutting_down"`
+	Database      DatabaseStatus `json:"database"`
+}
@@ -8,4 +8,10 @@ type HTTPServer struct {
 	Port          int           `envconfig:"PORT" default:"8080"`
 	ReadTimeout   time.Duration `envconfig:"HTTP_SERVER_READ_TIMEOUT" default:"1s"`
 	WriteTimeout  time.Duration `envconfig:"HTTP_SERVER_WRITE_TIMEOUT" default:"2s"`
+	// TrustedProxies IPs and CIDRs of the proxies whose X-Forwarded-For and X-Real-IP are used, the
+	// headers of the other peers are ignored
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+	// ShutdownDelay time that the requests keep being served after readiness starts failing, so the
+	// probes take the instance out of the load balancer before it stops accepting connections
+	ShutdownDelay time.Duration `envconfig:"HTTP_SERVER_SHUTDOWN_DELAY" default:"5s"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// ImportMode what to do with the valid rows of an import when other rows fail
+type ImportMode string
+
+const (
+	// ImportAtomic imports every row or none of them
+	ImportAtomic ImportMode = "atomic"
+	// ImportBestEffort imports the valid rows and reports the others
+	ImportBestEffort ImportMode = "best_effort"
+)
+
+// ImportOptions options of a bulk import
+type ImportOptions struct {
+	// DryRun checks every row and reports the result without saving anything
+	DryRun bool
+	Mode   ImportMode
+}
+
+// ParseImportOptions reads the dry_run and mode parameters, by default the import is atomic
+func ParseImportOptions(values url.Values) (ImportOptions, error) {
+	var opts = ImportOptions{Mode: ImportAtomic}
+
+	if v := values.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("%w: dry_run", ErrInvalidQuery)
+		}
+		opts.DryRun = dryRun
+	}
+
+	switch mode := ImportMode(values.Get("mode")); mode {
+	case "":
+	case ImportAtomic, ImportBestEffort:
+		opts.Mode = mode
+	default:
+		return opts, fmt.Errorf("%w: mode", ErrInvalidQuery)
+	}
+
+	return opts, nil
+}
+
+// Commit the rows are saved when it is not a dry run and the mode accepts the failed rows
+func (o ImportOptions) Commit(failed int) bool {
+	return !o.DryRun && (o.Mode == ImportBestEffort || failed == 0)
+}
+
+// ImportReport result of a bulk import
+type ImportReport struct {
+	DryRun bool       `json:"dry_run"`
+	Mode   ImportMode `json:"mode"`
+	// Committed the succeeded rows were saved
+	Committed bool             `json:"committed"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// ImportRowError why a row of the file was not imported, the same code and detail as the problem
+// of the single create endpoint
+type ImportRowError struct {
+	// Row line of the row in the file
+	Row    int              `json:"row"`
+	Code   string           `json:"code"`
+	Detail string           `json:"detail,omitempty"`
+	Errors ValidationErrors `json:"errors,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// JWT configuration of the access tokens
+type JWT struct {
+	// JWTPrivateKey secret of the HS256 tokens, it signs the tokens when there are no key files and
+	// verifies the tokens without kid while the key files are introduced
+	JWTPrivateKey string `envconfig:"JWT_PRIVATE_KEY"`
+	// JWTKeyFiles PEM private keys (RSA, P-256 or Ed25519), the first one signs and the others only
+	// verify the tokens signed before a rotation
+	JWTKeyFiles []string `envconfig:"JWT_KEY_FILES"`
+	// TokenTTL seconds until the access token expires
+	TokenTTL int `envconfig:"TOKEN_TTL" default:"300"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Mail plain text message sent by the mailer
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer configuration of the outgoing mail
+type Mailer struct {
+	// MailerDriver smtp, file writes each mail in MailerDir and memory keeps them in the process
+	MailerDriver string `envconfig:"MAILER_DRIVER" default:"file"`
+	MailerFrom   string `envconfig:"MAILER_FROM" default:"no-reply@ionix.local"`
+	MailerDir    string `envconfig:"MAILER_DIR" default:"log/mail"`
+	SMTPHost     string `envconfig:"SMTP_HOST" default:"localhost"`
+	SMTPPort     int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+// MFA configuration of the second factor
+type MFA struct {
+	// MFAIssuer name of the account in the authenticator apps
+	MFAIssuer string `envconfig:"MFA_ISSUER" default:"Ionix"`
+	// MFAChallengeTTL seconds to send the code after the password
+	MFAChallengeTTL int `envconfig:"MFA_CHALLENGE_TTL" default:"300"`
+	// MFARecoveryCodes recovery codes given on enrollment
+	MFARecoveryCodes int `envconfig:"MFA_RECOVERY_CODES" default:"10"`
+}
+
+// UserMFA totp second factor of a user, Secret is empty without enrollment and EnabledAt is nil until
+// the first code confirms the enrollment
+type UserMFA struct {
+	UserID    int32      `db:"id"`
+	Secret    string     `db:"mfa_secret"`
+	EnabledAt *time.Time `db:"mfa_enabled_at"`
+	// LastStep period of the last accepted code
+	LastStep int64 `db:"mfa_last_step"`
+	// Required the role of the user has to sign in with a second factor
+	Required bool `db:"mfa_required"`
+}
+
+// Enabled reports whether the sign in asks for a code
+func (m *UserMFA) Enabled() bool {
+	return m.Secret != "" && m.EnabledAt != nil
+}
+
+// MFAEnrollment secret to add to the authenticator app and the recovery codes, they are only shown once
+type MFAEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFACodeForm code of the authenticator app, the recovery codes are accepted once the enrollment is confirmed
+type MFACodeForm struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+func (u *MFACodeForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
+
+// MFAVerifyForm challenge token of the sign in and the code
+type MFAVerifyForm struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+func (u *MFAVerifyForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
+
+// RoleMFAForm whether the users of the role have to sign in with a second factor
+type RoleMFAForm struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+func (u *RoleMFAForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Pagination page and size requested by the client
+type Pagination struct {
+	Page  int
+	Limit int
+}
+
+// NewPagination creates the default pagination
+func NewPagination() Pagination {
+	return Pagination{Page: 1, Limit: DefaultPageLimit}
+}
+
+// ParsePagination reads page and limit from the url values
+func ParsePagination(values url.Values) (Pagination, error) {
+	var p = NewPagination()
+	var err error
+
+	if p.Page, err = parsePositiveInt(values, "page", p.Page); err != nil {
+		return p, err
+	}
+	if p.Limit, err = parsePositiveInt(values, "limit", p.Limit); err != nil {
+		return p, err
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	// the offset of the page has to fit in an int, an overflow would be a negative OFFSET
+	if p.Page-1 > math.MaxInt/p.Limit {
+		return p, fmt.Errorf("%w: page", ErrInvalidQuery)
+	}
+	return p, nil
+}
+
+// Offset returns the number of rows to skip
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// TotalPages returns the number of pages for the total of rows
+func (p Pagination) TotalPages(total int) int {
+	if total == 0 {
+		return 0
+	}
+	return (total + p.Limit - 1) / p.Limit
+}
+
+// Meta builds the pagination meta for the response
+func (p Pagination) Meta(total int) *Meta {
+	return &Meta{
+		Total:      total,
+		Page:       p.Page,
+		Limit:      p.Limit,
+		TotalPages: p.TotalPages(total),
+	}
+}
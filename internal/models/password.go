@@ -0,0 +1,17 @@
+package models
+
+// Password configuration of the password hashes and of the rules of the new passwords
+type Password struct {
+	// PasswordAlgorithm scheme of the new hashes, argon2id or bcrypt, the hashes of the other scheme are upgraded on sign in
+	PasswordAlgorithm string `envconfig:"PASSWORD_ALGORITHM" default:"argon2id"`
+	// Argon2Memory KiB of memory, Argon2Iterations passes and Argon2Parallelism threads of argon2id
+	Argon2Memory      uint32 `envconfig:"PASSWORD_ARGON2_MEMORY" default:"65536"`
+	Argon2Iterations  uint32 `envconfig:"PASSWORD_ARGON2_ITERATIONS" default:"3"`
+	Argon2Parallelism uint8  `envconfig:"PASSWORD_ARGON2_PARALLELISM" default:"2"`
+	BcryptCost        int    `envconfig:"PASSWORD_BCRYPT_COST" default:"10"`
+	// PasswordMinLength and PasswordMaxLength characters of the new passwords, 0 is no maximum
+	PasswordMinLength int `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	PasswordMaxLength int `envconfig:"PASSWORD_MAX_LENGTH" default:"128"`
+	// PasswordBreachedFile list of breached passwords that can not be used, one password or SHA-1 per line
+	PasswordBreachedFile string `envconfig:"PASSWORD_BREACHED_FILE"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Patient struct {
+	ID         int32      `json:"id"`
+	Name       string     `json:"name"`
+	DocumentID *string    `json:"document_id"`
+	Birthdate  *time.Time `json:"birthdate"`
+}
+
+// PatientSummary datos del paciente embebidos en otras respuestas
+type PatientSummary struct {
+	ID         int32   `json:"id"`
+	Name       string  `json:"name"`
+	DocumentID *string `json:"document_id"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type PatientForm struct {
+	Name       *string `json:"name" db:"name" validate:"required"`
+	DocumentID *string `json:"document_id" db:"document_id" validate:"omitempty,max=40"`
+	Birthdate  *string `json:"birthdate" db:"birthdate" validate:"omitempty,datetime=2006-01-02"`
+}
+
+func (u *PatientForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PatientQuery filters and pagination for the patients search
+type PatientQuery struct {
+	Pagination
+	Name       *string
+	DocumentID *string
+	Birthdate  *time.Time
+}
+
+// NewPatientQuery creates a patient query with the default pagination
+func NewPatientQuery() *PatientQuery {
+	return &PatientQuery{Pagination: NewPagination()}
+}
+
+// ParsePatientQuery reads the patient query from the url values
+func ParsePatientQuery(values url.Values) (*PatientQuery, error) {
+	var q = NewPatientQuery()
+	var err error
+
+	if q.Pagination, err = ParsePagination(values); err != nil {
+		return nil, err
+	}
+	if v := strings.TrimSpace(values.Get("name")); v != "" {
+		q.Name = &v
+	}
+	if v := strings.TrimSpace(values.Get("document_id")); v != "" {
+		q.DocumentID = &v
+	}
+	if v := values.Get("birthdate"); v != "" {
+		tm, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: birthdate", ErrInvalidQuery)
+		}
+		q.Birthdate = &tm
+	}
+
+	return q, nil
+}
+
+// Where returns the WHERE clause and its arguments
+func (q *PatientQuery) Where() (string, []any) {
+	var conditions = []string{"deleted_at IS NULL"}
+	var args = make([]any, 0)
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Name != nil {
+		add("name ILIKE $%d", likeEscaper.Replace(*q.Name)+"%")
+	}
+	if q.DocumentID != nil {
+		add("document_id = $%d", *q.DocumentID)
+	}
+	if q.Birthdate != nil {
+		add("birthdate = $%d", *q.Birthdate)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,13 @@
+package models
+
+// Problem error de la API en formato RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string           `json:"type"`
+	Title     string           `json:"title"`
+	Status    int              `json:"status"`
+	Detail    string           `json:"detail,omitempty"`
+	Instance  string           `json:"instance,omitempty"`
+	Code      string           `json:"code"`
+	RequestID string           `json:"request_id,omitempty"`
+	Errors    ValidationErrors `json:"errors,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+// RefreshToken stored refresh token, only the hash of the token is persisted
+type RefreshToken struct {
+	ID        int64      `db:"id"`
+	UserID    int32      `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+type RefreshTokenForm struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (u *RefreshTokenForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
@@ -2,8 +2,6 @@ package models
 
 import (
 	"encoding/hex"
-	"errors"
-	"fmt"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/sha3"
@@ -12,7 +10,7 @@ import (
 // RegisterForm struct para la data del registro
 type RegisterForm struct {
 	Email    string `form:"email" json:"email" validate:"required,email"`
-	Password string `form:"password" json:"password,omitempty" validate:"required"`
+	Password string `form:"password" json:"password,omitempty" validate:"required,password"`
 	Name     string `form:"name" json:"name,omitempty"`
 }
 
@@ -27,7 +25,7 @@ func (u *RegisterForm) BcryptPassword(password string) (string, error) {
 	buf := []byte(password)
 	hash, err := bcrypt.GenerateFromPassword(buf, bcrypt.DefaultCost)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	return string(hash), nil
 }
@@ -43,17 +41,7 @@ func (u *RegisterForm) ValidateBcryptPassword(password, password2 string) bool {
 }
 
 func (u *RegisterForm) Validate(v *validator.Validate) error {
-	err := v.Struct(u)
-	var ve validator.ValidationErrors
-	if errors.As(err, &ve) {
-		var out error
-		err = err.(validator.ValidationErrors)
-		for _, fe := range ve {
-			out = errors.New(fmt.Sprintf("%s. %s", fe.StructField(), msgForTag(fe.Tag())))
-		}
-		return out
-	}
-	return nil
+	return NewValidationErrors(v.Struct(u)).Err()
 }
 
 func msgForTag(tag string) string {
@@ -64,6 +52,18 @@ func msgForTag(tag string) string {
 		return "Bad email format"
 	case "gt":
 		return "The value needs to be more than 0 and non negative"
+	case "gte":
+		return "The value can not be negative"
+	case "oneof":
+		return "The value is not allowed"
+	case "max":
+		return "The value is too long"
+	case "datetime":
+		return "Bad date format"
+	case "min":
+		return "The value is too short"
+	case "password":
+		return "The password needs an uppercase letter, a lowercase letter and a digit"
 	}
 	return ""
 }
@@ -0,0 +1,66 @@
+package models
+
+// Role of a user, it goes in the role claim of the access token
+type Role string
+
+// Permission action over a resource
+type Permission string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleClinician Role = "clinician"
+	RoleReadOnly  Role = "readonly"
+)
+
+const (
+	PermDrugsRead         Permission = "drugs:read"
+	PermDrugsWrite        Permission = "drugs:write"
+	PermPatientsRead      Permission = "patients:read"
+	PermPatientsWrite     Permission = "patients:write"
+	PermVaccinationsRead  Permission = "vaccinations:read"
+	PermVaccinationsWrite Permission = "vaccinations:write"
+	PermUsersManage       Permission = "users:manage"
+	PermAuditRead         Permission = "audit:read"
+)
+
+// rolePermissions permissions granted to every role
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermDrugsRead, PermDrugsWrite,
+		PermPatientsRead, PermPatientsWrite,
+		PermVaccinationsRead, PermVaccinationsWrite,
+		PermUsersManage,
+		PermAuditRead,
+	},
+	RoleClinician: {
+		PermDrugsRead,
+		PermPatientsRead, PermPatientsWrite,
+		PermVaccinationsRead, PermVaccinationsWrite,
+	},
+	RoleReadOnly: {
+		PermDrugsRead,
+		PermPatientsRead,
+		PermVaccinationsRead,
+	},
+}
+
+// Valid reports if the role is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports if the role has the permission
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
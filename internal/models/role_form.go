@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type RoleForm struct {
+	Role *string `json:"role" validate:"required,oneof=admin clinician readonly"`
+}
+
+func (u *RoleForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
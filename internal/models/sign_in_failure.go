@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SignInScope what the failed sign ins are counted by
+type SignInScope string
+
+const (
+	SignInScopeEmail SignInScope = "email"
+	SignInScopeIP    SignInScope = "ip"
+)
+
+// SignInFailure failed sign ins of an email or an IP since the count started again
+type SignInFailure struct {
+	Scope         SignInScope `db:"scope"`
+	Subject       string      `db:"subject"`
+	Failures      int         `db:"failures"`
+	LastFailureAt time.Time   `db:"last_failure_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type Tracing struct {
+	TracingExporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	TracingEndpoint    string  `envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+	TracingInsecure    bool    `envconfig:"TRACING_OTLP_INSECURE" default:"true"`
+	TracingFile        string  `envconfig:"TRACING_FILE"`
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	TracingServiceName string  `envconfig:"TRACING_SERVICE_NAME" default:"api_drugs"`
+}
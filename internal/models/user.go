@@ -9,9 +9,12 @@ type User struct {
 	Name      string    `json:"name" db:"name"`
 	Email     string    `json:"email" db:"email"`
 	Password  string    `json:"-" db:"password"`
+	Role      Role      `json:"role" db:"role"`
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
 	DeletedAt time.Time `json:"-" db:"deleted_at"`
+	// EmailVerifiedAt nil until the user follows the link of the verification mail
+	EmailVerifiedAt *time.Time `json:"-" db:"email_verified_at"`
 }
 
 // NewUser crea un nuevo usuario
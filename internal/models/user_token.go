@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+	"time"
+)
+
+// TokenPurpose flow in which a user token can be used
+type TokenPurpose string
+
+const (
+	TokenPasswordReset     TokenPurpose = "password_reset"
+	TokenEmailVerification TokenPurpose = "email_verification"
+	// TokenMFAChallenge returned by the sign in of the users with a second factor instead of the access token
+	TokenMFAChallenge TokenPurpose = "mfa_challenge"
+)
+
+// UserToken single use token sent by mail or returned as MFA challenge, only the hash of the token is persisted
+type UserToken struct {
+	ID        int64        `db:"id"`
+	UserID    int32        `db:"user_id"`
+	Purpose   TokenPurpose `db:"purpose"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    *time.Time   `db:"used_at"`
+}
+
+// EmailForm email of the account that receives a password reset or a new verification
+type EmailForm struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (u *EmailForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
+
+// ResetPasswordForm token of the password reset mail and the new password
+type ResetPasswordForm struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password,omitempty" validate:"required,password"`
+}
+
+func (u *ResetPasswordForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
+
+// VerifyEmailForm token of the verification mail
+type VerifyEmailForm struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (u *VerifyEmailForm) Validate(v *validator.Validate) error {
+	return NewValidationErrors(v.Struct(u)).Err()
+}
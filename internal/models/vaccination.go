@@ -3,10 +3,29 @@ package models
 import "time"
 
 type Vaccination struct {
-	ID        int32     `json:"id"`
-	Name      string    `json:"name"`
-	Drug      string    `json:"drug"`
-	DrugID    int32     `json:"drug_id"`
-	Dose      int32     `json:"dose"`
-	AppliedAt time.Time `json:"date"`
+	ID        int32          `json:"id"`
+	Patient   PatientSummary `json:"patient"`
+	Drug      string         `json:"drug"`
+	DrugID    int32          `json:"drug_id"`
+	Dose      int32          `json:"dose"`
+	AppliedAt time.Time      `json:"date"`
+	// Version incremented by every update, the ETag of the vaccination
+	Version int `json:"-"`
+}
+
+// In renders the dates of the vaccination in loc
+func (v *Vaccination) In(loc *time.Location) *Vaccination {
+	v.AppliedAt = v.AppliedAt.In(loc)
+	return v
+}
+
+// Form the vaccination as the body of a PUT, the document patched by PATCH
+func (v *Vaccination) Form() *VaccinationForm {
+	var patientID, drugID, dose = int(v.Patient.ID), int(v.DrugID), int(v.Dose)
+	return &VaccinationForm{
+		PatientID: &patientID,
+		DrugID:    &drugID,
+		Dose:      &dose,
+		AppliedAt: &DateTime{Time: v.AppliedAt},
+	}
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"strings"
+)
+
+// FieldError error de validación de un campo
+type FieldError struct {
+	// Field json name of the field
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// Rule that failed and its parameter, like max and 40
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	// rule of the validator that failed, translated by its registered translation
+	tag validator.FieldError
+	// params of the message of the rules of the API in the catalog
+	params []string
+}
+
+// NewFieldError error of a rule of the API, its message is validation.{rule} in the catalog
+func NewFieldError(field, rule, message string, params ...string) FieldError {
+	return FieldError{Field: field, Message: message, Rule: rule, Param: strings.Join(params, " "), params: params}
+}
+
+// NewValidationErrors every failed rule of the error of validator.Struct, nil when err is nil
+func NewValidationErrors(err error) ValidationErrors {
+	var ve validator.ValidationErrors
+	if !errors.As(err, &ve) {
+		return nil
+	}
+
+	var out = make(ValidationErrors, 0, len(ve))
+	for _, fe := range ve {
+		out = append(out, FieldError{Field: fe.Field(), Message: msgForTag(fe.Tag()), Rule: fe.Tag(), Param: fe.Param(), tag: fe})
+	}
+	return out
+}
+
+// Err nil when there are no errors, so the list can be returned as error
+func (ve ValidationErrors) Err() error {
+	if len(ve) == 0 {
+		return nil
+	}
+	return ve
+}
+
+// ValidationErrors lista de errores de validación
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	var msgs = make([]string, 0, len(ve))
+	for _, fe := range ve {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Translate messages of the errors in the language of trans, the message stays when it has no translation
+func (ve ValidationErrors) Translate(trans ut.Translator) ValidationErrors {
+	var out = make(ValidationErrors, 0, len(ve))
+	for _, fe := range ve {
+		if fe.tag != nil {
+			// the validator returns the error text when the rule has no translation
+			if msg := fe.tag.Translate(trans); msg != fe.tag.Error() {
+				fe.Message = msg
+			}
+		} else if fe.Rule != "" {
+			if msg, err := trans.T("validation."+fe.Rule, fe.params...); err == nil {
+				fe.Message = msg
+			}
+		}
+		out = append(out, fe)
+	}
+	return out
+}
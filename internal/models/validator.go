@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NewValidator validator of the forms, the errors use the json name of the fields and it
+// knows the rules of the API
+func NewValidator() *validator.Validate {
+	var v = validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+	_ = v.RegisterValidation("password", validatePassword)
+	return v
+}
+
+// validatePassword the password needs an uppercase letter, a lowercase letter and a digit
+func validatePassword(fl validator.FieldLevel) bool {
+	var upper, lower, digit bool
+	for _, r := range fl.Field().String() {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
@@ -1,5 +1,22 @@
 package models
 
 type ResponseWrapper[T any] struct {
-	Data T `json:"data"`
+	Data  T      `json:"data"`
+	Meta  *Meta  `json:"meta,omitempty"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// Meta pagination information
+type Meta struct {
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	TotalPages int `json:"total_pages"`
+}
+
+// Links pagination links
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
@@ -0,0 +1,36 @@
+package patients
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+// Entity Errors
+var (
+	// Patients
+	InternalServerError   = errors.New("Error interno del servidor. Intente más tarde")
+	ErrTimeout            = errors.New("context timeout")
+	ErrPrepapareQuery     = errors.New("Fallo al preparar el query")
+	ErrPatientNotFound    = errors.New("Paciente no encontrado")
+	ErrServicePatients    = errors.New("Falla en el servicio patients")
+	ErrExecuteStatement   = errors.New("Fallo al ejecutar la declaración SQL")
+	ErrBeginTransaction   = errors.New("Fallo al iniciar la transacción")
+	ErrDuplicatePatient   = errors.New("Ya existe un paciente con este documento")
+	ErrCommitTransaction  = errors.New("Fallo al realizar el commit de la transacción")
+	ErrInsertFailed       = errors.New("Fallo al insertar un nuevo registro")
+	ErrNoRecords          = errors.New("No hay registros")
+	ErrUpdatingRecord     = errors.New("Fallo al actualizar el registro")
+	ErrDeletingRecord     = errors.New("Fallo al eliminar el registro")
+	ErrInvalidRequestBody = errors.New("El cuerpo de la petición es invalido")
+)
+
+// problems returned to the clients, the errors without entry are internal errors
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrTimeout, Code: "timeout", Status: http.StatusGatewayTimeout},
+		problem.Entry{Err: ErrPatientNotFound, Code: "patient_not_found", Status: http.StatusNotFound},
+		problem.Entry{Err: ErrDuplicatePatient, Code: "patient_exists", Status: http.StatusConflict},
+		problem.Entry{Err: ErrInvalidRequestBody, Code: "invalid_body", Status: http.StatusBadRequest},
+	)
+}
@@ -0,0 +1,222 @@
+package patients
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/problem"
+	"kiramishima/ionix/internal/pkg/timezone"
+	httpUtils "kiramishima/ionix/internal/pkg/utils"
+	"net/http"
+)
+
+var _ impl.PatientsHandlers = (*handler)(nil)
+
+// NewPatientHandlers creates an instance of patient handlers
+func NewPatientHandlers(r *chi.Mux, logger *zap.Logger, s impl.PatientService, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) {
+	handler := &handler{
+		logger:   logger,
+		service:  s,
+		response: render,
+		validate: validate,
+	}
+
+	r.Route("/v1/patients", func(r chi.Router) {
+		r.Use(signer.Verifier)
+		r.Use(httpUtils.Authenticator)
+		r.Use(httpUtils.Denylist(denylist))
+
+		r.With(httpUtils.Authorize(models.PermPatientsRead)).Get("/", handler.ListPatientsHandler)
+		r.With(httpUtils.Authorize(models.PermPatientsRead)).Get("/{id}", handler.GetPatientHandler)
+		r.With(httpUtils.Authorize(models.PermPatientsWrite)).Post("/", handler.CreatePatientHandler)
+		r.With(httpUtils.Authorize(models.PermPatientsWrite)).Put("/{id}", handler.UpdatePatientHandler)
+		r.With(httpUtils.Authorize(models.PermPatientsWrite)).Delete("/{id}", handler.DeletePatientHandler)
+		r.With(httpUtils.Authorize(models.PermPatientsRead)).Get("/{id}/schedule", handler.GetPatientScheduleHandler)
+	})
+}
+
+type handler struct {
+	logger   *zap.Logger
+	service  impl.PatientService
+	response *render.Render
+	validate *validator.Validate
+}
+
+func (h handler) ListPatientsHandler(w http.ResponseWriter, req *http.Request) {
+	// query string
+	query, err := models.ParsePatientQuery(req.URL.Query())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+	// Call Service
+	resp, total, err := h.service.GetListPatients(ctx, query)
+
+	if err != nil {
+		h.logger.Info(err.Error())
+		if errors.Is(err, ErrNoRecords) {
+			_ = h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Patient]{
+				Data:  make([]*models.Patient, 0),
+				Meta:  query.Meta(0),
+				Links: httpUtils.PaginationLinks(req, query.Page, 0),
+			})
+			return
+		}
+		problem.Write(w, req, err)
+		return
+	}
+
+	var body = models.ResponseWrapper[[]*models.Patient]{
+		Data:  resp,
+		Meta:  query.Meta(total),
+		Links: httpUtils.PaginationLinks(req, query.Page, query.TotalPages(total)),
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, body); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) GetPatientHandler(w http.ResponseWriter, req *http.Request) {
+	PatientID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	resp, err := h.service.GetPatient(ctx, PatientID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.Patient]{Data: resp}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) CreatePatientHandler(w http.ResponseWriter, req *http.Request) {
+	var form = &models.PatientForm{}
+
+	err := httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// Validate form
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	err = h.service.NewPatient(ctx, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "patient.created")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) UpdatePatientHandler(w http.ResponseWriter, req *http.Request) {
+	PatientID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.PatientForm{}
+
+	err = httpUtils.ReadJSON(w, req, &form)
+
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	err = h.service.UpdatePatient(ctx, PatientID, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "patient.updated")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) DeletePatientHandler(w http.ResponseWriter, req *http.Request) {
+	PatientID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	err = h.service.DeletePatient(ctx, PatientID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "patient.deleted")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) GetPatientScheduleHandler(w http.ResponseWriter, req *http.Request) {
+	PatientID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	resp, err := h.service.GetPatientSchedule(ctx, PatientID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	for _, progress := range resp {
+		progress.In(loc)
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.SeriesProgress]{Data: resp}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
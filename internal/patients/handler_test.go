@@ -0,0 +1,144 @@
+package patients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/unrolled/render"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/mocks"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_CreatePatientHandler(t *testing.T) {
+	t.Parallel()
+	var name = "Jhon Wick"
+	var birthdate = "1990-01-02"
+	var badBirthdate = "02/01/1990"
+
+	testCases := map[string]struct {
+		form          *models.PatientForm
+		buildStubs    func(uc *mocks.MockPatientService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Created": {
+			form: &models.PatientForm{Name: &name, Birthdate: &birthdate},
+			buildStubs: func(uc *mocks.MockPatientService) {
+				uc.EXPECT().NewPatient(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		"Name required": {
+			form:       &models.PatientForm{Birthdate: &birthdate},
+			buildStubs: func(uc *mocks.MockPatientService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		"Bad birthdate": {
+			form:       &models.PatientForm{Name: &name, Birthdate: &badBirthdate},
+			buildStubs: func(uc *mocks.MockPatientService) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		"Duplicate document": {
+			form: &models.PatientForm{Name: &name},
+			buildStubs: func(uc *mocks.MockPatientService) {
+				uc.EXPECT().NewPatient(gomock.Any(), gomock.Any()).Times(1).Return(ErrDuplicatePatient)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockPatientService(ctrl)
+			tc.buildStubs(uc)
+
+			data, err := json.Marshal(tc.form)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/v1/patients", bytes.NewReader(data))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			h.CreatePatientHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestHandler_GetPatientHandler(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		ID            string
+		buildStubs    func(uc *mocks.MockPatientService)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		"Getting Data": {
+			ID: "1",
+			buildStubs: func(uc *mocks.MockPatientService) {
+				uc.EXPECT().GetPatient(gomock.Any(), 1).Times(1).Return(&models.Patient{ID: 1, Name: "Jhon Wick"}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), `"name":"Jhon Wick"`)
+			},
+		},
+		"Not found": {
+			ID: "2",
+			buildStubs: func(uc *mocks.MockPatientService) {
+				uc.EXPECT().GetPatient(gomock.Any(), 2).Times(1).Return(nil, ErrPatientNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			uc := mocks.NewMockPatientService(ctrl)
+			tc.buildStubs(uc)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/v1/patients/"+tc.ID, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.ID)
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+			h := handler{
+				logger:   zap.NewNop(),
+				service:  uc,
+				response: render.New(),
+				validate: models.NewValidator(),
+			}
+			h.GetPatientHandler(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
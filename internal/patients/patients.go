@@ -0,0 +1,26 @@
+package patients
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"github.com/unrolled/render"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"time"
+)
+
+// Module patients
+var Module = fx.Module("patients",
+	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) error {
+		// loads repository
+		var repo = NewPatientRepository(conn, logger)
+		// loads service
+		var svc = NewPatientService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+		// loads handlers
+		NewPatientHandlers(r, logger, svc, render, validate, denylist, signer)
+		return nil
+	}),
+)
@@ -0,0 +1,312 @@
+package patients
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"time"
+)
+
+// implement patient repository
+var _ interfaces.PatientRepository = (*repository)(nil)
+
+// Repository struct
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+// NewPatientRepository Creates a new instance of Repository
+func NewPatientRepository(conn *sqlx.DB, logger *zap.Logger) *repository {
+	return &repository{
+		db:  conn,
+		log: logger,
+	}
+}
+
+// GetPatientsData gets a page of data from patients table
+func (repo repository) GetPatientsData(ctx context.Context, q *models.PatientQuery) ([]*models.Patient, int, error) {
+	var where, args = q.Where()
+	var list = make([]*models.Patient, 0)
+
+	total, err := repo.countPatients(ctx, where, args)
+	if err != nil {
+		return list, 0, err
+	}
+
+	var query = fmt.Sprintf(`SELECT id, name, document_id, birthdate FROM patients WHERE %s ORDER BY name ASC, id ASC LIMIT %d OFFSET %d`,
+		where, q.Limit, q.Offset())
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, 0, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	rows, err := stmt.QueryxContext(ctx, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return list, total, nil
+		} else {
+			return list, total, ErrExecuteStatement
+		}
+	}
+	for rows.Next() {
+		var item = &models.Patient{}
+		err = rows.Scan(&item.ID, &item.Name, &item.DocumentID, &item.Birthdate)
+		if err != nil {
+			return list, total, ErrExecuteStatement
+		}
+		list = append(list, item)
+	}
+
+	return list, total, nil
+}
+
+// countPatients counts the rows of patients table matching the filters
+func (repo repository) countPatients(ctx context.Context, where string, args []any) (int, error) {
+	var query = fmt.Sprintf(`SELECT COUNT(*) FROM patients WHERE %s`, where)
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return 0, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	var total int
+	err = stmt.QueryRowContext(ctx, args...).Scan(&total)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, ErrExecuteStatement
+	}
+
+	return total, nil
+}
+
+func (repo repository) GetPatientItemByID(ctx context.Context, patientId int) (*models.Patient, error) {
+	var query = `SELECT id, name, document_id, birthdate FROM patients WHERE deleted_at IS NULL AND id = $1`
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	row := stmt.QueryRowContext(ctx, patientId)
+
+	var item = &models.Patient{}
+	err = row.Scan(&item.ID, &item.Name, &item.DocumentID, &item.Birthdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPatientNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+
+	return item, nil
+}
+
+func (repo repository) CreateNewPatientItem(ctx context.Context, form *models.PatientForm) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var query = `INSERT INTO patients (name, document_id, birthdate) VALUES ($1, $2, CAST($3 AS DATE))`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	_, err = stmt.ExecContext(ctx, form.Name, form.DocumentID, form.Birthdate)
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicatePatient
+		}
+		return ErrInsertFailed
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+func (repo repository) UpdatePatientItem(ctx context.Context, patientId int, form *models.Patient) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var query = `UPDATE patients SET name = $1, document_id = $2, birthdate = $3, updated_at = NOW() WHERE id = $4 AND deleted_at IS NULL`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	_, err = stmt.ExecContext(ctx, form.Name, form.DocumentID, form.Birthdate, patientId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPatientNotFound
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return ErrDuplicatePatient
+		default:
+			return ErrUpdatingRecord
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+func (repo repository) DeletePatientItem(ctx context.Context, patientId int) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var query = `UPDATE patients SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	_, err = stmt.ExecContext(ctx, patientId)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPatientNotFound
+		default:
+			return ErrDeletingRecord
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// GetPatientSeries gets the applications of the patient for every drug with a dosing schedule
+func (repo repository) GetPatientSeries(ctx context.Context, patientId int) ([]*models.PatientSeries, error) {
+	var query = `SELECT
+		d.name drug,
+		s.drug_id,
+		s.doses,
+		s.min_interval_days,
+		s.recommended_interval_days,
+		s.booster_interval_days,
+		v.applied_at
+	FROM vaccinations v
+	INNER JOIN drugs d on d.id = v.drug_id
+	INNER JOIN drug_schedules s on s.drug_id = v.drug_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND v.patient_id = $1
+	ORDER BY s.drug_id ASC, v.applied_at ASC`
+
+	stmt, err := repo.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	var list = make([]*models.PatientSeries, 0)
+
+	rows, err := stmt.QueryxContext(ctx, patientId)
+	if err != nil {
+		return list, ErrExecuteStatement
+	}
+	defer rows.Close()
+
+	var current *models.PatientSeries
+	for rows.Next() {
+		var item = &models.PatientSeries{}
+		var appliedAt time.Time
+		err = rows.Scan(&item.Drug, &item.Schedule.DrugID, &item.Schedule.Doses, &item.Schedule.MinIntervalDays,
+			&item.Schedule.RecommendedIntervalDays, &item.Schedule.BoosterIntervalDays, &appliedAt)
+		if err != nil {
+			return list, ErrExecuteStatement
+		}
+		// rows are sorted by drug, a new drug starts a new series
+		if current == nil || current.Schedule.DrugID != item.Schedule.DrugID {
+			current = item
+			list = append(list, current)
+		}
+		current.AppliedAt = append(current.AppliedAt, appliedAt)
+	}
+	if err = rows.Err(); err != nil {
+		return list, ErrExecuteStatement
+	}
+
+	return list, nil
+}
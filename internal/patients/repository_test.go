@@ -0,0 +1,274 @@
+package patients
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/models"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRepository_GetPatientsData(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("close db", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewPatientRepository(sqlxDB, logger)
+
+	t.Run("Search by document and birthdate", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		q, err := models.ParsePatientQuery(url.Values{"document_id": {"AAA001"}, "birthdate": {"1990-01-02"}})
+		assert.NoError(t, err)
+		var birthdate = time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		var where = `deleted_at IS NULL AND document_id = $1 AND birthdate = $2`
+		mock.ExpectPrepare(`SELECT COUNT(*) FROM patients WHERE `+where).
+			ExpectQuery().
+			WithArgs("AAA001", birthdate).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectPrepare(`SELECT id, name, document_id, birthdate FROM patients WHERE `+where+` ORDER BY name ASC, id ASC LIMIT 20 OFFSET 0`).
+			ExpectQuery().
+			WithArgs("AAA001", birthdate).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "document_id", "birthdate"}).AddRow(1, "Jhon Wick", "AAA001", birthdate))
+
+		data, total, err := repo.GetPatientsData(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, "Jhon Wick", data[0].Name)
+		assert.Equal(t, "AAA001", *data[0].DocumentID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid birthdate", func(t *testing.T) {
+		_, err := models.ParsePatientQuery(url.Values{"birthdate": {"02/01/1990"}})
+		assert.ErrorIs(t, err, models.ErrInvalidQuery)
+	})
+}
+
+func TestRepository_GetPatientItemByID(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewPatientRepository(sqlxDB, logger)
+
+	var query = `SELECT id, name, document_id, birthdate FROM patients WHERE deleted_at IS NULL AND id = $1`
+
+	t.Run("OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "document_id", "birthdate"}).AddRow(1, "Jhon Wick", nil, nil))
+
+		data, err := repo.GetPatientItemByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jhon Wick", data.Name)
+		assert.Nil(t, data.DocumentID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No row", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetPatientItemByID(ctx, 1)
+		assert.EqualError(t, err, ErrPatientNotFound.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_CreateNewPatientItem(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewPatientRepository(sqlxDB, logger)
+
+	var query = `INSERT INTO patients (name, document_id, birthdate) VALUES ($1, $2, CAST($3 AS DATE))`
+
+	var name = "Jhon Wick"
+	var documentID = "AAA001"
+	var birthdate = "1990-01-02"
+	var item = &models.PatientForm{
+		Name:       &name,
+		DocumentID: &documentID,
+		Birthdate:  &birthdate,
+	}
+
+	t.Run("Insert is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs(item.Name, item.DocumentID, item.Birthdate).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		err := repo.CreateNewPatientItem(ctx, item)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Duplicate document", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WillReturnError(&pgconn.PgError{
+				Code: "23505", // Duplicate key error code
+			})
+
+		mock.ExpectRollback()
+
+		err := repo.CreateNewPatientItem(ctx, item)
+		assert.EqualError(t, err, ErrDuplicatePatient.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_DeletePatientItem(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewPatientRepository(sqlxDB, logger)
+
+	var query = `UPDATE patients SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+
+	t.Run("Deleted is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		err := repo.DeletePatientItem(ctx, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_GetPatientSeries(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("close db", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	repo := NewPatientRepository(sqlxDB, logger)
+
+	var query = `SELECT
+		d.name drug,
+		s.drug_id,
+		s.doses,
+		s.min_interval_days,
+		s.recommended_interval_days,
+		s.booster_interval_days,
+		v.applied_at
+	FROM vaccinations v
+	INNER JOIN drugs d on d.id = v.drug_id
+	INNER JOIN drug_schedules s on s.drug_id = v.drug_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND v.patient_id = $1
+	ORDER BY s.drug_id ASC, v.applied_at ASC`
+
+	t.Run("Group applications by drug", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
+		defer cancel()
+
+		var columns = []string{"drug", "drug_id", "doses", "min_interval_days", "recommended_interval_days", "booster_interval_days", "applied_at"}
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("Hepatitis B", 1, 3, 28, 30, nil, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).
+				AddRow("Hepatitis B", 1, 3, 28, 30, nil, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)).
+				AddRow("Tetanus", 2, 1, 0, 0, 3650, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+		series, err := repo.GetPatientSeries(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(series))
+		assert.Equal(t, 2, len(series[0].AppliedAt))
+		assert.Equal(t, "Tetanus", series[1].Drug)
+		assert.Equal(t, 3650, *series[1].Schedule.BoosterIntervalDays)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,225 @@
+package patients
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"time"
+)
+
+var _ impl.PatientService = (*service)(nil)
+
+// NewPatientService creates a new patient service
+func NewPatientService(repo impl.PatientRepository, logger *zap.Logger, timeout time.Duration) *service {
+	return &service{
+		logger:         logger,
+		repository:     repo,
+		contextTimeOut: timeout,
+	}
+}
+
+type service struct {
+	logger         *zap.Logger
+	repository     impl.PatientRepository
+	contextTimeOut time.Duration
+}
+
+func (svc service) GetListPatients(ctx context.Context, query *models.PatientQuery) ([]*models.Patient, int, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	if query == nil {
+		query = models.NewPatientQuery()
+	}
+
+	data, total, err := svc.repository.GetPatientsData(cxt, query)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ErrTimeout
+		default:
+			if errors.Is(err, ErrNoRecords) {
+				return nil, 0, ErrNoRecords
+			} else if errors.Is(err, ErrExecuteStatement) {
+				return nil, 0, ErrExecuteStatement
+			} else {
+				return nil, 0, ErrServicePatients
+			}
+		}
+	}
+
+	return data, total, nil
+}
+
+func (svc service) GetPatient(ctx context.Context, patientId int) (*models.Patient, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	patient, err := svc.repository.GetPatientItemByID(cxt, patientId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		default:
+			if errors.Is(err, ErrPatientNotFound) {
+				return nil, ErrPatientNotFound
+			} else if errors.Is(err, ErrExecuteStatement) {
+				return nil, ErrExecuteStatement
+			} else {
+				return nil, ErrServicePatients
+			}
+		}
+	}
+
+	return patient, nil
+}
+
+func (svc service) NewPatient(ctx context.Context, form *models.PatientForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	err := svc.repository.CreateNewPatientItem(cxt, form)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrDuplicatePatient) {
+				return ErrDuplicatePatient
+			} else {
+				return ErrExecuteStatement
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) UpdatePatient(ctx context.Context, patientId int, form *models.PatientForm) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+	// Retrieve the data
+	patient, err := svc.repository.GetPatientItemByID(cxt, patientId)
+	if errors.Is(err, ErrPatientNotFound) {
+		return ErrPatientNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
+	}
+
+	if form.Name != nil {
+		patient.Name = *form.Name
+	}
+	if form.DocumentID != nil {
+		patient.DocumentID = form.DocumentID
+	}
+	if form.Birthdate != nil {
+		tm, err := time.Parse("2006-01-02", *form.Birthdate)
+		if err != nil {
+			return ErrInvalidRequestBody
+		}
+		patient.Birthdate = &tm
+	}
+
+	// Call repository
+	err = svc.repository.UpdatePatientItem(cxt, patientId, patient)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrPatientNotFound) {
+				return ErrPatientNotFound
+			} else if errors.Is(err, ErrDuplicatePatient) {
+				return ErrDuplicatePatient
+			} else {
+				return ErrUpdatingRecord
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) DeletePatient(ctx context.Context, patientId int) error {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	_, err := svc.repository.GetPatientItemByID(cxt, patientId)
+	if errors.Is(err, ErrPatientNotFound) {
+		return ErrPatientNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
+	}
+
+	// Call repository
+	err = svc.repository.DeletePatientItem(cxt, patientId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrPatientNotFound) {
+				return ErrPatientNotFound
+			} else {
+				return ErrDeletingRecord
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc service) GetPatientSchedule(ctx context.Context, patientId int) ([]*models.SeriesProgress, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	_, err := svc.repository.GetPatientItemByID(cxt, patientId)
+	if errors.Is(err, ErrPatientNotFound) {
+		return nil, ErrPatientNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
+	}
+
+	series, err := svc.repository.GetPatientSeries(cxt, patientId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		default:
+			if errors.Is(err, ErrExecuteStatement) {
+				return nil, ErrExecuteStatement
+			} else {
+				return nil, ErrServicePatients
+			}
+		}
+	}
+
+	var now = time.Now()
+	var progress = make([]*models.SeriesProgress, 0, len(series))
+	for _, s := range series {
+		progress = append(progress, s.Schedule.Progress(s.Drug, s.AppliedAt, now))
+	}
+
+	return progress, nil
+}
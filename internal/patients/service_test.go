@@ -0,0 +1,163 @@
+package patients
+
+import (
+	"context"
+	"kiramishima/ionix/internal/mocks"
+	"kiramishima/ionix/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestService_GetListPatients(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockPatientRepository(mockCtrl)
+
+	var data = []*models.Patient{
+		{ID: 1, Name: "Jhon Wick"},
+		{ID: 2, Name: "Jhon Connor"},
+	}
+
+	repo.EXPECT().GetPatientsData(gomock.Any(), gomock.Any()).Times(1).Return(data, len(data), nil)
+	repo.EXPECT().GetPatientsData(gomock.Any(), gomock.Any()).Times(1).Return(nil, 0, ErrNoRecords)
+
+	svc := NewPatientService(repo, logger, 5*time.Second)
+
+	t.Run("Ok- Getting Data", func(t *testing.T) {
+		ctx := context.Background()
+		var items, total, err = svc.GetListPatients(ctx, models.NewPatientQuery())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(items))
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Ok - No Rows", func(t *testing.T) {
+		ctx := context.Background()
+		var items, _, err = svc.GetListPatients(ctx, nil)
+		assert.Error(t, err)
+		assert.Equal(t, 0, len(items))
+	})
+}
+
+func TestService_UpdatePatient(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockPatientRepository(mockCtrl)
+
+	svc := NewPatientService(repo, logger, 5*time.Second)
+
+	t.Run("Ok- Updating Data", func(t *testing.T) {
+		ctx := context.Background()
+		var birthdate = "1990-01-02"
+		var form = &models.PatientForm{Birthdate: &birthdate}
+
+		repo.EXPECT().GetPatientItemByID(gomock.Any(), 1).Times(1).Return(&models.Patient{ID: 1, Name: "Jhon Wick"}, nil)
+		repo.EXPECT().UpdatePatientItem(gomock.Any(), 1, gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ int, patient *models.Patient) error {
+				assert.Equal(t, "Jhon Wick", patient.Name)
+				assert.Equal(t, 1990, patient.Birthdate.Year())
+				return nil
+			})
+
+		assert.NoError(t, svc.UpdatePatient(ctx, 1, form))
+	})
+
+	t.Run("No existing record", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetPatientItemByID(gomock.Any(), 2).Times(1).Return(nil, ErrPatientNotFound)
+
+		var err = svc.UpdatePatient(ctx, 2, &models.PatientForm{})
+		assert.EqualError(t, err, ErrPatientNotFound.Error())
+	})
+}
+
+func TestService_DeletePatient(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockPatientRepository(mockCtrl)
+
+	svc := NewPatientService(repo, logger, 5*time.Second)
+
+	t.Run("Ok- Deleting Data", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetPatientItemByID(gomock.Any(), 1).Times(1).Return(&models.Patient{ID: 1}, nil)
+		repo.EXPECT().DeletePatientItem(gomock.Any(), 1).Times(1).Return(nil)
+
+		assert.NoError(t, svc.DeletePatient(ctx, 1))
+	})
+}
+
+func TestService_GetPatientSchedule(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockPatientRepository(mockCtrl)
+
+	svc := NewPatientService(repo, logger, 5*time.Second)
+
+	t.Run("Ok - Completed, overdue and due doses", func(t *testing.T) {
+		ctx := context.Background()
+		var booster = 365
+		var now = time.Now()
+		var series = []*models.PatientSeries{
+			{
+				Drug:      "Hepatitis B",
+				Schedule:  models.DrugSchedule{DrugID: 1, Doses: 3, MinIntervalDays: 28, RecommendedIntervalDays: 30},
+				AppliedAt: []time.Time{now.AddDate(0, 0, -90)},
+			},
+			{
+				Drug:      "Tetanus",
+				Schedule:  models.DrugSchedule{DrugID: 2, Doses: 1, BoosterIntervalDays: &booster},
+				AppliedAt: []time.Time{now.AddDate(-2, 0, 0)},
+			},
+		}
+
+		repo.EXPECT().GetPatientItemByID(gomock.Any(), 1).Times(1).Return(&models.Patient{ID: 1, Name: "Jhon Wick"}, nil)
+		repo.EXPECT().GetPatientSeries(gomock.Any(), 1).Times(1).Return(series, nil)
+
+		progress, err := svc.GetPatientSchedule(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(progress))
+
+		assert.Equal(t, models.SeriesInProgress, progress[0].Status)
+		assert.Equal(t, 1, progress[0].CompletedDoses)
+		assert.Equal(t, 3, len(progress[0].Schedule))
+		assert.Equal(t, models.DoseCompleted, progress[0].Schedule[0].Status)
+		assert.Equal(t, models.DoseOverdue, progress[0].Schedule[1].Status)
+		assert.Equal(t, models.DoseScheduled, progress[0].Schedule[2].Status)
+		assert.Equal(t, progress[0].Schedule[1].DueAt, progress[0].NextDueAt)
+
+		assert.Equal(t, models.SeriesCompleted, progress[1].Status)
+		assert.Equal(t, 2, len(progress[1].Schedule))
+		assert.True(t, progress[1].Schedule[1].Booster)
+		assert.Equal(t, models.DoseDue, progress[1].Schedule[1].Status)
+	})
+
+	t.Run("No existing patient", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetPatientItemByID(gomock.Any(), 2).Times(1).Return(nil, ErrPatientNotFound)
+
+		_, err := svc.GetPatientSchedule(ctx, 2)
+		assert.ErrorIs(t, err, ErrPatientNotFound)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"kiramishima/ionix/internal/models"
@@ -14,10 +15,12 @@ import (
 // NewDatabase creates an instance of DB
 func NewDatabase(cfg *models.Configuration, logger *zap.Logger) (*sqlx.DB, error) {
 
-	db, err := sqlx.Connect(cfg.DatabaseDriver, cfg.DatabaseURL)
+	// every prepared statement, query and exec gets a span
+	conn, err := otelsql.Open(cfg.DatabaseDriver, cfg.DatabaseURL, otelsql.WithDBSystem("postgresql"))
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(conn, cfg.DatabaseDriver)
 	// conf connections
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
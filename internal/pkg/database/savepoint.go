@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSavepoint a savepoint statement failed, the transaction can not continue
+var ErrSavepoint = errors.New("failed to execute savepoint")
+
+// Savepoint runs fn in a savepoint of tx, when fn fails only its statements are rolled back and
+// the transaction can continue, the error of fn is returned as is
+func Savepoint(ctx context.Context, tx *sqlx.Tx, name string, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: %s", ErrSavepoint, err)
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w: %s", ErrSavepoint, rbErr)
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%w: %s", ErrSavepoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// csvEncoder writes a header and a record per item
+type csvEncoder struct {
+	w   *csv.Writer
+	buf []string
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) header(names []string) error {
+	e.buf = make([]string, len(names))
+	return e.w.Write(names)
+}
+
+func (e *csvEncoder) record(_ any, values []any) error {
+	for i, value := range values {
+		e.buf[i] = formatValue(value)
+	}
+	return e.w.Write(e.buf)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) close() error {
+	return e.flush()
+}
+
+// ndjsonEncoder writes the JSON of each item, the columns are not used
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	var buf = bufio.NewWriter(w)
+	return &ndjsonEncoder{w: buf, enc: json.NewEncoder(buf)}
+}
+
+func (e *ndjsonEncoder) header(_ []string) error {
+	return nil
+}
+
+func (e *ndjsonEncoder) record(item any, _ []any) error {
+	// Encode ends each object with a new line
+	return e.enc.Encode(item)
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return e.w.Flush()
+}
+
+func (e *ndjsonEncoder) close() error {
+	return e.flush()
+}
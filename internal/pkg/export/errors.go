@@ -0,0 +1,16 @@
+package export
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+// ErrNotAcceptable the Accept header does not allow CSV, NDJSON or XLSX
+var ErrNotAcceptable = errors.New("El formato solicitado no está disponible, use CSV, NDJSON o XLSX")
+
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrNotAcceptable, Code: "not_acceptable", Status: http.StatusNotAcceptable},
+	)
+}
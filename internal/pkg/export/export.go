@@ -0,0 +1,227 @@
+package export
+
+import (
+	"fmt"
+	"kiramishima/ionix/internal/models"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format media type of an exported file
+type Format string
+
+const (
+	// CSV comma separated values with a header of the column names
+	CSV Format = "text/csv"
+	// NDJSON one JSON object per line, the objects are the ones of the API
+	NDJSON Format = "application/x-ndjson"
+	// XLSX Office Open XML workbook, one sheet every 1048576 rows
+	XLSX Format = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// FlushRows rows written between two flushes of the response
+const FlushRows = 1000
+
+// formats values of the format query parameter
+var formats = map[string]Format{
+	"csv":    CSV,
+	"ndjson": NDJSON,
+	"xlsx":   XLSX,
+}
+
+// mediaTypes media types of the Accept header, the wildcards are answered with CSV
+var mediaTypes = map[string]Format{
+	string(CSV):                CSV,
+	"text/*":                   CSV,
+	"*/*":                      CSV,
+	string(NDJSON):             NDJSON,
+	"application/ndjson":       NDJSON,
+	"application/jsonl":        NDJSON,
+	string(XLSX):               XLSX,
+	"application/vnd.ms-excel": XLSX,
+}
+
+// Extension file extension of the format
+func (f Format) Extension() string {
+	for extension, format := range formats {
+		if format == f {
+			return extension
+		}
+	}
+	return "txt"
+}
+
+// Negotiate chooses the format of the export, the format query parameter takes precedence over
+// the Accept header and without both the export is CSV
+func Negotiate(req *http.Request) (Format, error) {
+	if v := req.URL.Query().Get("format"); v != "" {
+		format, ok := formats[strings.ToLower(v)]
+		if !ok {
+			return "", fmt.Errorf("%w: format", models.ErrInvalidQuery)
+		}
+		return format, nil
+	}
+
+	var accept = strings.TrimSpace(req.Header.Get("Accept"))
+	if accept == "" {
+		return CSV, nil
+	}
+	var best Format
+	var bestQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		var q = 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		// on a tie the first media type wins
+		if format, ok := mediaTypes[mediaType]; ok && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotAcceptable, accept)
+	}
+	return best, nil
+}
+
+// Column of a CSV or XLSX export, Value returns the cell of the item
+type Column[T any] struct {
+	Name  string
+	Value func(item *T) any
+}
+
+// encoder writes the rows of a format, close ends the file and flushes it
+type encoder interface {
+	header(names []string) error
+	record(item any, values []any) error
+	flush() error
+	close() error
+}
+
+// Writer streams the items to the response, the headers of the response are sent with the first
+// row so an error before it can still be answered with a problem
+type Writer[T any] struct {
+	w       http.ResponseWriter
+	format  Format
+	name    string
+	columns []Column[T]
+	enc     encoder
+	rows    int
+}
+
+// NewWriter creates a writer of the items in format, name is the name of the downloaded file
+func NewWriter[T any](w http.ResponseWriter, format Format, name string, columns []Column[T]) *Writer[T] {
+	return &Writer[T]{w: w, format: format, name: name, columns: columns}
+}
+
+// Started reports whether the response was sent
+func (w *Writer[T]) Started() bool {
+	return w.enc != nil
+}
+
+// Write writes a row of the item
+func (w *Writer[T]) Write(item *T) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	var values = make([]any, len(w.columns))
+	for i, column := range w.columns {
+		values[i] = column.Value(item)
+	}
+	if err := w.enc.record(item, values); err != nil {
+		return err
+	}
+	if w.rows++; w.rows%FlushRows == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close ends the file, an export without items has only the header
+func (w *Writer[T]) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.enc.close(); err != nil {
+		return err
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (w *Writer[T]) start() error {
+	if w.enc != nil {
+		return nil
+	}
+	switch w.format {
+	case NDJSON:
+		w.enc = newNDJSONEncoder(w.w)
+	case XLSX:
+		w.enc = newXLSXEncoder(w.w)
+	default:
+		w.enc = newCSVEncoder(w.w)
+	}
+
+	var header = w.w.Header()
+	header.Set("Content-Type", string(w.format))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": w.name + "." + w.format.Extension()}))
+	header.Add("Vary", "Accept")
+	w.w.WriteHeader(http.StatusOK)
+
+	var names = make([]string, len(w.columns))
+	for i, column := range w.columns {
+		names[i] = column.Name
+	}
+	return w.enc.header(names)
+}
+
+func (w *Writer[T]) flush() error {
+	if err := w.enc.flush(); err != nil {
+		return err
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// formatValue text of a cell, the dates are RFC 3339 and the texts can not be formulas
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return escapeFormula(v)
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return escapeFormula(*v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula prefixes with ' the texts that a spreadsheet would run as a formula, the names are
+// written by the users and could be =HYPERLINK(...) when the staff opens the export
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	ID       int32     `json:"id"`
+	Name     string    `json:"name"`
+	Approved bool      `json:"approved"`
+	Note     *string   `json:"note"`
+	Date     time.Time `json:"date"`
+}
+
+var testColumns = []Column[testItem]{
+	{Name: "id", Value: func(i *testItem) any { return i.ID }},
+	{Name: "name", Value: func(i *testItem) any { return i.Name }},
+	{Name: "approved", Value: func(i *testItem) any { return i.Approved }},
+	{Name: "note", Value: func(i *testItem) any { return i.Note }},
+	{Name: "date", Value: func(i *testItem) any { return i.Date }},
+}
+
+var testItems = []*testItem{
+	{ID: 1, Name: "Aspirina", Approved: true, Date: time.Date(2024, 5, 5, 13, 50, 0, 0, time.UTC)},
+	{ID: 2, Name: "Cafiaspirina, forte", Note: new(string), Date: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+}
+
+func TestNegotiate(t *testing.T) {
+	testCases := map[string]struct {
+		URL    string
+		Accept string
+		Format Format
+		Err    error
+	}{
+		"Default":          {URL: "/export", Format: CSV},
+		"Wildcard":         {URL: "/export", Accept: "*/*", Format: CSV},
+		"NDJSON":           {URL: "/export", Accept: "application/x-ndjson", Format: NDJSON},
+		"Quality":          {URL: "/export", Accept: "text/csv;q=0.5, application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Format: XLSX},
+		"Parameter":        {URL: "/export?format=XLSX", Accept: "text/csv", Format: XLSX},
+		"Invalid format":   {URL: "/export?format=pdf", Err: models.ErrInvalidQuery},
+		"Not acceptable":   {URL: "/export", Accept: "application/json", Err: ErrNotAcceptable},
+		"Rejected formats": {URL: "/export", Accept: "text/csv;q=0", Err: ErrNotAcceptable},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.URL, nil)
+			if tc.Accept != "" {
+				req.Header.Set("Accept", tc.Accept)
+			}
+			format, err := Negotiate(req)
+			if tc.Err != nil {
+				assert.ErrorIs(t, err, tc.Err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Format, format)
+		})
+	}
+}
+
+func write(t *testing.T, format Format, items []*testItem) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	writer := NewWriter(recorder, format, "items", testColumns)
+	assert.False(t, writer.Started())
+	for _, item := range items {
+		assert.NoError(t, writer.Write(item))
+		assert.True(t, writer.Started())
+	}
+	assert.NoError(t, writer.Close())
+	return recorder
+}
+
+func TestWriterCSV(t *testing.T) {
+	recorder := write(t, CSV, testItems)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=items.csv`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+	assert.Equal(t, "id,name,approved,note,date\n"+
+		"1,Aspirina,true,,2024-05-05T13:50:00Z\n"+
+		"2,\"Cafiaspirina, forte\",false,,2024-05-06T00:00:00Z\n", recorder.Body.String())
+
+	// an export without items has the header
+	recorder = write(t, CSV, nil)
+	assert.Equal(t, "id,name,approved,note,date\n", recorder.Body.String())
+}
+
+func TestWriterFormulas(t *testing.T) {
+	var names = []string{`=HYPERLINK("http://evil","x")`, "+1", "-1", "@SUM(A1)", "\tA", "\rA", "Aspirina-forte"}
+	var items = make([]*testItem, len(names))
+	for i, name := range names {
+		items[i] = &testItem{ID: -int32(i), Name: name, Note: &names[i]}
+	}
+
+	records, err := csv.NewReader(write(t, CSV, items).Body).ReadAll()
+	assert.NoError(t, err)
+	for i, name := range []string{`'=HYPERLINK("http://evil","x")`, "'+1", "'-1", "'@SUM(A1)", "'\tA", "'\rA", "Aspirina-forte"} {
+		// the numbers are not texts, a negative number is kept
+		assert.Equal(t, strconv.Itoa(-i), records[i+1][0])
+		assert.Equal(t, name, records[i+1][1])
+		assert.Equal(t, name, records[i+1][3])
+	}
+
+	parts := readXLSX(t, write(t, XLSX, items[:1]).Body.Bytes())
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<t xml:space="preserve">&#39;=HYPERLINK(&#34;http://evil&#34;,&#34;x&#34;)</t>`)
+}
+
+func TestWriterNDJSON(t *testing.T) {
+	recorder := write(t, NDJSON, testItems)
+
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"id":1,"name":"Aspirina","approved":true,"note":null,"date":"2024-05-05T13:50:00Z"}`+"\n"+
+		`{"id":2,"name":"Cafiaspirina, forte","approved":false,"note":"","date":"2024-05-06T00:00:00Z"}`+"\n", recorder.Body.String())
+}
+
+// readXLSX returns the parts of the workbook
+func readXLSX(t *testing.T, body []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	assert.NoError(t, err)
+	var parts = make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		assert.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		_ = rc.Close()
+		parts[file.Name] = string(data)
+	}
+	return parts
+}
+
+func TestWriterXLSX(t *testing.T) {
+	recorder := write(t, XLSX, []*testItem{{ID: 1, Name: "A & <B>", Approved: true, Date: time.Date(2024, 5, 5, 13, 50, 0, 0, time.UTC)}})
+
+	assert.Equal(t, `attachment; filename=items.xlsx`, recorder.Header().Get("Content-Disposition"))
+	parts := readXLSX(t, recorder.Body.Bytes())
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts, "_rels/.rels")
+	assert.Contains(t, parts, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Sheet1" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<sheetData><row><c t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<row><c><v>1</v></c><c t="inlineStr"><is><t xml:space="preserve">A &amp; &lt;B&gt;</t></is></c><c t="b"><v>1</v></c><c t="inlineStr"><is><t xml:space="preserve"></t></is></c><c t="inlineStr"><is><t xml:space="preserve">2024-05-05T13:50:00Z</t></is></c></row></sheetData></worksheet>`)
+}
+
+func TestWriterXLSXSheets(t *testing.T) {
+	defer func(rows int) { sheetRows = rows }(sheetRows)
+	sheetRows = 3
+
+	recorder := write(t, XLSX, append(append(testItems, testItems...), testItems[0]))
+
+	parts := readXLSX(t, recorder.Body.Bytes())
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Sheet3" sheetId="3" r:id="rId3"/>`)
+	assert.Contains(t, parts["[Content_Types].xml"], `PartName="/xl/worksheets/sheet3.xml"`)
+	// every sheet starts with the header
+	for _, name := range []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		assert.Contains(t, parts[name], `<sheetData><row><c t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	}
+	assert.NotContains(t, parts, "xl/worksheets/sheet4.xml")
+}
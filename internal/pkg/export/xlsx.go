@@ -0,0 +1,171 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sheetRows rows of a sheet including the header, the limit of Excel
+var sheetRows = 1 << 20
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>%s</Types>`
+	xlsxSheetContentType = `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+	xlsxRels             = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>%s</sheets></workbook>`
+	xlsxWorkbookSheet = `<sheet name="Sheet%d" sheetId="%d" r:id="rId%d"/>`
+	xlsxWorkbookRels  = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s</Relationships>`
+	xlsxWorkbookSheetRel = `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`
+	xlsxSheetStart       = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxEncoder streams the rows in the sheets of a zip, the parts that list the sheets are written
+// at the end because the number of sheets is only known then
+type xlsxEncoder struct {
+	zip    *zip.Writer
+	w      *bufio.Writer
+	names  []string
+	sheets int
+	rows   int
+}
+
+func newXLSXEncoder(w io.Writer) *xlsxEncoder {
+	return &xlsxEncoder{zip: zip.NewWriter(w)}
+}
+
+func (e *xlsxEncoder) header(names []string) error {
+	e.names = names
+	return e.newSheet()
+}
+
+// newSheet ends the current sheet and starts the next one with the header
+func (e *xlsxEncoder) newSheet() error {
+	if err := e.endSheet(); err != nil {
+		return err
+	}
+	e.sheets++
+	part, err := e.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", e.sheets))
+	if err != nil {
+		return err
+	}
+	e.w = bufio.NewWriter(part)
+	e.rows = 0
+	if _, err := e.w.WriteString(xlsxSheetStart); err != nil {
+		return err
+	}
+	var values = make([]any, len(e.names))
+	for i, name := range e.names {
+		values[i] = name
+	}
+	return e.row(values)
+}
+
+func (e *xlsxEncoder) endSheet() error {
+	if e.w == nil {
+		return nil
+	}
+	if _, err := e.w.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *xlsxEncoder) record(_ any, values []any) error {
+	if e.rows == sheetRows {
+		if err := e.newSheet(); err != nil {
+			return err
+		}
+	}
+	return e.row(values)
+}
+
+// row writes the cells without reference, they take the next column and row
+func (e *xlsxEncoder) row(values []any) error {
+	e.rows++
+	if _, err := e.w.WriteString("<row>"); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := e.cell(value); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxEncoder) cell(value any) error {
+	var err error
+	switch v := value.(type) {
+	case nil:
+		_, err = e.w.WriteString("<c/>")
+	case bool:
+		var b = "0"
+		if v {
+			b = "1"
+		}
+		_, err = e.w.WriteString(`<c t="b"><v>` + b + `</v></c>`)
+	case int:
+		_, err = e.w.WriteString("<c><v>" + strconv.Itoa(v) + "</v></c>")
+	case int32:
+		_, err = e.w.WriteString("<c><v>" + strconv.FormatInt(int64(v), 10) + "</v></c>")
+	case int64:
+		_, err = e.w.WriteString("<c><v>" + strconv.FormatInt(v, 10) + "</v></c>")
+	default:
+		if _, err = e.w.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err = xml.EscapeText(e.w, []byte(formatValue(v))); err != nil {
+			return err
+		}
+		_, err = e.w.WriteString("</t></is></c>")
+	}
+	return err
+}
+
+func (e *xlsxEncoder) flush() error {
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Flush()
+}
+
+func (e *xlsxEncoder) close() error {
+	if err := e.endSheet(); err != nil {
+		return err
+	}
+
+	var overrides, sheets, rels strings.Builder
+	for i := 1; i <= e.sheets; i++ {
+		fmt.Fprintf(&overrides, xlsxSheetContentType, i)
+		fmt.Fprintf(&sheets, xlsxWorkbookSheet, i, i, i)
+		fmt.Fprintf(&rels, xlsxWorkbookSheetRel, i, i)
+	}
+	var parts = []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, overrides.String())},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, rels.String())},
+	}
+	for _, part := range parts {
+		w, err := e.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+	return e.zip.Close()
+}
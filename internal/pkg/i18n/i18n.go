@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"context"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
+	"golang.org/x/text/language"
+	"net/http"
+)
+
+const (
+	Spanish = "es"
+	English = "en"
+	// DefaultLanguage language of the responses when Accept-Language has no supported language
+	DefaultLanguage = Spanish
+)
+
+var (
+	universal = ut.New(es.New(), es.New(), en.New())
+	// the first language is the default of the matcher
+	matcher = language.NewMatcher([]language.Tag{language.Spanish, language.English})
+)
+
+func init() {
+	for lang, catalog := range messages {
+		trans := Translator(lang)
+		for key, text := range catalog {
+			if err := trans.Add(key, text, false); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// Negotiate supported language that best matches the Accept-Language header
+func Negotiate(acceptLanguage string) string {
+	tag, _ := language.MatchStrings(matcher, acceptLanguage)
+	base, _ := tag.Base()
+	if _, ok := messages[base.String()]; !ok {
+		return DefaultLanguage
+	}
+	return base.String()
+}
+
+// Translator of a supported language, the default language for the others
+func Translator(lang string) ut.Translator {
+	trans, _ := universal.GetTranslator(lang)
+	return trans
+}
+
+type ctxKey struct{}
+
+// Middleware negotiates the language of the response from the Accept-Language header
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var lang = Negotiate(req.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKey{}, Translator(lang))))
+	})
+}
+
+// FromContext translator of the request, the default language when the middleware did not run
+func FromContext(ctx context.Context) ut.Translator {
+	if trans, ok := ctx.Value(ctxKey{}).(ut.Translator); ok {
+		return trans
+	}
+	return Translator(DefaultLanguage)
+}
+
+// T message of the catalog in the language of the request, the key when the message does not exist
+func T(ctx context.Context, key string, params ...string) string {
+	msg, err := FromContext(ctx).T(key, params...)
+	if err != nil {
+		return key
+	}
+	return msg
+}
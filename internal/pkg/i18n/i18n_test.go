@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	assert.Equal(t, Spanish, Negotiate(""))
+	assert.Equal(t, Spanish, Negotiate("es-MX,es;q=0.9"))
+	assert.Equal(t, English, Negotiate("en-US,en;q=0.9,es;q=0.8"))
+	assert.Equal(t, English, Negotiate("fr-FR,en;q=0.5"))
+	assert.Equal(t, Spanish, Negotiate("fr-FR"))
+	assert.Equal(t, Spanish, Negotiate("not a language"))
+}
+
+func TestCatalogs(t *testing.T) {
+	// every message needs its translation
+	for key := range messages[Spanish] {
+		assert.Contains(t, messages[English], key)
+	}
+	for key := range messages[English] {
+		assert.Contains(t, messages[Spanish], key)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var message string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		message = T(req.Context(), "drug.deleted")
+	}))
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/v1/drugs/1", nil)
+	req.Header.Set("Accept-Language", "en")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, English, recorder.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", recorder.Header().Get("Vary"))
+	assert.Equal(t, "The drug was deleted successfully", message)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/v1/drugs/1", nil))
+	assert.Equal(t, Spanish, recorder.Header().Get("Content-Language"))
+	assert.Equal(t, "Se ha eliminado el medicamento de manera exitosa", message)
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "La dosis debe estar entre 1 y 3", T(context.Background(), "validation.dose_range", "1", "3"))
+	assert.Equal(t, "unknown.key", T(context.Background(), "unknown.key"))
+}
+
+func TestRegisterValidator(t *testing.T) {
+	var v = validator.New()
+	assert.NoError(t, RegisterValidator(v))
+
+	var form = struct {
+		Name      string `validate:"required"`
+		AppliedAt string `validate:"datetime=2006-01-02"`
+	}{AppliedAt: "today"}
+	var ve validator.ValidationErrors
+	assert.True(t, errors.As(v.Struct(form), &ve))
+
+	assert.Equal(t, "Name es un campo requerido", ve[0].Translate(Translator(Spanish)))
+	assert.Equal(t, "Name is a required field", ve[0].Translate(Translator(English)))
+	assert.Equal(t, "AppliedAt no cumple con el formato 2006-01-02", ve[1].Translate(Translator(Spanish)))
+	assert.Equal(t, "AppliedAt does not match the 2006-01-02 format", ve[1].Translate(Translator(English)))
+}
@@ -0,0 +1,278 @@
+package i18n
+
+// messages catalog of the API by language, the problems use the keys problem.{code} for the title and
+// problem.{code}.detail for the detail, the field errors use validation.{rule}
+var messages = map[string]map[string]string{
+	Spanish: {
+		// auth
+		"auth.signed_up":                  "Registro exitoso.",
+		"auth.logged_out":                 "La sesión se ha cerrado de manera exitosa",
+		"auth.role_updated":               "Se ha actualizado el rol del usuario de manera exitosa",
+		"auth.password_reset_requested":   "Si la cuenta existe se le ha enviado un correo para restablecer la contraseña",
+		"auth.password_reset":             "La contraseña se ha restablecido de manera exitosa",
+		"auth.email_verified":             "El email se ha verificado de manera exitosa",
+		"auth.verification_sent":          "Si la cuenta existe y no ha sido verificada se le ha enviado un nuevo correo de verificación",
+		"auth.user_unlocked":              "Se han borrado los intentos fallidos de inicio de sesión del usuario",
+		"auth.mfa_enabled":                "La verificación en dos pasos se ha activado de manera exitosa",
+		"auth.mfa_disabled":               "La verificación en dos pasos se ha desactivado de manera exitosa",
+		"auth.mfa_reset":                  "Se ha quitado la verificación en dos pasos del usuario",
+		"auth.role_mfa_updated":           "Se ha actualizado la verificación en dos pasos del rol",
+		"mail.password_reset.subject":     "Restablecer contraseña",
+		"mail.password_reset.body":        "Recibimos una solicitud para restablecer la contraseña de su cuenta.\n\nAbra el siguiente enlace para elegir una nueva contraseña, el enlace expira en {0} minutos:\n\n{1}\n\nSi no solicitó el cambio puede ignorar este correo.",
+		"mail.email_verification.subject": "Verifique su email",
+		"mail.email_verification.body":    "Abra el siguiente enlace para verificar el email de su cuenta, el enlace expira en {0} minutos:\n\n{1}\n\nSi no creó una cuenta puede ignorar este correo.",
+		// drugs
+		"drug.created":          "Se ha registrado el nuevo medicamento de manera exitosa",
+		"drug.updated":          "Se ha actualizado la información del medicamento de manera exitosa",
+		"drug.deleted":          "Se ha eliminado el medicamento de manera exitosa",
+		"drug.schedule_updated": "Se ha actualizado el esquema de dosis del medicamento de manera exitosa",
+		// patients
+		"patient.created": "Se ha registrado el paciente de manera exitosa",
+		"patient.updated": "Se ha actualizado la información del paciente de manera exitosa",
+		"patient.deleted": "Se ha eliminado el paciente de manera exitosa",
+		// vaccinations
+		"vaccination.created": "Se ha registrado de manera exitosa",
+		"vaccination.updated": "Se ha actualizado la información de manera exitosa",
+		"vaccination.deleted": "Se ha eliminado el registro de manera exitosa",
+		// validation rules of the API
+		"validation.datetime":           "La fecha no cumple con el formato {0}",
+		"validation.drug_not_approved":  "El medicamento no está aprobado",
+		"validation.dose_range":         "La dosis debe estar entre {0} y {1}",
+		"validation.drug_not_available": "El medicamento no está disponible antes de {0}",
+		"validation.series_completed":   "La serie de {0} dosis ya está completa",
+		"validation.dose_too_early":     "La dosis {0} no puede aplicarse antes de {1}",
+		"validation.dose_too_late":      "La dosis {0} no puede aplicarse después de {1}",
+		"validation.gtefield":           "El valor no puede ser menor que {0}",
+		"validation.password_min":       "La contraseña debe tener al menos {0} caracteres",
+		"validation.password_max":       "La contraseña no puede tener más de {0} caracteres",
+		"validation.password_breached":  "La contraseña aparece en una lista de contraseñas filtradas, elija otra",
+		// problems
+		"problem.internal_error":                   "Error interno",
+		"problem.internal_error.detail":            "Ocurrió un error interno. Por favor intente más tarde",
+		"problem.validation_failed":                "Los datos de la petición son invalidos",
+		"problem.timeout":                          "Tiempo de espera agotado",
+		"problem.timeout.detail":                   "El tiempo para procesar su petición ha excedido",
+		"problem.not_found":                        "Recurso no encontrado",
+		"problem.not_found.detail":                 "El recurso no existe",
+		"problem.method_not_allowed":               "Método no permitido",
+		"problem.method_not_allowed.detail":        "El método no está permitido para este recurso",
+		"problem.unauthorized":                     "No autenticado",
+		"problem.unauthorized.detail":              "Se requiere un token de acceso valido",
+		"problem.forbidden":                        "Acceso denegado",
+		"problem.forbidden.detail":                 "No tiene permiso para realizar esta acción",
+		"problem.invalid_query":                    "Parámetros de consulta invalidos",
+		"problem.invalid_query.detail":             "Los parámetros de consulta son invalidos",
+		"problem.invalid_body":                     "Cuerpo de la petición invalido",
+		"problem.invalid_body.detail":              "El cuerpo de la petición es invalido",
+		"problem.invalid_id":                       "Identificador invalido",
+		"problem.invalid_id.detail":                "El identificador es invalido",
+		"problem.invalid_time_zone":                "Zona horaria invalida",
+		"problem.invalid_time_zone.detail":         "La zona horaria es invalida",
+		"problem.precondition_required":            "Precondición requerida",
+		"problem.precondition_required.detail":     "Se requiere el encabezado If-Match",
+		"problem.precondition_failed":              "Precondición fallida",
+		"problem.precondition_failed.detail":       "El encabezado If-Match es invalido",
+		"problem.unsupported_media_type":           "Tipo de contenido no soportado",
+		"problem.unsupported_media_type.detail":    "El tipo de contenido no es un documento de parche soportado",
+		"problem.invalid_patch":                    "Parche invalido",
+		"problem.invalid_patch.detail":             "El documento de parche es invalido",
+		"problem.patch_test_failed":                "Prueba del parche fallida",
+		"problem.patch_test_failed.detail":         "La operación test del parche falló",
+		"problem.unsupported_import_format":        "Formato de importación no soportado",
+		"problem.unsupported_import_format.detail": "El archivo debe ser CSV o NDJSON",
+		"problem.invalid_import_file":              "Archivo de importación invalido",
+		"problem.invalid_import_file.detail":       "El archivo de importación no se pudo leer",
+		"problem.not_acceptable":                   "Formato no disponible",
+		"problem.not_acceptable.detail":            "El formato solicitado no está disponible, use CSV, NDJSON o XLSX",
+		"problem.token_revoked":                    "Token revocado",
+		"problem.token_revoked.detail":             "El token ha sido revocado",
+		"problem.invalid_credentials":              "Credenciales invalidas",
+		"problem.invalid_credentials.detail":       "El email y/o contraseña son erroneos",
+		"problem.user_not_found":                   "Usuario no encontrado",
+		"problem.user_not_found.detail":            "Usuario no existe",
+		"problem.user_exists":                      "La cuenta ya existe",
+		"problem.user_exists.detail":               "Ya existe una cuenta con esta email",
+		"problem.invalid_role":                     "Rol invalido",
+		"problem.invalid_role.detail":              "El rol es invalido",
+		"problem.invalid_refresh_token":            "Refresh token invalido",
+		"problem.invalid_refresh_token.detail":     "El refresh token es invalido",
+		"problem.refresh_token_expired":            "Refresh token expirado",
+		"problem.refresh_token_expired.detail":     "El refresh token ha expirado",
+		"problem.refresh_token_reused":             "Refresh token reutilizado",
+		"problem.refresh_token_reused.detail":      "El refresh token ya fue utilizado, la sesión ha sido revocada",
+		"problem.invalid_token":                    "Token invalido",
+		"problem.invalid_token.detail":             "El token es invalido o ya fue utilizado",
+		"problem.token_expired":                    "Token expirado",
+		"problem.token_expired.detail":             "El token ha expirado, solicite uno nuevo",
+		"problem.email_not_verified":               "Email no verificado",
+		"problem.email_not_verified.detail":        "El email de la cuenta no ha sido verificado",
+		"problem.too_many_attempts":                "Demasiados intentos",
+		"problem.too_many_attempts.detail":         "Demasiados intentos fallidos de inicio de sesión, intente más tarde",
+		"problem.invalid_mfa_code":                 "Código invalido",
+		"problem.invalid_mfa_code.detail":          "El código de verificación es invalido o ya fue utilizado",
+		"problem.mfa_already_enabled":              "Verificación en dos pasos activada",
+		"problem.mfa_already_enabled.detail":       "La verificación en dos pasos ya está activada, desactívela para registrar otro dispositivo",
+		"problem.mfa_not_enrolled":                 "Verificación en dos pasos no activada",
+		"problem.mfa_not_enrolled.detail":          "El usuario no tiene la verificación en dos pasos activada",
+		"problem.mfa_required":                     "Verificación en dos pasos requerida",
+		"problem.mfa_required.detail":              "El rol del usuario requiere la verificación en dos pasos",
+		"problem.drug_not_found":                   "Medicamento no encontrado",
+		"problem.drug_not_found.detail":            "No existe el medicamento",
+		"problem.drug_exists":                      "El medicamento ya existe",
+		"problem.drug_exists.detail":               "Este medicamento ya existe",
+		"problem.drug_schedule_not_found":          "Esquema de dosis no encontrado",
+		"problem.drug_schedule_not_found.detail":   "El medicamento no tiene un esquema de dosis",
+		"problem.drug_modified":                    "Medicamento modificado",
+		"problem.drug_modified.detail":             "El medicamento fue modificado por otra petición",
+		"problem.patient_not_found":                "Paciente no encontrado",
+		"problem.patient_not_found.detail":         "Paciente no encontrado",
+		"problem.patient_exists":                   "El paciente ya existe",
+		"problem.patient_exists.detail":            "Ya existe un paciente con este documento",
+		"problem.vaccination_not_found":            "Vacunación no encontrada",
+		"problem.vaccination_not_found.detail":     "Vacunación no encontrada",
+		"problem.vaccination_exists":               "La vacunación ya existe",
+		"problem.vaccination_exists.detail":        "Registro existente",
+		"problem.vaccination_modified":             "Vacunación modificada",
+		"problem.vaccination_modified.detail":      "La vacunación fue modificada por otra petición",
+		"problem.unknown_patient":                  "El paciente de la vacunación no existe",
+		"problem.unknown_patient.detail":           "Paciente no encontrado",
+		"problem.unknown_drug":                     "El medicamento de la vacunación no existe",
+		"problem.unknown_drug.detail":              "No existe el medicamento",
+	},
+	English: {
+		// auth
+		"auth.signed_up":                  "Sign up successful.",
+		"auth.logged_out":                 "The session was closed successfully",
+		"auth.role_updated":               "The role of the user was updated successfully",
+		"auth.password_reset_requested":   "If the account exists a mail to reset the password was sent",
+		"auth.password_reset":             "The password was reset successfully",
+		"auth.email_verified":             "The email was verified successfully",
+		"auth.verification_sent":          "If the account exists and is not verified a new verification mail was sent",
+		"auth.user_unlocked":              "The failed sign ins of the user were removed",
+		"auth.mfa_enabled":                "The two-step verification was enabled",
+		"auth.mfa_disabled":               "The two-step verification was disabled",
+		"auth.mfa_reset":                  "The two-step verification of the user was removed",
+		"auth.role_mfa_updated":           "The two-step verification of the role was updated",
+		"mail.password_reset.subject":     "Reset your password",
+		"mail.password_reset.body":        "We received a request to reset the password of your account.\n\nOpen the following link to choose a new password, the link expires in {0} minutes:\n\n{1}\n\nIf you did not request the change you can ignore this mail.",
+		"mail.email_verification.subject": "Verify your email",
+		"mail.email_verification.body":    "Open the following link to verify the email of your account, the link expires in {0} minutes:\n\n{1}\n\nIf you did not create an account you can ignore this mail.",
+		// drugs
+		"drug.created":          "The new drug was registered successfully",
+		"drug.updated":          "The drug was updated successfully",
+		"drug.deleted":          "The drug was deleted successfully",
+		"drug.schedule_updated": "The dose schedule of the drug was updated successfully",
+		// patients
+		"patient.created": "The patient was registered successfully",
+		"patient.updated": "The patient was updated successfully",
+		"patient.deleted": "The patient was deleted successfully",
+		// vaccinations
+		"vaccination.created": "The vaccination was registered successfully",
+		"vaccination.updated": "The vaccination was updated successfully",
+		"vaccination.deleted": "The vaccination was deleted successfully",
+		// validation rules of the API
+		"validation.datetime":           "The date does not match the {0} format",
+		"validation.drug_not_approved":  "The drug is not approved",
+		"validation.dose_range":         "The dose must be between {0} and {1}",
+		"validation.drug_not_available": "The drug is not available before {0}",
+		"validation.series_completed":   "The series of {0} doses is already completed",
+		"validation.dose_too_early":     "The dose {0} can not be applied before {1}",
+		"validation.dose_too_late":      "The dose {0} can not be applied after {1}",
+		"validation.gtefield":           "The value can not be less than {0}",
+		"validation.password_min":       "The password needs at least {0} characters",
+		"validation.password_max":       "The password can have at most {0} characters",
+		"validation.password_breached":  "The password appears in a list of breached passwords, choose another one",
+		// problems
+		"problem.internal_error":                   "Internal error",
+		"problem.internal_error.detail":            "An internal error occurred. Please try again later",
+		"problem.validation_failed":                "The request data is invalid",
+		"problem.timeout":                          "Timeout",
+		"problem.timeout.detail":                   "The time to process the request was exceeded",
+		"problem.not_found":                        "Resource not found",
+		"problem.not_found.detail":                 "The resource does not exist",
+		"problem.method_not_allowed":               "Method not allowed",
+		"problem.method_not_allowed.detail":        "The method is not allowed for this resource",
+		"problem.unauthorized":                     "Unauthorized",
+		"problem.unauthorized.detail":              "A valid access token is required",
+		"problem.forbidden":                        "Forbidden",
+		"problem.forbidden.detail":                 "You do not have permission to perform this action",
+		"problem.invalid_query":                    "Invalid query parameters",
+		"problem.invalid_query.detail":             "The query parameters are invalid",
+		"problem.invalid_body":                     "Invalid request body",
+		"problem.invalid_body.detail":              "The request body is invalid",
+		"problem.invalid_id":                       "Invalid identifier",
+		"problem.invalid_id.detail":                "The identifier is invalid",
+		"problem.invalid_time_zone":                "Invalid time zone",
+		"problem.invalid_time_zone.detail":         "The time zone is invalid",
+		"problem.precondition_required":            "Precondition required",
+		"problem.precondition_required.detail":     "The If-Match header is required",
+		"problem.precondition_failed":              "Precondition failed",
+		"problem.precondition_failed.detail":       "The If-Match header is invalid",
+		"problem.unsupported_media_type":           "Unsupported media type",
+		"problem.unsupported_media_type.detail":    "The content type is not a supported patch document",
+		"problem.invalid_patch":                    "Invalid patch",
+		"problem.invalid_patch.detail":             "The patch document is invalid",
+		"problem.patch_test_failed":                "Patch test failed",
+		"problem.patch_test_failed.detail":         "A test operation of the patch failed",
+		"problem.unsupported_import_format":        "Unsupported import format",
+		"problem.unsupported_import_format.detail": "The file must be CSV or NDJSON",
+		"problem.invalid_import_file":              "Invalid import file",
+		"problem.invalid_import_file.detail":       "The import file could not be read",
+		"problem.not_acceptable":                   "Not acceptable",
+		"problem.not_acceptable.detail":            "The requested format is not available, use CSV, NDJSON or XLSX",
+		"problem.token_revoked":                    "Token revoked",
+		"problem.token_revoked.detail":             "The token was revoked",
+		"problem.invalid_credentials":              "Invalid credentials",
+		"problem.invalid_credentials.detail":       "The email and/or password are wrong",
+		"problem.user_not_found":                   "User not found",
+		"problem.user_not_found.detail":            "The user does not exist",
+		"problem.user_exists":                      "The account already exists",
+		"problem.user_exists.detail":               "An account with this email already exists",
+		"problem.invalid_role":                     "Invalid role",
+		"problem.invalid_role.detail":              "The role is invalid",
+		"problem.invalid_refresh_token":            "Invalid refresh token",
+		"problem.invalid_refresh_token.detail":     "The refresh token is invalid",
+		"problem.refresh_token_expired":            "Refresh token expired",
+		"problem.refresh_token_expired.detail":     "The refresh token has expired",
+		"problem.refresh_token_reused":             "Refresh token reused",
+		"problem.refresh_token_reused.detail":      "The refresh token was already used, the session was revoked",
+		"problem.invalid_token":                    "Invalid token",
+		"problem.invalid_token.detail":             "The token is invalid or was already used",
+		"problem.token_expired":                    "Expired token",
+		"problem.token_expired.detail":             "The token has expired, request a new one",
+		"problem.email_not_verified":               "Email not verified",
+		"problem.email_not_verified.detail":        "The email of the account was not verified",
+		"problem.too_many_attempts":                "Too many attempts",
+		"problem.too_many_attempts.detail":         "Too many failed sign ins, try again later",
+		"problem.invalid_mfa_code":                 "Invalid code",
+		"problem.invalid_mfa_code.detail":          "The verification code is invalid or was already used",
+		"problem.mfa_already_enabled":              "Two-step verification enabled",
+		"problem.mfa_already_enabled.detail":       "The two-step verification is already enabled, disable it to add another device",
+		"problem.mfa_not_enrolled":                 "Two-step verification not enabled",
+		"problem.mfa_not_enrolled.detail":          "The user does not have the two-step verification enabled",
+		"problem.mfa_required":                     "Two-step verification required",
+		"problem.mfa_required.detail":              "The role of the user requires the two-step verification",
+		"problem.drug_not_found":                   "Drug not found",
+		"problem.drug_not_found.detail":            "The drug does not exist",
+		"problem.drug_exists":                      "The drug already exists",
+		"problem.drug_exists.detail":               "This drug already exists",
+		"problem.drug_schedule_not_found":          "Dose schedule not found",
+		"problem.drug_schedule_not_found.detail":   "The drug has no dose schedule",
+		"problem.drug_modified":                    "Drug modified",
+		"problem.drug_modified.detail":             "The drug was modified by another request",
+		"problem.patient_not_found":                "Patient not found",
+		"problem.patient_not_found.detail":         "The patient does not exist",
+		"problem.patient_exists":                   "The patient already exists",
+		"problem.patient_exists.detail":            "A patient with this document already exists",
+		"problem.vaccination_not_found":            "Vaccination not found",
+		"problem.vaccination_not_found.detail":     "The vaccination does not exist",
+		"problem.vaccination_exists":               "The vaccination already exists",
+		"problem.vaccination_exists.detail":        "The record already exists",
+		"problem.vaccination_modified":             "Vaccination modified",
+		"problem.vaccination_modified.detail":      "The vaccination was modified by another request",
+		"problem.unknown_patient":                  "The patient of the vaccination does not exist",
+		"problem.unknown_patient.detail":           "The patient does not exist",
+		"problem.unknown_drug":                     "The drug of the vaccination does not exist",
+		"problem.unknown_drug.detail":              "The drug does not exist",
+	},
+}
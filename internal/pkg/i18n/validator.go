@@ -0,0 +1,54 @@
+package i18n
+
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	esTranslations "github.com/go-playground/validator/v10/translations/es"
+	"go.uber.org/fx"
+)
+
+// RegisterValidator registers the messages of the validator rules in every language
+func RegisterValidator(v *validator.Validate) error {
+	if err := enTranslations.RegisterDefaultTranslations(v, Translator(English)); err != nil {
+		return err
+	}
+	if err := esTranslations.RegisterDefaultTranslations(v, Translator(Spanish)); err != nil {
+		return err
+	}
+
+	// rules without spanish message in the validator and the rules of the API
+	var custom = []struct {
+		lang, tag, text string
+	}{
+		{Spanish, "datetime", "{0} no cumple con el formato {1}"},
+		{Spanish, "password", "{0} debe tener al menos una mayúscula, una minúscula y un número"},
+		{English, "password", "{0} must have an uppercase letter, a lowercase letter and a digit"},
+	}
+	for _, c := range custom {
+		err := v.RegisterTranslation(c.tag, Translator(c.lang),
+			func(trans ut.Translator) error {
+				return trans.Add(c.tag, c.text, false)
+			},
+			translateField,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateField message of the rule with the field and the parameter of the rule
+func translateField(trans ut.Translator, fe validator.FieldError) string {
+	msg, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
+
+// Module translates the validator messages
+var Module = fx.Module("i18n",
+	fx.Invoke(RegisterValidator),
+)
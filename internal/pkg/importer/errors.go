@@ -0,0 +1,21 @@
+package importer
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+var (
+	// ErrUnsupportedFormat the Content-Type is not CSV or NDJSON
+	ErrUnsupportedFormat = errors.New("El archivo debe ser CSV o NDJSON")
+	// ErrInvalidFile the file can not be read, the errors of a single row are reported in the row
+	ErrInvalidFile = errors.New("El archivo es invalido")
+)
+
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrUnsupportedFormat, Code: "unsupported_import_format", Status: http.StatusUnsupportedMediaType},
+		problem.Entry{Err: ErrInvalidFile, Code: "invalid_import_file", Status: http.StatusBadRequest},
+	)
+}
@@ -0,0 +1,243 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/problem"
+	httpUtils "kiramishima/ionix/internal/pkg/utils"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Format media type of an imported file
+type Format string
+
+const (
+	// CSV comma separated values with a header of the JSON field names
+	CSV Format = "text/csv"
+	// NDJSON one JSON object per line
+	NDJSON Format = "application/x-ndjson"
+)
+
+const (
+	// MaxBytes size limit of an imported file
+	MaxBytes = 10 << 20
+	// MaxRows rows limit of an imported file
+	MaxRows = 5000
+)
+
+// ParseFormat reads the format of a Content-Type header
+func ParseFormat(contentType string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+	}
+	switch mediaType {
+	case string(CSV):
+		return CSV, nil
+	case string(NDJSON), "application/ndjson", "application/jsonl":
+		return NDJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, mediaType)
+	}
+}
+
+// Row of an imported file, Err when the row can not be decoded
+type Row[T any] struct {
+	Line int
+	Form *T
+	Err  error
+}
+
+// Decode reads the rows of the file, each row is decoded in a new T with the rules of a JSON body
+func Decode[T any](format Format, body io.Reader) ([]Row[T], error) {
+	var rows []Row[T]
+	var err error
+	switch format {
+	case CSV:
+		rows, err = decodeCSV[T](body)
+	case NDJSON:
+		rows, err = decodeNDJSON[T](body)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, fmt.Errorf("%w: file must not be larger than %d bytes", ErrInvalidFile, maxBytesError.Limit)
+		}
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: file has no rows", ErrInvalidFile)
+	}
+	return rows, nil
+}
+
+func decodeNDJSON[T any](body io.Reader) ([]Row[T], error) {
+	var rows []Row[T]
+	var scanner = bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxBytes)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("%w: file must not have more than %d rows", ErrInvalidFile, MaxRows)
+		}
+		var form = new(T)
+		rows = append(rows, Row[T]{Line: line, Form: form, Err: httpUtils.DecodeJSON(data, form)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	return rows, nil
+}
+
+func decodeCSV[T any](body io.Reader) ([]Row[T], error) {
+	var reader = csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: file has no rows", ErrInvalidFile)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	var quoted = stringFields[T]()
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if _, ok := quoted[header[i]]; !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFile, header[i])
+		}
+	}
+
+	var rows []Row[T]
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("%w: file must not have more than %d rows", ErrInvalidFile, MaxRows)
+		}
+		if err != nil {
+			rows = append(rows, Row[T]{Line: line, Err: fmt.Errorf("%w: row has %d columns, the header %d", httpUtils.ErrInvalidBody, len(record), len(header))})
+			continue
+		}
+
+		// the row is decoded as the JSON object of its non empty cells
+		var object = make(map[string]json.RawMessage, len(record))
+		for i, cell := range record {
+			if cell = strings.TrimSpace(cell); cell == "" {
+				continue
+			}
+			if quoted[header[i]] {
+				object[header[i]], _ = json.Marshal(cell)
+			} else {
+				object[header[i]] = json.RawMessage(cell)
+			}
+		}
+		var form = new(T)
+		data, err := json.Marshal(object)
+		if err != nil {
+			// a cell of a number or boolean column is not a JSON value
+			err = fmt.Errorf("%w: %s", httpUtils.ErrInvalidBody, "row contains a value of incorrect type")
+		} else {
+			err = httpUtils.DecodeJSON(data, form)
+		}
+		rows = append(rows, Row[T]{Line: line, Form: form, Err: err})
+	}
+	return rows, nil
+}
+
+// stringFields JSON names of the fields of T, true for the fields whose JSON value is a string
+func stringFields[T any]() map[string]bool {
+	var fields = make(map[string]bool)
+	var t = reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		var ft = t.Field(i).Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// the dates are structs decoded from strings
+		fields[name] = ft.Kind() == reflect.String || ft.Kind() == reflect.Struct
+	}
+	return fields
+}
+
+// SaveFunc saves the valid rows and returns the error of each row, nil for the saved rows
+type SaveFunc[T any] func(ctx context.Context, forms []*T, opts models.ImportOptions) ([]error, error)
+
+// Run validates the decoded rows, saves the valid ones and reports the result, the errors of the rows
+// are described with the code and detail of their problem
+func Run[T any](req *http.Request, rows []Row[T], opts models.ImportOptions, validate func(*T) error, save SaveFunc[T]) (*models.ImportReport, error) {
+	var report = &models.ImportReport{DryRun: opts.DryRun, Mode: opts.Mode, Total: len(rows), Errors: make([]models.ImportRowError, 0)}
+	var rowErrs = make(map[int]error)
+	var forms = make([]*T, 0, len(rows))
+	var lines = make([]int, 0, len(rows))
+
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validate(row.Form)
+		}
+		if row.Err != nil {
+			rowErrs[row.Line] = row.Err
+			continue
+		}
+		forms = append(forms, row.Form)
+		lines = append(lines, row.Line)
+	}
+
+	// the valid rows of an atomic import with invalid rows are only checked against the database
+	var saveOpts = opts
+	if opts.Mode == models.ImportAtomic && len(rowErrs) > 0 {
+		saveOpts.DryRun = true
+	}
+	var dbFailed int
+	if len(forms) > 0 {
+		errs, err := save(req.Context(), forms, saveOpts)
+		if err != nil {
+			return nil, err
+		}
+		for i, err := range errs {
+			if err != nil {
+				rowErrs[lines[i]] = err
+				dbFailed++
+			}
+		}
+		report.Committed = saveOpts.Commit(dbFailed)
+	}
+
+	for _, row := range rows {
+		err, ok := rowErrs[row.Line]
+		if !ok {
+			continue
+		}
+		p := problem.New(req, err)
+		report.Errors = append(report.Errors, models.ImportRowError{Row: row.Line, Code: p.Code, Detail: p.Detail, Errors: p.Errors})
+	}
+	report.Failed = len(report.Errors)
+	report.Succeeded = report.Total - report.Failed
+
+	return report, nil
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"kiramishima/ionix/internal/models"
+	httpUtils "kiramishima/ionix/internal/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testForm struct {
+	Name *string `json:"name"`
+	Dose *int    `json:"dose"`
+}
+
+var errDuplicate = errors.New("duplicate")
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("text/csv; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, CSV, format)
+
+	format, err = ParseFormat("application/ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, NDJSON, format)
+
+	_, err = ParseFormat("application/json")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestDecodeCSV(t *testing.T) {
+	const file = "name, dose\n" +
+		"Aspirina,1\n" +
+		"\"Cafiaspirina, forte\",\n" +
+		"Paracetamol,uno\n" +
+		"Ibuprofeno\n" +
+		"Naproxeno,2,3\n"
+
+	rows, err := Decode[testForm](CSV, strings.NewReader(file))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 5)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, "Aspirina", *rows[0].Form.Name)
+	assert.Equal(t, 1, *rows[0].Form.Dose)
+
+	// the empty cells are omitted
+	assert.NoError(t, rows[1].Err)
+	assert.Equal(t, "Cafiaspirina, forte", *rows[1].Form.Name)
+	assert.Nil(t, rows[1].Form.Dose)
+
+	assert.Equal(t, 4, rows[2].Line)
+	assert.ErrorIs(t, rows[2].Err, httpUtils.ErrInvalidBody)
+	assert.ErrorIs(t, rows[3].Err, httpUtils.ErrInvalidBody)
+	assert.ErrorIs(t, rows[4].Err, httpUtils.ErrInvalidBody)
+	assert.Equal(t, 6, rows[4].Line)
+
+	_, err = Decode[testForm](CSV, strings.NewReader("name,color\nAspirina,red\n"))
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	_, err = Decode[testForm](CSV, strings.NewReader("name,dose\n"))
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	const file = `{"name":"Aspirina","dose":1}` + "\n\n" +
+		`{"name":"Cafiaspirina","color":"red"}` + "\n" +
+		`{"name":`
+
+	rows, err := Decode[testForm](NDJSON, strings.NewReader(file))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, "Aspirina", *rows[0].Form.Name)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.ErrorIs(t, rows[1].Err, httpUtils.ErrInvalidBody)
+	assert.ErrorIs(t, rows[2].Err, httpUtils.ErrInvalidBody)
+
+	_, err = Decode[testForm](NDJSON, strings.NewReader("\n"))
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestRun(t *testing.T) {
+	var rows, err = Decode[testForm](NDJSON, strings.NewReader(`{"name":"Aspirina","dose":1}
+{"name":"Ibuprofeno"}
+{"name":"Aspirina","dose":1}
+{"name":"Paracetamol","dose":2}`))
+	assert.NoError(t, err)
+
+	var validate = func(form *testForm) error {
+		if form.Dose == nil {
+			return models.ValidationErrors{models.NewFieldError("dose", "required", "This field is required")}
+		}
+		return nil
+	}
+	var saved models.ImportOptions
+	var save = func(_ context.Context, forms []*testForm, opts models.ImportOptions) ([]error, error) {
+		saved = opts
+		assert.Len(t, forms, 3)
+		return []error{nil, errDuplicate, nil}, nil
+	}
+	var req = httptest.NewRequest(http.MethodPost, "/v1/drugs:import", nil)
+
+	t.Run("Best effort", func(t *testing.T) {
+		report, err := Run(req, rows, models.ImportOptions{Mode: models.ImportBestEffort}, validate, save)
+		assert.NoError(t, err)
+		assert.False(t, saved.DryRun)
+		assert.True(t, report.Committed)
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Equal(t, 2, report.Failed)
+		assert.Equal(t, models.ImportRowError{Row: 2, Code: "validation_failed", Detail: "dose: This field is required", Errors: models.ValidationErrors{models.NewFieldError("dose", "required", "This field is required")}}, report.Errors[0])
+		// the errors without problem entry are internal errors
+		assert.Equal(t, models.ImportRowError{Row: 3, Code: "internal_error"}, report.Errors[1])
+	})
+
+	t.Run("Atomic with invalid rows only checks the valid ones", func(t *testing.T) {
+		report, err := Run(req, rows, models.ImportOptions{Mode: models.ImportAtomic}, validate, save)
+		assert.NoError(t, err)
+		assert.True(t, saved.DryRun)
+		assert.False(t, report.Committed)
+		assert.Equal(t, 2, report.Failed)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		report, err := Run(req, rows, models.ImportOptions{DryRun: true, Mode: models.ImportBestEffort}, validate, save)
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.False(t, report.Committed)
+	})
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"kiramishima/ionix/internal/models"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each mail as an .eml file of the directory, for local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a mailer that writes in dir
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(_ context.Context, mail *models.Mail) error {
+	var now = time.Now()
+	msg, err := message(m.from, mail, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+
+	var suffix = make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	var name = fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	// the mails carry tokens, only the owner can read them
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o600)
+}
@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"time"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+var (
+	// ErrUnknownDriver MAILER_DRIVER is not smtp, file or memory
+	ErrUnknownDriver = errors.New("unknown mailer driver")
+	// ErrInvalidAddress the sender or the recipient is not an email address
+	ErrInvalidAddress = errors.New("invalid mail address")
+)
+
+// New creates the mailer of the driver in the configuration
+func New(cfg *models.Configuration, logger *zap.Logger) (interfaces.Mailer, error) {
+	logger.Info("Mailer", zap.String("driver", cfg.MailerDriver))
+	switch cfg.MailerDriver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.Mailer), nil
+	case DriverFile:
+		return NewFileMailer(cfg.MailerDir, cfg.MailerFrom), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.MailerDriver)
+	}
+}
+
+// Module provides the mailer used by the auth flows
+var Module = fx.Module("mailer",
+	fx.Provide(New),
+)
+
+// message RFC 5322 message of the mail, the body is quoted-printable UTF-8 text
+func message(from string, m *models.Mail, date time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, from)
+	}
+	if _, err := mail.ParseAddress(m.To); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, m.To)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	var body = quotedprintable.NewWriter(&buf)
+	if _, err := body.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,139 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/models"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMail = &models.Mail{To: "jhonwick@gmail.com", Subject: "Restablecer contraseña", Body: "Abra el enlace http://localhost:8080/reset-password?token=abc"}
+
+func TestMessage(t *testing.T) {
+	msg, err := message("no-reply@ionix.local", testMail, time.Date(2024, 5, 5, 13, 50, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "From: no-reply@ionix.local\r\n"+
+		"To: jhonwick@gmail.com\r\n"+
+		"Subject: =?utf-8?q?Restablecer_contrase=C3=B1a?=\r\n"+
+		"Date: Sun, 05 May 2024 13:50:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\n"+
+		"Abra el enlace http://localhost:8080/reset-password?token=3Dabc", string(msg))
+
+	// a recipient with new lines could add headers
+	_, err = message("no-reply@ionix.local", &models.Mail{To: "jhonwick@gmail.com\r\nBcc: all@gmail.com"}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestNew(t *testing.T) {
+	var cfg = &models.Configuration{Mailer: models.Mailer{MailerDriver: DriverMemory}}
+	m, err := New(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryMailer{}, m)
+
+	cfg.MailerDriver = DriverFile
+	m, err = New(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	assert.IsType(t, &FileMailer{}, m)
+
+	cfg.MailerDriver = DriverSMTP
+	m, err = New(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	assert.IsType(t, &SMTPMailer{}, m)
+
+	cfg.MailerDriver = "pigeon"
+	_, err = New(cfg, zap.NewNop())
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	assert.NoError(t, m.Send(context.Background(), testMail))
+	assert.Equal(t, []models.Mail{*testMail}, m.Mails())
+}
+
+func TestFileMailer(t *testing.T) {
+	var dir = filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir, "no-reply@ionix.local")
+	assert.NoError(t, m.Send(context.Background(), testMail))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: jhonwick@gmail.com\r\n")
+	info, err := os.Stat(files[0])
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+// serveSMTP answers a single SMTP session without extensions and returns the received commands and data
+func serveSMTP(listener net.Listener) <-chan []string {
+	var received = make(chan []string, 1)
+	go func() {
+		var lines []string
+		defer func() { received <- lines }()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var reader = bufio.NewReader(conn)
+		var reply = func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var data bool
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case data && line == ".":
+				data = false
+				reply("250 OK")
+			case data:
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case line == "DATA":
+				data = true
+				reply("354 Go ahead")
+			case line == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	received := serveSMTP(listener)
+
+	var port = listener.Addr().(*net.TCPAddr).Port
+	m := NewSMTPMailer(models.Mailer{SMTPHost: "127.0.0.1", SMTPPort: port, MailerFrom: "no-reply@ionix.local"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, m.Send(ctx, testMail))
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@ionix.local>")
+	assert.Contains(t, lines, "RCPT TO:<jhonwick@gmail.com>")
+	assert.Contains(t, lines, "To: jhonwick@gmail.com")
+	assert.Equal(t, "QUIT", lines[len(lines)-1])
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"kiramishima/ionix/internal/models"
+	"sync"
+)
+
+// MemoryMailer keeps the sent mails in memory, for the tests
+type MemoryMailer struct {
+	mu    sync.Mutex
+	mails []models.Mail
+}
+
+// NewMemoryMailer creates an empty memory mailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(_ context.Context, mail *models.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, *mail)
+	return nil
+}
+
+// Mails copy of the sent mails in order
+func (m *MemoryMailer) Mails() []models.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.Mail(nil), m.mails...)
+}
@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"kiramishima/ionix/internal/models"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer sends the mails to an SMTP server, STARTTLS is used when the server offers it and
+// the credentials are only sent over TLS
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+// NewSMTPMailer creates a mailer of the SMTP server in the configuration
+func NewSMTPMailer(cfg models.Mailer) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		host:     cfg.SMTPHost,
+		from:     cfg.MailerFrom,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail *models.Mail) error {
+	msg, err := message(m.from, mail, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	// the deadline of the request bounds the whole conversation
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		// PlainAuth refuses to send the password without TLS except to localhost
+		if err = client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(m.from); err != nil {
+		return err
+	}
+	if err = client.Rcpt(mail.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
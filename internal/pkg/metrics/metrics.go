@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "ionix"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern, method and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of the repository methods.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"repository", "method"})
+
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Errors returned by the repository methods by sentinel error.",
+	}, []string{"repository", "method", "error"})
+
+	// VaccinationsRecorded vaccinations recorded successfully
+	VaccinationsRecorded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vaccinations_recorded_total",
+		Help:      "Vaccinations recorded.",
+	})
+
+	// SignInsFailed failed sign in attempts by reason
+	SignInsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_ins_failed_total",
+		Help:      "Failed sign in attempts by reason.",
+	}, []string{"reason"})
+)
+
+// ErrorNames label of each sentinel error of a package
+type ErrorNames map[error]string
+
+// name label of err, "validation" for validation errors and "other" for unknown errors
+func (n ErrorNames) name(err error) string {
+	var ve models.ValidationErrors
+	if errors.As(err, &ve) {
+		return "validation"
+	}
+	for sentinel, name := range n {
+		if errors.Is(err, sentinel) {
+			return name
+		}
+	}
+	return "other"
+}
+
+// ObserveQuery records the duration and the error of a repository method, use it with defer
+func ObserveQuery(repository string, method string, start time.Time, err error, names ErrorNames) {
+	queryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		queryErrors.WithLabelValues(repository, method, names.name(err)).Inc()
+	}
+}
+
+// Middleware counts the requests and observes their latency by route pattern
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var start = time.Now()
+		ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+
+		next.ServeHTTP(ww, req)
+
+		// the pattern is known once the router matched the request
+		var route = "unmatched"
+		if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		var status = ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		var labels = []string{req.Method, route, strconv.Itoa(status)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Module exposes /metrics and the connection pool stats of the database
+var Module = fx.Module("metrics",
+	fx.Invoke(func(r *chi.Mux, conn *sqlx.DB) error {
+		if err := prometheus.Register(collectors.NewDBStatsCollector(conn.DB, "ionix")); err != nil {
+			return err
+		}
+		r.Handle("/metrics", promhttp.Handler())
+		return nil
+	}),
+)
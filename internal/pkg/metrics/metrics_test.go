@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/v1/drugs/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"1", "2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/drugs/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nothing", nil))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/v1/drugs/{id}", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "unmatched", "404")))
+}
+
+func TestObserveQuery(t *testing.T) {
+	var errNotFound = errors.New("not found")
+	var names = ErrorNames{errNotFound: "not_found"}
+
+	ObserveQuery("test", "Get", time.Now(), nil, names)
+	ObserveQuery("test", "Get", time.Now(), errNotFound, names)
+	ObserveQuery("test", "Get", time.Now(), models.ValidationErrors{{Field: "dose"}}, names)
+	ObserveQuery("test", "Get", time.Now(), errors.New("boom"), names)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(queryErrors.WithLabelValues("test", "Get", "not_found")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(queryErrors.WithLabelValues("test", "Get", "validation")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(queryErrors.WithLabelValues("test", "Get", "other")))
+	assert.Equal(t, 1, testutil.CollectAndCount(queryDuration, "ionix_db_query_duration_seconds"))
+}
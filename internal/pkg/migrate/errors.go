@@ -0,0 +1,13 @@
+package migrate
+
+import "errors"
+
+var (
+	ErrInvalidFileName = errors.New("invalid migration file name")
+	ErrDuplicateFile   = errors.New("duplicate migration file")
+	ErrMissingDown     = errors.New("migration without down file")
+	ErrUnknownVersion  = errors.New("unknown migration version")
+	ErrDirty           = errors.New("database is dirty, fix the last migration and force the version")
+	ErrLock            = errors.New("failed to acquire the migrations lock")
+	ErrNoChange        = errors.New("no change")
+)
@@ -0,0 +1,264 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"io/fs"
+	"kiramishima/ionix/migrations"
+)
+
+// lockID key of the postgres advisory lock taken while migrating, so replicas
+// starting at the same time run the migrations only once
+const lockID int64 = 0x696f6e6978
+
+// schema_migrations keeps a single row with the current version, the same layout
+// used by golang-migrate so databases migrated with the migrate binary keep working
+const (
+	createTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	versionQuery     = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+	truncateQuery    = `TRUNCATE schema_migrations`
+	setVersionQuery  = `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`
+	lockQuery        = `SELECT pg_advisory_lock($1)`
+	unlockQuery      = `SELECT pg_advisory_unlock($1)`
+)
+
+// Status state of a migration in the database
+type Status struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator applies the migrations to the database
+type Migrator struct {
+	db         *sqlx.DB
+	log        *zap.Logger
+	migrations []*Migration
+}
+
+// NewMigrator creates a migrator with the migrations of fsys
+func NewMigrator(db *sqlx.DB, logger *zap.Logger, fsys fs.FS) (*Migrator, error) {
+	list, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, log: logger, migrations: list}, nil
+}
+
+// Up applies all the pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(current uint) (uint, error) {
+		if len(m.migrations) == 0 {
+			return current, nil
+		}
+		return m.Latest(), nil
+	})
+}
+
+// Down reverts the last steps migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.run(ctx, func(current uint) (uint, error) {
+		var index = m.indexOf(current)
+		if index < 0 {
+			return current, nil
+		}
+		if index-steps < 0 {
+			return 0, nil
+		}
+		return m.migrations[index-steps].Version, nil
+	})
+}
+
+// Goto applies or reverts the migrations until the schema is at version, 0 reverts all of them
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.run(ctx, func(uint) (uint, error) {
+		return version, nil
+	})
+}
+
+// Status lists the migrations and whether they are applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, uint, error) {
+	if _, err := m.db.ExecContext(ctx, createTableQuery); err != nil {
+		return nil, 0, err
+	}
+	current, dirty, err := version(ctx, m.db)
+	if err != nil {
+		return nil, 0, err
+	}
+	if dirty {
+		return nil, current, ErrDirty
+	}
+
+	var list = make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		list = append(list, Status{Version: migration.Version, Name: migration.Name, Applied: migration.Version <= current})
+	}
+	return list, current, nil
+}
+
+// Version current version of the schema and whether the last migration failed
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	return version(ctx, m.db)
+}
+
+// Latest version of the embedded migrations, the one expected by this build
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// run takes the lock and moves the schema from the current version to the one returned by target
+func (m *Migrator) run(ctx context.Context, target func(current uint) (uint, error)) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(conn *sqlx.Conn) {
+		err := conn.Close()
+		if err != nil {
+			m.log.Error("failed to close connection", zap.Error(err))
+		}
+	}(conn)
+
+	// advisory locks belong to the session, lock and unlock must use the same connection
+	if _, err = conn.ExecContext(ctx, lockQuery, lockID); err != nil {
+		m.log.Error(err.Error())
+		return ErrLock
+	}
+	defer func(conn *sqlx.Conn) {
+		if _, err := conn.ExecContext(context.Background(), unlockQuery, lockID); err != nil {
+			m.log.Error("failed to release the migrations lock", zap.Error(err))
+		}
+	}(conn)
+
+	if _, err = conn.ExecContext(ctx, createTableQuery); err != nil {
+		return err
+	}
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty
+	}
+
+	to, err := target(current)
+	if err != nil {
+		return err
+	}
+	if to == current {
+		return ErrNoChange
+	}
+
+	for _, step := range m.plan(current, to) {
+		if err = m.apply(ctx, conn, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type step struct {
+	migration *Migration
+	up        bool
+	version   uint
+}
+
+// plan steps to go from the current version to the target version
+func (m *Migrator) plan(current uint, to uint) []step {
+	var steps []step
+	if to > current {
+		for _, migration := range m.migrations {
+			if migration.Version > current && migration.Version <= to {
+				steps = append(steps, step{migration: migration, up: true, version: migration.Version})
+			}
+		}
+		return steps
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		var migration = m.migrations[i]
+		if migration.Version <= current && migration.Version > to {
+			var previous uint
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			steps = append(steps, step{migration: migration, version: previous})
+		}
+	}
+	return steps
+}
+
+// apply runs a migration and stores the new version in the same transaction
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, s step) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			m.log.Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	var query, direction = s.migration.Down, "down"
+	if s.up {
+		query, direction = s.migration.Up, "up"
+	}
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("migration %d_%s.%s: %w", s.migration.Version, s.migration.Name, direction, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, truncateQuery); err != nil {
+		return err
+	}
+	if s.version > 0 {
+		if _, err = tx.ExecContext(ctx, setVersionQuery, s.version); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	m.log.Info(fmt.Sprintf("migration %d_%s %s", s.migration.Version, s.migration.Name, direction))
+	return nil
+}
+
+func (m *Migrator) indexOf(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// version current version of the schema, 0 when no migration has been applied
+func version(ctx context.Context, q sqlx.QueryerContext) (uint, bool, error) {
+	var current uint
+	var dirty bool
+	err := q.QueryRowxContext(ctx, versionQuery).Scan(&current, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return current, dirty, err
+}
+
+// Module migrator with the embedded migrations
+var Module = fx.Module("migrate",
+	fx.Provide(func(db *sqlx.DB, logger *zap.Logger) (*Migrator, error) {
+		return NewMigrator(db, logger, migrations.FS)
+	}),
+)
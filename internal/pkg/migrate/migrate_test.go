@@ -0,0 +1,143 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"kiramishima/ionix/migrations"
+	"testing"
+	"testing/fstest"
+)
+
+var testFS = fstest.MapFS{
+	"000001_create_table_users.up.sql":   {Data: []byte("CREATE TABLE users (id BIGSERIAL)")},
+	"000001_create_table_users.down.sql": {Data: []byte("DROP TABLE users")},
+	"000002_create_table_drugs.up.sql":   {Data: []byte("CREATE TABLE drugs (id BIGSERIAL)")},
+	"000002_create_table_drugs.down.sql": {Data: []byte("DROP TABLE drugs")},
+	"migrations.go":                      {Data: []byte("package migrations")},
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Embedded migrations", func(t *testing.T) {
+		list, err := Load(migrations.FS)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, list)
+		assert.Equal(t, uint(1), list[0].Version)
+		assert.Equal(t, "create_table_users", list[0].Name)
+	})
+
+	t.Run("Sorted by version", func(t *testing.T) {
+		list, err := Load(testFS)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(list))
+		assert.Equal(t, uint(2), list[1].Version)
+		assert.Equal(t, "DROP TABLE drugs", list[1].Down)
+	})
+
+	t.Run("Missing down file", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"000001_create_table_users.up.sql": {Data: []byte("CREATE TABLE users (id BIGSERIAL)")}})
+		assert.ErrorIs(t, err, ErrMissingDown)
+	})
+}
+
+func newMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	m, err := NewMigrator(sqlx.NewDb(db, "sqlmock"), zap.NewNop(), testFS)
+	assert.NoError(t, err)
+	return m, mock, func() {
+		_ = db.Close()
+	}
+}
+
+func expectLock(mock sqlmock.Sqlmock, version *uint) {
+	mock.ExpectExec(lockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createTableQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	if version == nil {
+		mock.ExpectQuery(versionQuery).WillReturnError(sql.ErrNoRows)
+	} else {
+		mock.ExpectQuery(versionQuery).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(*version, false))
+	}
+}
+
+func expectStep(mock sqlmock.Sqlmock, query string, version uint) {
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(truncateQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	if version > 0 {
+		mock.ExpectExec(setVersionQuery).WithArgs(version).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestMigrator_Up(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Applies the pending migrations", func(t *testing.T) {
+		m, mock, closeDB := newMigrator(t)
+		defer closeDB()
+
+		var current uint = 1
+		expectLock(mock, &current)
+		expectStep(mock, "CREATE TABLE drugs (id BIGSERIAL)", 2)
+		mock.ExpectExec(unlockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, m.Up(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No change", func(t *testing.T) {
+		m, mock, closeDB := newMigrator(t)
+		defer closeDB()
+
+		var current uint = 2
+		expectLock(mock, &current)
+		mock.ExpectExec(unlockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, m.Up(context.Background()), ErrNoChange)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dirty database", func(t *testing.T) {
+		m, mock, closeDB := newMigrator(t)
+		defer closeDB()
+
+		mock.ExpectExec(lockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(createTableQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(1, true))
+		mock.ExpectExec(unlockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, m.Up(context.Background()), ErrDirty)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Goto(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Reverts all the migrations", func(t *testing.T) {
+		m, mock, closeDB := newMigrator(t)
+		defer closeDB()
+
+		var current uint = 2
+		expectLock(mock, &current)
+		expectStep(mock, "DROP TABLE drugs", 1)
+		expectStep(mock, "DROP TABLE users", 0)
+		mock.ExpectExec(unlockQuery).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, m.Goto(context.Background(), 0))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		m, _, closeDB := newMigrator(t)
+		defer closeDB()
+
+		assert.ErrorIs(t, m.Goto(context.Background(), 5), ErrUnknownVersion)
+	})
+}
@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration a version of the schema with the sql to apply and revert it
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads the migrations of the root of fsys sorted by version,
+// files must be named {version}_{name}.up.sql and {version}_{name}.down.sql
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var byVersion = make(map[uint]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !fileName.MatchString(entry.Name()) {
+			continue
+		}
+		var parts = fileName.FindStringSubmatch(entry.Name())
+		version, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, entry.Name())
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		var m, ok = byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: parts[2]}
+			byVersion[uint(version)] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateFile, entry.Name())
+		}
+
+		var target = &m.Up
+		if parts[3] == "down" {
+			target = &m.Down
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateFile, entry.Name())
+		}
+		*target = string(body)
+	}
+
+	var list = make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Down == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingDown, m.Version, m.Name)
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// argon2idScheme hashes in the PHC string format $argon2id$v=19$m=65536,t=3,p=2$salt$key, the salt and
+// the key are unpadded base64 so the parameters are read from the hash to verify it
+type argon2idScheme struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// argon2idHash parts of a stored argon2id hash
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (s *argon2idScheme) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (s *argon2idScheme) hash(password string) (string, error) {
+	var salt = make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	var key = argon2.IDKey([]byte(password), salt, s.iterations, s.memory, s.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, s.memory, s.iterations, s.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (s *argon2idScheme) verify(password string, hash string) (bool, error) {
+	h, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	var key = argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func (s *argon2idScheme) current(hash string) bool {
+	h, err := parseArgon2id(hash)
+	return err == nil && h.memory == s.memory && h.iterations == s.iterations && h.parallelism == s.parallelism &&
+		len(h.salt) == argon2SaltLength && len(h.key) == argon2KeyLength
+}
+
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	// "", argon2id, v=19, m=65536,t=3,p=2, salt, key
+	var parts = strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("%w: argon2id parts", ErrInvalidHash)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("%w: argon2id version", ErrInvalidHash)
+	}
+	var h = &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, fmt.Errorf("%w: argon2id parameters", ErrInvalidHash)
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("%w: argon2id salt", ErrInvalidHash)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, fmt.Errorf("%w: argon2id key", ErrInvalidHash)
+	}
+	return h, nil
+}
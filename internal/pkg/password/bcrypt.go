@@ -0,0 +1,48 @@
+package password
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/sha3"
+	"strings"
+)
+
+// bcryptScheme bcrypt of the hex SHA3-256 of the password, the scheme of the accounts created before
+// argon2id, the SHA3 keeps the passwords longer than 72 bytes from being truncated
+type bcryptScheme struct {
+	cost int
+}
+
+func (s *bcryptScheme) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (s *bcryptScheme) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(sha3Hex(password)), s.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (s *bcryptScheme) verify(password string, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(sha3Hex(password)))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+	return true, nil
+}
+
+func (s *bcryptScheme) current(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost == s.cost
+}
+
+func sha3Hex(password string) string {
+	var sum = sha3.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	// ErrUnknownAlgorithm PASSWORD_ALGORITHM is not argon2id or bcrypt
+	ErrUnknownAlgorithm = errors.New("unknown password algorithm")
+	// ErrUnknownHash the stored hash does not belong to a scheme
+	ErrUnknownHash = errors.New("unknown password hash")
+	// ErrInvalidHash the stored hash can not be parsed
+	ErrInvalidHash = errors.New("invalid password hash")
+)
+
+// scheme a way to hash the passwords, the stored hashes tell their scheme by their prefix
+type scheme interface {
+	matches(hash string) bool
+	hash(password string) (string, error)
+	verify(password string, hash string) (bool, error)
+	// current reports whether the hash was made with the parameters of the scheme
+	current(hash string) bool
+}
+
+// Hasher hashes the new passwords with the configured scheme and verifies the hashes of every scheme
+type Hasher struct {
+	preferred scheme
+	schemes   []scheme
+}
+
+// NewHasher creates the hasher of the algorithm of the configuration
+func NewHasher(cfg models.Password) (*Hasher, error) {
+	var argon = &argon2idScheme{memory: cfg.Argon2Memory, iterations: cfg.Argon2Iterations, parallelism: cfg.Argon2Parallelism}
+	var legacy = &bcryptScheme{cost: cfg.BcryptCost}
+	var h = &Hasher{schemes: []scheme{argon, legacy}}
+
+	switch cfg.PasswordAlgorithm {
+	case AlgorithmArgon2id:
+		h.preferred = argon
+	case AlgorithmBcrypt:
+		h.preferred = legacy
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, cfg.PasswordAlgorithm)
+	}
+	return h, nil
+}
+
+// Hash hashes the password with the configured scheme
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.hash(password)
+}
+
+// Verify compares the password with the hash of any scheme
+func (h *Hasher) Verify(password string, hash string) (bool, bool, error) {
+	for _, s := range h.schemes {
+		if !s.matches(hash) {
+			continue
+		}
+		ok, err := s.verify(password, hash)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, s != h.preferred || !s.current(hash), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// Module provides the hasher and the policy of the passwords
+var Module = fx.Module("password",
+	fx.Provide(
+		func(cfg *models.Configuration, logger *zap.Logger) (interfaces.PasswordHasher, error) {
+			logger.Info("Password hasher", zap.String("algorithm", cfg.PasswordAlgorithm))
+			return NewHasher(cfg.Password)
+		},
+		func(cfg *models.Configuration, logger *zap.Logger) (interfaces.PasswordPolicy, error) {
+			policy, err := NewPolicy(cfg.Password)
+			if err != nil {
+				return nil, err
+			}
+			logger.Info("Password policy", zap.Int("min_length", cfg.PasswordMinLength), zap.Int("max_length", cfg.PasswordMaxLength), zap.Int("breached", policy.Breached()))
+			return policy, nil
+		},
+	),
+)
@@ -0,0 +1,141 @@
+package password
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"kiramishima/ionix/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig cheap argon2id parameters so the tests run fast
+var testConfig = models.Password{PasswordAlgorithm: AlgorithmArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: bcrypt.MinCost}
+
+func TestHasher_Argon2id(t *testing.T) {
+	h, err := NewHasher(testConfig)
+	assert.NoError(t, err)
+
+	hash, err := h.Hash("Secret123")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	ok, rehash, err := h.Verify("Secret123", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify("Secret124", hash)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the hashes of older parameters still verify and are upgraded
+	var stronger = testConfig
+	stronger.Argon2Iterations = 2
+	h2, err := NewHasher(stronger)
+	assert.NoError(t, err)
+	ok, rehash, err = h2.Verify("Secret123", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+}
+
+func TestHasher_Legacy(t *testing.T) {
+	h, err := NewHasher(testConfig)
+	assert.NoError(t, err)
+
+	// bcrypt(sha3-256) of the accounts created before argon2id
+	legacy, err := bcrypt.GenerateFromPassword([]byte(sha3Hex("Secret123")), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	ok, rehash, err := h.Verify("Secret123", string(legacy))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	// a wrong password is never upgraded
+	ok, rehash, err = h.Verify("secret123", string(legacy))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	// with bcrypt as the algorithm the legacy hashes are current
+	var cfg = testConfig
+	cfg.PasswordAlgorithm = AlgorithmBcrypt
+	hb, err := NewHasher(cfg)
+	assert.NoError(t, err)
+	ok, rehash, err = hb.Verify("Secret123", string(legacy))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestHasher_Errors(t *testing.T) {
+	var cfg = testConfig
+	cfg.PasswordAlgorithm = "md5"
+	_, err := NewHasher(cfg)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	h, err := NewHasher(testConfig)
+	assert.NoError(t, err)
+
+	// an empty hash never matches a password
+	_, _, err = h.Verify("Secret123", "")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	_, _, err = h.Verify("Secret123", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+	_, _, err = h.Verify("Secret123", "$2a$10$short")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestPolicy(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "breached.txt")
+	var list = "Password123\r\n" +
+		// SHA-1 of Qwerty123 with its count
+		sha1Hex("Qwerty123") + ":52\n" +
+		"\n"
+	assert.NoError(t, os.WriteFile(file, []byte(list), 0o600))
+
+	var cfg = testConfig
+	cfg.PasswordMinLength, cfg.PasswordMaxLength, cfg.PasswordBreachedFile = 10, 12, file
+	p, err := NewPolicy(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.Breached())
+
+	testCases := map[string]struct {
+		password string
+		rule     string
+	}{
+		"Valid":           {password: "Secret1234"},
+		"Multibyte runes": {password: "Contraseña1"},
+		"Too short":       {password: "Secret123", rule: "password_min"},
+		"Too long":        {password: "Secret1234567", rule: "password_max"},
+		"Breached":        {password: "Password123", rule: "password_breached"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := p.Check(tc.password)
+			if tc.rule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var ve models.ValidationErrors
+			assert.True(t, errors.As(err, &ve))
+			assert.Equal(t, tc.rule, ve[0].Rule)
+		})
+	}
+
+	// the lines with a SHA-1 match the password of the hash
+	cfg.PasswordMinLength = 8
+	p, err = NewPolicy(cfg)
+	assert.NoError(t, err)
+	var ve models.ValidationErrors
+	assert.True(t, errors.As(p.Check("Qwerty123"), &ve))
+	assert.Equal(t, "password_breached", ve[0].Rule)
+
+	cfg.PasswordBreachedFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = NewPolicy(cfg)
+	assert.Error(t, err)
+}
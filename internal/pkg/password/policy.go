@@ -0,0 +1,84 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"kiramishima/ionix/internal/models"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policy rules of the new passwords besides the characters checked by the password validation rule
+type Policy struct {
+	minLength int
+	maxLength int
+	// breached upper case hex SHA-1 of the breached passwords
+	breached map[string]struct{}
+}
+
+// NewPolicy creates the policy of the configuration, the breached passwords are read once
+func NewPolicy(cfg models.Password) (*Policy, error) {
+	var p = &Policy{minLength: cfg.PasswordMinLength, maxLength: cfg.PasswordMaxLength, breached: make(map[string]struct{})}
+	if cfg.PasswordBreachedFile == "" {
+		return p, nil
+	}
+
+	file, err := os.Open(cfg.PasswordBreachedFile)
+	if err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	defer file.Close()
+
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			p.breached[breachedKey(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	return p, nil
+}
+
+// breachedKey SHA-1 of a line of the list, the lines of 40 hex characters are already a SHA-1 and may
+// have the count after a colon like the lists of Have I Been Pwned
+func breachedKey(line string) string {
+	var hash, _, _ = strings.Cut(line, ":")
+	if _, err := hex.DecodeString(hash); err == nil && len(hash) == sha1.Size*2 {
+		return strings.ToUpper(hash)
+	}
+	return sha1Hex(line)
+}
+
+func sha1Hex(s string) string {
+	var sum = sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// Breached number of passwords of the list
+func (p *Policy) Breached() int {
+	return len(p.breached)
+}
+
+// Check validates the length of the password and that it is not in the breached list
+func (p *Policy) Check(password string) error {
+	var length = utf8.RuneCountInString(password)
+	if length < p.minLength {
+		return models.ValidationErrors{models.NewFieldError("password", "password_min",
+			fmt.Sprintf("The password needs at least %d characters", p.minLength), strconv.Itoa(p.minLength))}
+	}
+	if p.maxLength > 0 && length > p.maxLength {
+		return models.ValidationErrors{models.NewFieldError("password", "password_max",
+			fmt.Sprintf("The password can have at most %d characters", p.maxLength), strconv.Itoa(p.maxLength))}
+	}
+	if _, ok := p.breached[sha1Hex(password)]; ok {
+		return models.ValidationErrors{models.NewFieldError("password", "password_breached",
+			"The password appears in a list of breached passwords, choose another one")}
+	}
+	return nil
+}
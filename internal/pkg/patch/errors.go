@@ -0,0 +1,24 @@
+package patch
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+var (
+	// ErrUnsupportedMediaType the Content-Type is not a supported patch document
+	ErrUnsupportedMediaType = errors.New("El tipo de contenido no es un documento de parche soportado")
+	// ErrInvalidPatch the patch document is malformed or can not be applied
+	ErrInvalidPatch = errors.New("El documento de parche es invalido")
+	// ErrTestFailed a test operation of a JSON Patch did not match
+	ErrTestFailed = errors.New("La operación test del parche falló")
+)
+
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrUnsupportedMediaType, Code: "unsupported_media_type", Status: http.StatusUnsupportedMediaType},
+		problem.Entry{Err: ErrInvalidPatch, Code: "invalid_patch", Status: http.StatusBadRequest},
+		problem.Entry{Err: ErrTestFailed, Code: "patch_test_failed", Status: http.StatusConflict},
+	)
+}
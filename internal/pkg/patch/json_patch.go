@@ -0,0 +1,267 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// operation of a JSON Patch document
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op operation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %s without path", ErrInvalidPatch, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s without value", ErrInvalidPatch, op.Op)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: %s", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s without from", ErrInvalidPatch, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if op.Op == "move" {
+			if strings.HasPrefix(*op.Path+"/", *op.From+"/") && *op.Path != *op.From {
+				return nil, fmt.Errorf("%w: can not move %s into one of its children", ErrInvalidPatch, *op.From)
+			}
+			if doc, value, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = get(doc, from); err != nil {
+				return nil, err
+			}
+			// the copy must not share maps or slices with the source
+			if value, err = clone(value); err != nil {
+				return nil, err
+			}
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer, RFC 6901, in its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid pointer %q", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// walk calls fn with the container of the last token and replaces the container with its result
+func walk(node any, tokens []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return fn(node, tokens[0])
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, tokens[0])
+		}
+		child, err := walk(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = child
+		return n, nil
+	case []any:
+		i, err := index(tokens[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := walk(n[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: %q is not an object or array", ErrInvalidPatch, tokens[0])
+	}
+}
+
+// index parses an array index between 0 and max
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	return i, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch n := doc.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+			}
+			doc = child
+		case []any:
+			i, err := index(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array", ErrInvalidPatch, token)
+		}
+	}
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return walk(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			if token == "-" {
+				return append(c, value), nil
+			}
+			i, err := index(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array", ErrInvalidPatch, token)
+		}
+	})
+}
+
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: can not remove the document", ErrInvalidPatch)
+	}
+	var removed any
+	doc, err := walk(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			value, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []any:
+			i, err := index(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[i]
+			return append(c[:i], c[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array", ErrInvalidPatch, token)
+		}
+	})
+	return doc, removed, err
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return walk(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+			}
+			c[token] = value
+			return c, nil
+		case []any:
+			i, err := index(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array", ErrInvalidPatch, token)
+		}
+	})
+}
+
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+// equal compares two JSON values, the numbers by value so 1 and 1.0 are equal
+func equal(a any, b any) bool {
+	var values [2]any
+	for i, v := range []any{a, b} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(data, &values[i]); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(values[0], values[1])
+}
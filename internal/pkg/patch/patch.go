@@ -0,0 +1,100 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+)
+
+// Type media type of a patch document
+type Type string
+
+const (
+	// MergePatch JSON Merge Patch, RFC 7386
+	MergePatch Type = "application/merge-patch+json"
+	// JSONPatch JSON Patch, RFC 6902
+	JSONPatch Type = "application/json-patch+json"
+)
+
+// Accept value of the Accept-Patch header
+const Accept = string(MergePatch) + ", " + string(JSONPatch)
+
+// ParseType reads the patch type of a Content-Type header
+func ParseType(contentType string) (Type, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+	switch t := Type(mediaType); t {
+	case MergePatch, JSONPatch:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+}
+
+// Apply applies the patch document to the JSON document doc and returns the patched document
+func (t Type) Apply(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case MergePatch:
+		p, err := decode(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+		}
+		target = merge(target, p)
+	case JSONPatch:
+		var ops []operation
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+		}
+		for i, op := range ops {
+			if target, err = op.apply(target); err != nil {
+				return nil, fmt.Errorf("%w (operation %d)", err, i)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, t)
+	}
+
+	return json.Marshal(target)
+}
+
+// merge applies a merge patch to target, null members remove the member
+func merge(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = merge(t[key], value)
+		}
+	}
+	return t
+}
+
+// decode keeps the numbers as json.Number so the integers are not changed
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("document must only contain a single JSON value")
+	}
+	return v, nil
+}
@@ -0,0 +1,103 @@
+package patch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	typ, err := ParseType("application/merge-patch+json; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, MergePatch, typ)
+
+	typ, err = ParseType("application/json-patch+json")
+	assert.NoError(t, err)
+	assert.Equal(t, JSONPatch, typ)
+
+	_, err = ParseType("application/json")
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+	_, err = ParseType("")
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
+
+func TestMergePatch(t *testing.T) {
+	const doc = `{"name":"Aspirina","approved":true,"min_dose":1,"max_dose":5,"tags":{"a":1,"b":2}}`
+	testCases := map[string]struct {
+		patch string
+		want  string
+	}{
+		"Replace":        {`{"name":"Cafiaspirina","max_dose":4}`, `{"approved":true,"max_dose":4,"min_dose":1,"name":"Cafiaspirina","tags":{"a":1,"b":2}}`},
+		"False is a set": {`{"approved":false}`, `{"approved":false,"max_dose":5,"min_dose":1,"name":"Aspirina","tags":{"a":1,"b":2}}`},
+		"Null removes":   {`{"name":null,"tags":{"a":null}}`, `{"approved":true,"max_dose":5,"min_dose":1,"tags":{"b":2}}`},
+		"Not an object":  {`[1]`, `[1]`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := MergePatch.Apply([]byte(doc), []byte(tc.patch))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+
+	_, err := MergePatch.Apply([]byte(doc), []byte(`{"name":`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestJSONPatch(t *testing.T) {
+	const doc = `{"name":"Aspirina","min_dose":1,"doses":[1,2],"a/b":{"c~d":3}}`
+	testCases := map[string]struct {
+		patch string
+		want  string
+		err   error
+	}{
+		"Replace": {
+			patch: `[{"op":"test","path":"/min_dose","value":1.0},{"op":"replace","path":"/name","value":"Cafiaspirina"}]`,
+			want:  `{"name":"Cafiaspirina","min_dose":1,"doses":[1,2],"a/b":{"c~d":3}}`,
+		},
+		"Add and remove": {
+			patch: `[{"op":"add","path":"/doses/1","value":5},{"op":"add","path":"/doses/-","value":9},{"op":"remove","path":"/doses/0"},{"op":"add","path":"/max_dose","value":null}]`,
+			want:  `{"name":"Aspirina","min_dose":1,"doses":[5,2,9],"a/b":{"c~d":3},"max_dose":null}`,
+		},
+		"Move and copy with escaped pointers": {
+			patch: `[{"op":"move","from":"/a~1b/c~0d","path":"/max_dose"},{"op":"copy","from":"/doses","path":"/schedule"}]`,
+			want:  `{"name":"Aspirina","min_dose":1,"doses":[1,2],"a/b":{},"max_dose":3,"schedule":[1,2]}`,
+		},
+		"Test failed": {
+			patch: `[{"op":"test","path":"/name","value":"Cafiaspirina"},{"op":"remove","path":"/name"}]`,
+			err:   ErrTestFailed,
+		},
+		"Missing member": {
+			patch: `[{"op":"replace","path":"/max_dose","value":4}]`,
+			err:   ErrInvalidPatch,
+		},
+		"Index out of range": {
+			patch: `[{"op":"add","path":"/doses/3","value":4}]`,
+			err:   ErrInvalidPatch,
+		},
+		"Unknown operation": {
+			patch: `[{"op":"increment","path":"/min_dose","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		"Move into a child": {
+			patch: `[{"op":"move","from":"/a~1b","path":"/a~1b/child"}]`,
+			err:   ErrInvalidPatch,
+		},
+		"Not an array": {
+			patch: `{"op":"remove","path":"/name"}`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := JSONPatch.Apply([]byte(doc), []byte(tc.patch))
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
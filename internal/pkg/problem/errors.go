@@ -0,0 +1,28 @@
+package problem
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+)
+
+// Generic errors of the API
+var (
+	ErrInternal         = errors.New("Ocurrió un error interno. Por favor intente más tarde")
+	ErrTimeout          = errors.New("El tiempo para procesar su petición ha excedido")
+	ErrNotFound         = errors.New("El recurso no existe")
+	ErrMethodNotAllowed = errors.New("El método no está permitido para este recurso")
+	ErrUnauthorized     = errors.New("Se requiere un token de acceso valido")
+	ErrForbidden        = errors.New("No tiene permiso para realizar esta acción")
+)
+
+func init() {
+	Register(
+		Entry{Err: ErrTimeout, Code: "timeout", Status: http.StatusGatewayTimeout},
+		Entry{Err: ErrNotFound, Code: "not_found", Status: http.StatusNotFound},
+		Entry{Err: ErrMethodNotAllowed, Code: "method_not_allowed", Status: http.StatusMethodNotAllowed},
+		Entry{Err: ErrUnauthorized, Code: "unauthorized", Status: http.StatusUnauthorized},
+		Entry{Err: ErrForbidden, Code: "forbidden", Status: http.StatusForbidden},
+		Entry{Err: models.ErrInvalidQuery, Code: "invalid_query", Status: http.StatusBadRequest},
+	)
+}
@@ -0,0 +1,52 @@
+package problem
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/fx"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"net/http"
+)
+
+// NotFound problem of the routes that do not exist
+func NotFound(w http.ResponseWriter, req *http.Request) {
+	Write(w, req, ErrNotFound)
+}
+
+// MethodNotAllowed problem of the methods not supported by a route
+func MethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	Write(w, req, ErrMethodNotAllowed)
+}
+
+// catalogHandler lists the codes of the catalog, or describes one of them
+func catalogHandler(w http.ResponseWriter, req *http.Request) {
+	var entries = Translated(i18n.FromContext(req.Context()))
+	var body any = entries
+
+	if code := chi.URLParam(req, "code"); code != "" {
+		body = nil
+		for _, entry := range entries {
+			if entry.Code == code {
+				body = entry
+				break
+			}
+		}
+		if body == nil {
+			NotFound(w, req)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Module answers the unknown routes with problems and documents the codes in /problems
+var Module = fx.Module("problem",
+	fx.Invoke(func(r *chi.Mux) {
+		r.NotFound(NotFound)
+		r.MethodNotAllowed(MethodNotAllowed)
+		r.Get("/problems", catalogHandler)
+		r.Get("/problems/{code}", catalogHandler)
+	}),
+)
@@ -0,0 +1,148 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	ut "github.com/go-playground/universal-translator"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// ContentType media type of the error responses
+	ContentType = "application/problem+json"
+	// TypePrefix prefix of the type of the problems, /problems/{code} describes each code
+	TypePrefix = "/problems/"
+)
+
+// Entry code and status of the responses of a sentinel error, the title and the detail are
+// the messages problem.{code} and problem.{code}.detail of the i18n catalog, Title is only
+// used for the codes without translation
+type Entry struct {
+	Err    error  `json:"-"`
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+}
+
+var (
+	// internalError response of the errors without entry, their message is never sent
+	internalError = Entry{Err: ErrInternal, Code: "internal_error", Status: http.StatusInternalServerError}
+	// validationFailed response of models.ValidationErrors
+	validationFailed = Entry{Code: "validation_failed", Status: http.StatusUnprocessableEntity}
+)
+
+var catalog = struct {
+	sync.RWMutex
+	entries []Entry
+}{entries: []Entry{internalError, validationFailed}}
+
+// Register adds the entries of the sentinel errors of a package to the catalog, packages call it from init
+func Register(entries ...Entry) {
+	catalog.Lock()
+	defer catalog.Unlock()
+	catalog.entries = append(catalog.entries, entries...)
+}
+
+// Entries codes of the catalog without duplicates
+func Entries() []Entry {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	var seen = make(map[string]bool)
+	var out = make([]Entry, 0, len(catalog.entries))
+	for _, entry := range catalog.entries {
+		if !seen[entry.Code] {
+			seen[entry.Code] = true
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// Lookup entry of err, the first registered sentinel matched by errors.Is wins
+func Lookup(err error) Entry {
+	var ve models.ValidationErrors
+	if errors.As(err, &ve) {
+		return validationFailed
+	}
+
+	catalog.RLock()
+	defer catalog.RUnlock()
+	for _, entry := range catalog.entries {
+		if entry.Err != nil && errors.Is(err, entry.Err) {
+			return entry
+		}
+	}
+	return internalError
+}
+
+// New builds the problem of err for the request, the detail is only sent for the client errors
+func New(req *http.Request, err error) models.Problem {
+	// the request was cancelled or ran out of time, whatever the error of the service
+	if req.Context().Err() != nil && !errors.Is(err, ErrTimeout) {
+		err = ErrTimeout
+	}
+
+	var entry = Lookup(err)
+	var trans = i18n.FromContext(req.Context())
+	var p = models.Problem{
+		Type:      TypePrefix + entry.Code,
+		Title:     title(trans, entry),
+		Status:    entry.Status,
+		Instance:  req.URL.Path,
+		Code:      entry.Code,
+		RequestID: middleware.GetReqID(req.Context()),
+	}
+	if entry.Status < http.StatusInternalServerError {
+		p.Detail = detail(trans, entry, err)
+	}
+	if errors.As(err, &p.Errors) {
+		p.Errors = p.Errors.Translate(trans)
+		p.Detail = p.Errors.Error()
+	}
+
+	return p
+}
+
+// Translated entries of the catalog in the language of trans
+func Translated(trans ut.Translator) []Entry {
+	var entries = Entries()
+	for i := range entries {
+		entries[i].Title = title(trans, entries[i])
+	}
+	return entries
+}
+
+// title of the entry in the language of trans, its Title or code when it has no translation
+func title(trans ut.Translator, entry Entry) string {
+	if msg, err := trans.T("problem." + entry.Code); err == nil {
+		return msg
+	}
+	if entry.Title != "" {
+		return entry.Title
+	}
+	return entry.Code
+}
+
+// detail translates the message of the sentinel error of the entry, keeping the context
+// wrapped after it, like the name of the invalid parameter
+func detail(trans ut.Translator, entry Entry, err error) string {
+	msg, tErr := trans.T("problem." + entry.Code + ".detail")
+	if tErr != nil || entry.Err == nil || !strings.HasPrefix(err.Error(), entry.Err.Error()) {
+		return err.Error()
+	}
+	return msg + strings.TrimPrefix(err.Error(), entry.Err.Error())
+}
+
+// Write sends the problem of err
+func Write(w http.ResponseWriter, req *http.Request, err error) {
+	var p = New(req, err)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
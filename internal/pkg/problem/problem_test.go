@@ -0,0 +1,120 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var errConflict = errors.New("El registro ya existe")
+
+func init() {
+	Register(Entry{Err: errConflict, Code: "test_conflict", Status: http.StatusConflict, Title: "Conflicto"})
+}
+
+func TestLookup(t *testing.T) {
+	assert.Equal(t, "test_conflict", Lookup(errConflict).Code)
+	assert.Equal(t, "test_conflict", Lookup(fmt.Errorf("insert: %w", errConflict)).Code)
+	assert.Equal(t, "validation_failed", Lookup(models.ValidationErrors{{Field: "dose"}}).Code)
+	assert.Equal(t, "internal_error", Lookup(errors.New("connection refused")).Code)
+}
+
+func TestNew(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/drugs", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "host/abc-000001"))
+
+	p := New(req, errConflict)
+	assert.Equal(t, models.Problem{
+		Type:      "/problems/test_conflict",
+		Title:     "Conflicto",
+		Status:    http.StatusConflict,
+		Detail:    errConflict.Error(),
+		Instance:  "/v1/drugs",
+		Code:      "test_conflict",
+		RequestID: "host/abc-000001",
+	}, p)
+
+	// the message of the internal errors is never sent
+	p = New(req, errors.New("pq: password authentication failed"))
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Empty(t, p.Detail)
+
+	var ve = models.ValidationErrors{{Field: "dose", Message: "The value can not be negative"}}
+	p = New(req, fmt.Errorf("create: %w", ve))
+	assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+	assert.Equal(t, ve, p.Errors)
+}
+
+func TestNewTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/v1/drugs", nil).WithContext(ctx)
+
+	p := New(req, errors.New("service drug error"))
+	assert.Equal(t, http.StatusGatewayTimeout, p.Status)
+	assert.Equal(t, "timeout", p.Code)
+}
+
+func TestNewTranslated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/drugs", nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	i18n.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { req = r })).ServeHTTP(httptest.NewRecorder(), req)
+
+	p := New(req, fmt.Errorf("%w: sort", models.ErrInvalidQuery))
+	assert.Equal(t, "Invalid query parameters", p.Title)
+	assert.Equal(t, "The query parameters are invalid: sort", p.Detail)
+
+	p = New(req, models.ValidationErrors{models.NewFieldError("drug_id", "drug_not_approved", "El medicamento no está aprobado")})
+	assert.Equal(t, "The request data is invalid", p.Title)
+	assert.Equal(t, "The drug is not approved", p.Errors[0].Message)
+	assert.Equal(t, "drug_id: The drug is not approved", p.Detail)
+
+	// the codes without translation keep their title
+	p = New(req, errConflict)
+	assert.Equal(t, "Conflicto", p.Title)
+}
+
+func TestWrite(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Write(recorder, httptest.NewRequest(http.MethodGet, "/v1/drugs/1", nil), errConflict)
+
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"/problems/test_conflict","title":"Conflicto","status":409,"detail":"El registro ya existe","instance":"/v1/drugs/1","code":"test_conflict"}`+"\n", recorder.Body.String())
+}
+
+func TestCatalogHandler(t *testing.T) {
+	r := chi.NewRouter()
+	r.NotFound(NotFound)
+	r.Get("/problems", catalogHandler)
+	r.Get("/problems/{code}", catalogHandler)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/problems/test_conflict", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"code":"test_conflict","status":409,"title":"Conflicto"}`+"\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/problems", nil))
+	var entries []Entry
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&entries))
+	assert.Contains(t, entries, Entry{Code: "internal_error", Status: http.StatusInternalServerError, Title: "Error interno"})
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/problems/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/nothing", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+}
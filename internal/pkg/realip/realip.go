@@ -0,0 +1,99 @@
+package realip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidProxy an entry of TRUSTED_PROXIES is not an IP or a CIDR
+var ErrInvalidProxy = errors.New("invalid trusted proxy")
+
+// Parse IPs and CIDRs of the trusted proxies, like 10.0.0.0/8 or 192.168.1.10
+func Parse(proxies []string) ([]netip.Prefix, error) {
+	var trusted = make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			trusted = append(trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, proxy)
+		}
+		addr = addr.Unmap()
+		trusted = append(trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return trusted, nil
+}
+
+// Middleware replaces the RemoteAddr with the address of the client sent by the trusted proxies in
+// X-Forwarded-For or X-Real-IP, the headers of the other peers are ignored so a client can not choose
+// the address that the rate limit and the sign in lockout count
+func Middleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if ip, ok := clientIP(req, trusted); ok {
+				req.RemoteAddr = ip.String()
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// clientIP first address of X-Forwarded-For from the right that is not a trusted proxy, each proxy
+// appends the address of its peer so the addresses at the left are written by the client
+func clientIP(req *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseAddr(req.RemoteAddr)
+	if !ok || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+
+	if forwarded := req.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		var hops = strings.Split(strings.Join(forwarded, ","), ",")
+		var client = peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+			if !ok {
+				break
+			}
+			client = addr
+			if !isTrusted(addr, trusted) {
+				break
+			}
+		}
+		return client, true
+	}
+	if addr, ok := parseAddr(strings.TrimSpace(req.Header.Get("X-Real-IP"))); ok {
+		return addr, true
+	}
+	return netip.Addr{}, false
+}
+
+// parseAddr IP of an address with or without port
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package realip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	trusted, err := Parse([]string{"10.0.0.0/8", " 192.168.1.10 ", "", "::ffff:172.16.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", trusted[0].String())
+	assert.Equal(t, "192.168.1.10/32", trusted[1].String())
+	assert.Equal(t, "172.16.0.1/32", trusted[2].String())
+
+	_, err = Parse([]string{"proxy.local"})
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+}
+
+func TestMiddleware(t *testing.T) {
+	trusted, err := Parse([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		"Without proxy": {
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7:51234",
+		},
+		"Untrusted peer spoofs the headers": {
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.7:51234",
+		},
+		"Trusted proxy": {
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			expected:   "203.0.113.7",
+		},
+		"Client prepends a fake address": {
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.3"},
+			expected:   "203.0.113.7",
+		},
+		"Every hop is trusted": {
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.4, 10.0.0.3"},
+			expected:   "10.0.0.4",
+		},
+		"Invalid hop": {
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7, garbage"},
+			expected:   "10.0.0.2",
+		},
+		"X-Real-IP": {
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.7"},
+			expected:   "203.0.113.7",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var remoteAddr string
+			handler := Middleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				remoteAddr = req.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/sign-in", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.expected, remoteAddr)
+		})
+	}
+}
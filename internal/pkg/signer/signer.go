@@ -0,0 +1,282 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jwksMaxAge seconds that the clients cache the JWKS, a new key must be published that long before it
+// signs the tokens
+const jwksMaxAge = 300
+
+var (
+	// ErrNoKeys neither JWT_KEY_FILES nor JWT_PRIVATE_KEY are set
+	ErrNoKeys = errors.New("no jwt keys, set JWT_KEY_FILES or JWT_PRIVATE_KEY")
+	// ErrUnsupportedKey the key file is not a RSA, P-256, P-384, P-521 or Ed25519 private key
+	ErrUnsupportedKey = errors.New("unsupported jwt key")
+	// ErrDuplicateKey two key files have the same key
+	ErrDuplicateKey = errors.New("duplicated jwt key")
+	// ErrUnknownKey the kid or the algorithm of the token does not match any key
+	ErrUnknownKey = errors.New("unknown jwt key")
+)
+
+var _ interfaces.TokenSigner = (*Signer)(nil)
+
+// Signer signs the access tokens with the first key file, or with the HS256 secret when there are
+// no key files, and verifies the tokens of every key so the keys can be rotated
+type Signer struct {
+	// signKey key of the new tokens, a jwk.Key with kid or the HS256 secret
+	signKey interface{}
+	signAlg jwa.SignatureAlgorithm
+	// keys public keys by kid, secret verifies the tokens without kid
+	keys   map[string]jwk.Key
+	secret []byte
+	jwks   jwk.Set
+	ttl    time.Duration
+}
+
+// New loads the keys of the configuration
+func New(cfg models.JWT) (*Signer, error) {
+	var s = &Signer{
+		keys: map[string]jwk.Key{},
+		jwks: jwk.NewSet(),
+		ttl:  time.Duration(cfg.TokenTTL) * time.Second,
+	}
+	if cfg.JWTPrivateKey != "" {
+		s.secret = []byte(cfg.JWTPrivateKey)
+		s.signKey = s.secret
+		s.signAlg = jwa.HS256
+	}
+
+	var files = 0
+	for _, path := range cfg.JWTKeyFiles {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := LoadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		public, err := key.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrUnsupportedKey, path, err)
+		}
+		if _, ok := s.keys[key.KeyID()]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKey, path)
+		}
+		s.keys[key.KeyID()] = public
+		if err := s.jwks.AddKey(public); err != nil {
+			return nil, err
+		}
+		if files == 0 {
+			s.signKey = key
+			s.signAlg = key.Algorithm().(jwa.SignatureAlgorithm)
+		}
+		files++
+	}
+
+	if s.signKey == nil {
+		return nil, ErrNoKeys
+	}
+	return s, nil
+}
+
+// LoadKey reads a PEM private key, the kid is its RFC 7638 thumbprint and the algorithm follows the
+// type of the key
+func LoadKey(path string) (jwk.Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwk.ParseKey(data, jwk.WithPEM(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrUnsupportedKey, path, err)
+	}
+
+	var alg jwa.SignatureAlgorithm
+	switch k := key.(type) {
+	case jwk.RSAPrivateKey:
+		alg = jwa.RS256
+	case jwk.ECDSAPrivateKey:
+		switch k.Crv() {
+		case jwa.P256:
+			alg = jwa.ES256
+		case jwa.P384:
+			alg = jwa.ES384
+		case jwa.P521:
+			alg = jwa.ES512
+		}
+	case jwk.OKPPrivateKey:
+		if k.Crv() == jwa.Ed25519 {
+			alg = jwa.EdDSA
+		}
+	}
+	if alg == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, path)
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.KeyIDKey, base64.RawURLEncoding.EncodeToString(thumbprint)); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Algorithm of the new tokens
+func (s *Signer) Algorithm() jwa.SignatureAlgorithm {
+	return s.signAlg
+}
+
+// KeyIDs kids of the verification keys, the first one signs
+func (s *Signer) KeyIDs() []string {
+	var kids = make([]string, 0, s.jwks.Len())
+	for i := 0; i < s.jwks.Len(); i++ {
+		key, _ := s.jwks.Key(i)
+		kids = append(kids, key.KeyID())
+	}
+	return kids
+}
+
+// JWKS public keys, it is empty with the HS256 secret
+func (s *Signer) JWKS() jwk.Set {
+	return s.jwks
+}
+
+// TTL lifetime of the signed tokens
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign access token of the user, the user id goes in the subject and every token gets its own jti
+func (s *Signer) Sign(user *models.User) (string, error) {
+	jti, err := utils.NewTokenID()
+	if err != nil {
+		return "", err
+	}
+	var now = time.Now()
+	token, err := jwt.NewBuilder().
+		JwtID(jti).
+		Subject(strconv.Itoa(int(user.ID))).
+		IssuedAt(now).
+		NotBefore(now).
+		Expiration(now.Add(s.ttl)).
+		Claim("role", string(user.Role)).
+		Build()
+	if err != nil {
+		return "", err
+	}
+	signed, err := jwt.Sign(token, jwt.WithKey(s.signAlg, s.signKey))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// Verify checks the signature and the dates of the token
+func (s *Signer) Verify(tokenString string) (jwt.Token, error) {
+	token, err := jwt.Parse([]byte(tokenString), jwt.WithKeyProvider(jws.KeyProviderFunc(s.fetchKey)), jwt.WithValidate(true))
+	if err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+	return token, nil
+}
+
+// fetchKey key of the kid of the token, the algorithm must be the one of the key so a public key is
+// never used as a HS256 secret
+func (s *Signer) fetchKey(_ context.Context, sink jws.KeySink, sig *jws.Signature, _ *jws.Message) error {
+	var headers = sig.ProtectedHeaders()
+	if headers.KeyID() == "" {
+		if s.secret == nil || headers.Algorithm() != jwa.HS256 {
+			return ErrUnknownKey
+		}
+		sink.Key(jwa.HS256, s.secret)
+		return nil
+	}
+	key, ok := s.keys[headers.KeyID()]
+	if !ok || key.Algorithm() != headers.Algorithm() {
+		return ErrUnknownKey
+	}
+	sink.Key(headers.Algorithm(), key)
+	return nil
+}
+
+// Verifier stores the token of the Authorization header or of the jwt cookie and its error for
+// jwtauth.FromContext, like jwtauth.Verifier
+func (s *Signer) Verifier(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var tokenString = jwtauth.TokenFromHeader(req)
+		if tokenString == "" {
+			tokenString = jwtauth.TokenFromCookie(req)
+		}
+
+		var token jwt.Token
+		var err = jwtauth.ErrNoTokenFound
+		if tokenString != "" {
+			token, err = s.Verify(tokenString)
+		}
+		next.ServeHTTP(w, req.WithContext(jwtauth.NewContext(req.Context(), token, err)))
+	})
+}
+
+// JWKSHandler publishes the public keys so other services verify the tokens without the secret
+func (s *Signer) JWKSHandler(w http.ResponseWriter, req *http.Request) {
+	body, err := json.Marshal(s.jwks)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(jwksMaxAge))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// Module provides the token signer and publishes /.well-known/jwks.json
+var Module = fx.Module("signer",
+	fx.Provide(
+		func(cfg *models.Configuration, logger *zap.Logger) (*Signer, error) {
+			s, err := New(cfg.JWT)
+			if err != nil {
+				return nil, err
+			}
+			logger.Info("Token signer", zap.String("algorithm", s.Algorithm().String()), zap.Strings("kids", s.KeyIDs()))
+			return s, nil
+		},
+		func(s *Signer) interfaces.TokenSigner {
+			return s
+		},
+	),
+	fx.Invoke(func(r *chi.Mux, s *Signer) {
+		r.Get("/.well-known/jwks.json", s.JWKSHandler)
+	}),
+)
@@ -0,0 +1,221 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKey stores the PKCS #8 PEM of the key in the temp dir of the test
+func writeKey(t *testing.T, name string, key crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	var path = filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func rsaKey(t *testing.T) crypto.Signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return key
+}
+
+func ecKey(t *testing.T) crypto.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func edKey(t *testing.T) crypto.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestSigner_Algorithms(t *testing.T) {
+	testCases := map[string]struct {
+		key func(t *testing.T) crypto.Signer
+		alg jwa.SignatureAlgorithm
+	}{
+		"RSA":     {key: rsaKey, alg: jwa.RS256},
+		"P-256":   {key: ecKey, alg: jwa.ES256},
+		"Ed25519": {key: edKey, alg: jwa.EdDSA},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := New(models.JWT{JWTKeyFiles: []string{writeKey(t, "key.pem", tc.key(t))}, TokenTTL: 300})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.alg, s.Algorithm())
+			assert.Len(t, s.KeyIDs(), 1)
+
+			token, err := s.Sign(&models.User{ID: 7, Role: models.RoleClinician})
+			assert.NoError(t, err)
+			msg, err := jws.Parse([]byte(token))
+			assert.NoError(t, err)
+			var headers = msg.Signatures()[0].ProtectedHeaders()
+			assert.Equal(t, tc.alg, headers.Algorithm())
+			assert.Equal(t, s.KeyIDs()[0], headers.KeyID())
+
+			verified, err := s.Verify(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "7", verified.Subject())
+			role, _ := verified.Get("role")
+			assert.Equal(t, string(models.RoleClinician), role)
+			assert.NotEmpty(t, verified.JwtID())
+
+			// the JWKS only has the public key
+			key, ok := s.JWKS().LookupKeyID(headers.KeyID())
+			assert.True(t, ok)
+			body, err := json.Marshal(key)
+			assert.NoError(t, err)
+			assert.NotContains(t, string(body), `"d"`)
+		})
+	}
+}
+
+func TestSigner_Rotation(t *testing.T) {
+	var current, next, unknown = writeKey(t, "current.pem", ecKey(t)), writeKey(t, "next.pem", edKey(t)), writeKey(t, "unknown.pem", ecKey(t))
+
+	// the next key is published before it signs
+	before, err := New(models.JWT{JWTKeyFiles: []string{current, next}, TokenTTL: 300})
+	assert.NoError(t, err)
+	after, err := New(models.JWT{JWTKeyFiles: []string{next, current}, TokenTTL: 300})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, before.JWKS().Len())
+	assert.Equal(t, jwa.EdDSA, after.Algorithm())
+
+	oldToken, err := before.Sign(&models.User{ID: 1})
+	assert.NoError(t, err)
+	newToken, err := after.Sign(&models.User{ID: 1})
+	assert.NoError(t, err)
+	for _, s := range []*Signer{before, after} {
+		_, err = s.Verify(oldToken)
+		assert.NoError(t, err)
+		_, err = s.Verify(newToken)
+		assert.NoError(t, err)
+	}
+
+	other, err := New(models.JWT{JWTKeyFiles: []string{unknown}, TokenTTL: 300})
+	assert.NoError(t, err)
+	otherToken, err := other.Sign(&models.User{ID: 1})
+	assert.NoError(t, err)
+	_, err = before.Verify(otherToken)
+	assert.ErrorIs(t, err, jwtauth.ErrUnauthorized)
+
+	_, err = New(models.JWT{JWTKeyFiles: []string{current, current}})
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+}
+
+func TestSigner_HS256(t *testing.T) {
+	_, err := New(models.JWT{})
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	legacy, err := New(models.JWT{JWTPrivateKey: "Megaman", TokenTTL: 300})
+	assert.NoError(t, err)
+	assert.Equal(t, jwa.HS256, legacy.Algorithm())
+	assert.Equal(t, 0, legacy.JWKS().Len())
+	token, err := legacy.Sign(&models.User{ID: 1})
+	assert.NoError(t, err)
+
+	// with key files the secret only verifies the tokens signed before them
+	var path = writeKey(t, "key.pem", rsaKey(t))
+	s, err := New(models.JWT{JWTPrivateKey: "Megaman", JWTKeyFiles: []string{path}, TokenTTL: 300})
+	assert.NoError(t, err)
+	assert.Equal(t, jwa.RS256, s.Algorithm())
+	_, err = s.Verify(token)
+	assert.NoError(t, err)
+
+	withoutSecret, err := New(models.JWT{JWTKeyFiles: []string{path}, TokenTTL: 300})
+	assert.NoError(t, err)
+	_, err = withoutSecret.Verify(token)
+	assert.ErrorIs(t, err, jwtauth.ErrUnauthorized)
+
+	// a token signed with the public key as HS256 secret is rejected
+	key, _ := s.JWKS().Key(0)
+	var raw rsa.PublicKey
+	assert.NoError(t, key.Raw(&raw))
+	der, err := x509.MarshalPKIXPublicKey(&raw)
+	assert.NoError(t, err)
+	headers := jws.NewHeaders()
+	assert.NoError(t, headers.Set(jws.KeyIDKey, key.KeyID()))
+	forged, err := jwt.Sign(jwt.New(), jwt.WithKey(jwa.HS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), jws.WithProtectedHeaders(headers)))
+	assert.NoError(t, err)
+	_, err = s.Verify(string(forged))
+	assert.ErrorIs(t, err, jwtauth.ErrUnauthorized)
+}
+
+func TestSigner_Verifier(t *testing.T) {
+	s, err := New(models.JWT{JWTKeyFiles: []string{writeKey(t, "key.pem", ecKey(t))}, TokenTTL: 300})
+	assert.NoError(t, err)
+	token, err := s.Sign(&models.User{ID: 3, Role: models.RoleAdmin})
+	assert.NoError(t, err)
+
+	var handler = s.Verifier(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, claims, err := jwtauth.FromContext(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(claims["sub"].(string)))
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "3", recorder.Body.String())
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, jwtauth.ErrNoTokenFound.Error()+"\n", recorder.Body.String())
+}
+
+func TestSigner_JWKSHandler(t *testing.T) {
+	s, err := New(models.JWT{JWTKeyFiles: []string{writeKey(t, "a.pem", rsaKey(t)), writeKey(t, "b.pem", edKey(t))}, TokenTTL: 300})
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	s.JWKSHandler(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/jwk-set+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+	var body struct {
+		Keys []map[string]any `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Len(t, body.Keys, 2)
+	for i, key := range body.Keys {
+		assert.Equal(t, s.KeyIDs()[i], key["kid"])
+		assert.Equal(t, "sig", key["use"])
+		assert.NotContains(t, key, "d")
+	}
+	assert.Equal(t, "RS256", body.Keys[0]["alg"])
+	assert.Equal(t, "EdDSA", body.Keys[1]["alg"])
+
+	_, err = LoadKey(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
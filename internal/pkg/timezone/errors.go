@@ -0,0 +1,14 @@
+package timezone
+
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
+
+// ErrInvalidTimeZone the time zone is not an IANA time zone
+var ErrInvalidTimeZone = errors.New("La zona horaria es invalida")
+
+func init() {
+	problem.Register(problem.Entry{Err: ErrInvalidTimeZone, Code: "invalid_time_zone", Status: http.StatusBadRequest})
+}
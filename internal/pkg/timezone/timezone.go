@@ -0,0 +1,53 @@
+package timezone
+
+import (
+	"context"
+	"fmt"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+	"time"
+	// the images may not have the zoneinfo database
+	_ "time/tzdata"
+)
+
+// Header the clients choose the time zone of the dates of the response with it
+const Header = "Time-Zone"
+
+// Load IANA time zone, like America/Mexico_City or UTC
+func Load(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimeZone, name)
+	}
+	return loc, nil
+}
+
+type ctxKey struct{}
+
+// Middleware uses the time zone of the Time-Zone header, or def when the request has none
+func Middleware(def *time.Location) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var loc = def
+			if name := req.Header.Get(Header); name != "" {
+				var err error
+				if loc, err = Load(name); err != nil {
+					problem.Write(w, req, err)
+					return
+				}
+			}
+			w.Header().Set(Header, loc.String())
+			// the dates of the body, and so the ETag, depend on the time zone
+			w.Header().Add("Vary", Header)
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKey{}, loc)))
+		})
+	}
+}
+
+// FromContext time zone of the request, UTC when the middleware did not run
+func FromContext(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(ctxKey{}).(*time.Location); ok {
+		return loc
+	}
+	return time.UTC
+}
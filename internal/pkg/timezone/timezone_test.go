@@ -0,0 +1,46 @@
+package timezone
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	loc, err := Load("America/Mexico_City")
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Mexico_City", loc.String())
+
+	_, err = Load("Mars/Olympus_Mons")
+	assert.ErrorIs(t, err, ErrInvalidTimeZone)
+	_, err = Load("")
+	assert.ErrorIs(t, err, ErrInvalidTimeZone)
+}
+
+func TestMiddleware(t *testing.T) {
+	var loc *time.Location
+	handler := Middleware(time.UTC)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		loc = FromContext(req.Context())
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/drugs", nil))
+	assert.Equal(t, time.UTC, loc)
+	assert.Equal(t, "UTC", recorder.Header().Get(Header))
+
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/drugs", nil)
+	req.Header.Set(Header, "America/Mexico_City")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, "America/Mexico_City", loc.String())
+	assert.Equal(t, "America/Mexico_City", recorder.Header().Get(Header))
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v1/drugs", nil)
+	req.Header.Set(Header, "Mars/Olympus_Mons")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"code":"invalid_time_zone"`)
+}
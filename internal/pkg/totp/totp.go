@@ -0,0 +1,101 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits of a code, modulo is 10^Digits
+	Digits = 6
+	modulo = 1_000_000
+	// Period seconds that a code is valid
+	Period = 30
+	// Skew periods before and after the current one whose codes are accepted, the clocks of the phones drift
+	Skew = 1
+	// secretSize bytes of a secret, the size of a SHA-1
+	secretSize = 20
+)
+
+// ErrInvalidSecret the secret is not base32
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret random secret in base32 without padding, the format of the authenticator apps
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// URI otpauth URI of the secret, the authenticator apps read it from a QR code
+func URI(issuer string, account string, secret string) string {
+	var query = url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+	var uri = url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// Step period of the time, the codes are counted from the unix epoch
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code of the secret in the step (RFC 6238 with SHA-1)
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%modulo), nil
+}
+
+// Validate finds the step of the code around t, the caller keeps the last used step so a code
+// can not be used twice
+func Validate(secret string, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	var now = Step(t)
+	for step := now - Skew; step <= now+Skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret base32 of the key "12345678901234567890" of the test vectors of RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// the vectors of RFC 6238 have 8 digits, the codes are their last 6 digits
+	testCases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range testCases {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+
+	_, err := Code("not base32!", 1)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestValidate(t *testing.T) {
+	var now = time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, Step(now))
+	step, ok := Validate(rfcSecret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// the code of the previous period is still accepted
+	step, ok = Validate(rfcSecret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	_, ok = Validate(rfcSecret, code, now.Add(2*Period*time.Second))
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+	_, err = Code(secret, 1)
+	assert.NoError(t, err)
+
+	uri, err := url.Parse(URI("Ionix", "jhonwick@gmail.com", secret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Ionix:jhonwick@gmail.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Ionix", uri.Query().Get("issuer"))
+}
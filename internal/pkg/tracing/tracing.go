@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"io"
+	"kiramishima/ionix/internal/models"
+	"os"
+)
+
+const (
+	tracerName = "kiramishima/ionix"
+
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// NewTracerProvider creates the tracer provider of the API and registers it as the global one with the
+// W3C trace context propagator, spans are always created so the logs carry trace ids even without exporter
+func NewTracerProvider(lifecycle fx.Lifecycle, cfg *models.Configuration, logger *zap.Logger) (trace.TracerProvider, error) {
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		return nil, ErrSampleRatio
+	}
+
+	exporter, closer, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+
+	var options = []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.TracingServiceName))),
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	var provider = sdktrace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			// flushes the pending spans
+			err := provider.Shutdown(ctx)
+			if closer != nil {
+				closer.Close()
+			}
+			return err
+		},
+	})
+	logger.Info("Tracing", zap.String("exporter", cfg.TracingExporter))
+
+	return provider, nil
+}
+
+// newExporter creates the span exporter, the closer is the file of the stdout exporter
+func newExporter(cfg models.Tracing) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.TracingExporter {
+	case ExporterNone, "":
+		return nil, nil, nil
+	case ExporterOTLP:
+		var options = []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.TracingEndpoint)}
+		if cfg.TracingInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), options...)
+		return exporter, nil, err
+	case ExporterStdout:
+		if cfg.TracingFile == "" {
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+			return exporter, nil, err
+		}
+		file, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, ErrUnknownExporter
+	}
+}
+
+// Start starts a span as child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err in the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Logger adds the trace and span ids of the span in ctx to the logger
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	var sc = trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logger
+	}
+	return logger.With(zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+}
+
+// Module sets up the tracer provider before the database and the routers are created
+var Module = fx.Module("tracing",
+	fx.Provide(NewTracerProvider),
+	fx.Invoke(func(trace.TracerProvider) {}),
+)
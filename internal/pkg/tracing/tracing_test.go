@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func setup(t *testing.T) *tracetest.SpanRecorder {
+	var recorder = tracetest.NewSpanRecorder()
+	var provider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	var recorder = setup(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/v1/drugs/{id}", func(w http.ResponseWriter, req *http.Request) {
+		_, span := Start(req.Context(), "DrugService.GetDrug")
+		End(span, nil)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/drugs/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+	assert.Equal(t, "GET /v1/drugs/{id}", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	var recorder = setup(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/v1/drugs", func(w http.ResponseWriter, req *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nothing", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET unmatched", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestEnd(t *testing.T) {
+	var recorder = setup(t)
+
+	_, span := Start(context.Background(), "failed")
+	End(span, ErrUnknownExporter)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, ErrUnknownExporter.Error(), spans[0].Status().Description)
+}
+
+func TestLogger(t *testing.T) {
+	setup(t)
+	core, logs := observer.New(zap.InfoLevel)
+	var logger = zap.New(core)
+
+	Logger(context.Background(), logger).Info("without span")
+	ctx, span := Start(context.Background(), "with span")
+	Logger(ctx, logger).Info("with span")
+	span.End()
+
+	entries := logs.All()
+	assert.Empty(t, entries[0].ContextMap())
+	assert.Equal(t, span.SpanContext().TraceID().String(), entries[1].ContextMap()["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entries[1].ContextMap()["span_id"])
+}
+
+func TestNewExporter(t *testing.T) {
+	exporter, closer, err := newExporter(models.Tracing{TracingExporter: ExporterNone})
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+	assert.Nil(t, closer)
+
+	exporter, closer, err = newExporter(models.Tracing{TracingExporter: ExporterStdout, TracingFile: filepath.Join(t.TempDir(), "spans.json")})
+	assert.NoError(t, err)
+	assert.NotNil(t, exporter)
+	assert.NoError(t, closer.Close())
+
+	_, _, err = newExporter(models.Tracing{TracingExporter: "zipkin"})
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPreconditionRequired la petición que modifica un recurso no envía If-Match
+var ErrPreconditionRequired = errors.New("Se requiere el encabezado If-Match")
+
+// ErrPreconditionFailed el encabezado If-Match no es un ETag de este API
+var ErrPreconditionFailed = errors.New("El encabezado If-Match es invalido")
+
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrPreconditionRequired, Code: "precondition_required", Status: http.StatusPreconditionRequired},
+		problem.Entry{Err: ErrPreconditionFailed, Code: "precondition_failed", Status: http.StatusPreconditionFailed},
+	)
+}
+
+// ETag representa la versión de un recurso como ETag fuerte
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ContentETag ETag débil calculado con el JSON de v, para los recursos sin versión como los listados
+func ContentETag(v any) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// IfMatch lee la versión esperada del encabezado If-Match, "*" acepta cualquier versión y regresa 0
+func IfMatch(req *http.Request) (int, error) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" {
+		return 0, ErrPreconditionRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+	// los ETag débiles no pueden usarse en If-Match
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 || header != ETag(version) {
+		return 0, fmt.Errorf("%w: %s", ErrPreconditionFailed, header)
+	}
+	return version, nil
+}
+
+// NotModified agrega el ETag a la respuesta y responde 304 cuando coincide con If-None-Match,
+// en ese caso el handler no debe escribir el body
+func NotModified(w http.ResponseWriter, req *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// comparación débil, RFC 9110 sección 13.1.2
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
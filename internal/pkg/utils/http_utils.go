@@ -1,22 +1,89 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"io"
+	"kiramishima/ionix/internal/pkg/problem"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// ReadJSON el body contiene un campo que no puede ser mapeado
+// ErrInvalidBody el body de la petición no es un JSON valido para el recurso
+var ErrInvalidBody = errors.New("El cuerpo de la petición es invalido")
+
+// ErrInvalidID el parámetro id de la ruta no es un entero positivo
+var ErrInvalidID = errors.New("El identificador es invalido")
+
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrInvalidBody, Code: "invalid_body", Status: http.StatusBadRequest},
+		problem.Entry{Err: ErrInvalidID, Code: "invalid_id", Status: http.StatusBadRequest},
+	)
+}
+
+// maxBodyBytes tamaño máximo del request body, 1MB
+const maxBodyBytes = 1_048_576
+
+// Timeout cancels the context of the requests after the timeout like middleware.Timeout, except the
+// streaming exports that take as long as the table needs and the imports that have IMPORT_TIMEOUT
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	var withTimeout = middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		var limited = withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, ":export") || strings.HasSuffix(r.URL.Path, ":import") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ReadJSON decodifica el body en dst, los errores envuelven ErrInvalidBody
 func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	// usamos MaxBytesReader para limitar el tamaño del request body a 1MB
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	// usamos MaxBytesReader para limitar el tamaño del request body
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := decodeJSON(r.Body, dst); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidBody, err)
+	}
+	return nil
+}
+
+// DecodeJSON decodifica el documento data en dst con las mismas reglas que ReadJSON
+func DecodeJSON(data []byte, dst any) error {
+	if err := decodeJSON(bytes.NewReader(data), dst); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidBody, err)
+	}
+	return nil
+}
 
+// ReadBody lee el body sin decodificarlo, los errores envuelven ErrInvalidBody
+func ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		return nil, fmt.Errorf("%w: body must not be larger than %d bytes", ErrInvalidBody, maxBytesError.Limit)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBody, err)
+	case len(body) == 0:
+		return nil, fmt.Errorf("%w: body must not be empty", ErrInvalidBody)
+	}
+	return body, nil
+}
+
+// decodeJSON el body contiene un campo que no puede ser mapeado
+func decodeJSON(body io.Reader, dst any) error {
 	// Inicializamos el json.Decoder
-	dec := json.NewDecoder(r.Body)
+	dec := json.NewDecoder(body)
 	dec.DisallowUnknownFields()
 
 	// Decodificamos
@@ -65,3 +132,12 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	}
 	return nil
 }
+
+// ParseID lee el parámetro de ruta indicado como entero positivo
+func ParseID(req *http.Request, key string) (int, error) {
+	id, err := strconv.ParseInt(chi.URLParam(req, key), 10, 32)
+	if err != nil || id < 1 {
+		return 0, ErrInvalidID
+	}
+	return int(id), nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"kiramishima/ionix/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// PaginationLinks builds the self, next and prev links keeping the rest of the query string
+func PaginationLinks(req *http.Request, page, totalPages int) *models.Links {
+	pageURL := func(p int) string {
+		var u = *req.URL
+		var values = u.Query()
+		values.Set("page", strconv.Itoa(p))
+		u.RawQuery = values.Encode()
+		return u.RequestURI()
+	}
+
+	var links = &models.Links{Self: pageURL(page)}
+	if page < totalPages {
+		links.Next = pageURL(page + 1)
+	}
+	if page > 1 {
+		links.Prev = pageURL(min(page-1, max(totalPages, 1)))
+	}
+	return links
+}
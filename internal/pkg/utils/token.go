@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+	"strings"
+)
+
+// ErrTokenRevoked the jti of the access token is in the denylist
+var ErrTokenRevoked = errors.New("El token ha sido revocado")
+
+func init() {
+	problem.Register(problem.Entry{Err: ErrTokenRevoked, Code: "token_revoked", Status: http.StatusUnauthorized})
+}
+
+// NewTokenID random identifier used as jti and as refresh token family
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateToken creates an opaque token and the hash to store
+func GenerateToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// GenerateRefreshToken creates an opaque refresh token and the hash to store
+func GenerateRefreshToken() (string, string, error) {
+	return GenerateToken()
+}
+
+// GenerateRecoveryCode creates a recovery code of the second factor and the hash to store, the code
+// is split in groups of 4 characters to type it
+func GenerateRecoveryCode() (string, string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	code = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+	return code, HashRecoveryCode(code), nil
+}
+
+// HashRecoveryCode hash of the recovery code without the case and the separators
+func HashRecoveryCode(code string) string {
+	return HashToken(strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code)))
+}
+
+// HashToken sha256 of the token, tokens are never stored in plain text
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Denylist rejects the access tokens whose jti was revoked, it goes after TokenSigner.Verifier
+func Denylist(denylist interfaces.TokenDenylist) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token, _, err := jwtauth.FromContext(req.Context())
+			if err != nil || token == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			revoked, err := denylist.IsTokenRevoked(req.Context(), token.JwtID())
+			if err != nil {
+				problem.Write(w, req, err)
+				return
+			}
+			if revoked {
+				problem.Write(w, req, ErrTokenRevoked)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// Authenticator rejects with a problem the requests without a valid access token, it goes after TokenSigner.Verifier
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, _, err := jwtauth.FromContext(req.Context())
+		if err != nil || token == nil || jwt.Validate(token) != nil {
+			problem.Write(w, req, problem.ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// RoleFromContext role claim of the verified access token
+func RoleFromContext(ctx context.Context) models.Role {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return ""
+	}
+	role, _ := claims["role"].(string)
+	return models.Role(role)
+}
+
+// Authorize rejects with 403 the requests whose role does not have the permission, it goes after jwtauth.Authenticator
+func Authorize(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !RoleFromContext(req.Context()).Can(permission) {
+				problem.Write(w, req, fmt.Errorf("%w: %s", problem.ErrForbidden, permission))
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
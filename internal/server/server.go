@@ -7,17 +7,15 @@ import (
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 )
 
 const (
 	maxHeaderBytes    = 1 << 20
-	ctxTimeout        = 10
 	ReadHeaderTimeout = 3 * time.Second
 )
 
@@ -25,21 +23,30 @@ type Server struct {
 	router chi.Router
 	logger *zap.Logger
 	cfg    *models.Configuration
+	health impl.HealthService
+	server *http.Server
 }
 
-func NewServer(cfg *models.Configuration, logger *zap.Logger, r *chi.Mux) *Server {
+func NewServer(cfg *models.Configuration, logger *zap.Logger, r *chi.Mux, health impl.HealthService) *Server {
 	return &Server{
 		router: r,
 		logger: logger,
 		cfg:    cfg,
+		health: health,
 	}
 }
 
+// Run starts listening, the requests are served in the background until Shutdown
 func (s *Server) Run() error {
 	// Create a new http.Server with the specified read header timeout and handler
 	var addr = fmt.Sprintf("%s:%d", s.cfg.ServerAddress, s.cfg.Port)
 
-	server := &http.Server{
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.server = &http.Server{
 		Addr:              addr,
 		ReadHeaderTimeout: ReadHeaderTimeout,
 		MaxHeaderBytes:    maxHeaderBytes,
@@ -49,38 +56,38 @@ func (s *Server) Run() error {
 	go func() {
 		s.logger.Info(fmt.Sprintf("Server is listening on PORT: %d", s.cfg.Port))
 
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			s.logger.Fatal(fmt.Sprintf("Error starting Server: %T", err))
 		}
-
-		_ = waitForShutdown(s.logger, server)
 	}()
 	return nil
-	// graceful shutdown
-
 }
 
-// waitForShutdown graceful shutdown
-func waitForShutdown(logger *zap.Logger, server *http.Server) error {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	<-sig
+// Shutdown graceful shutdown, readiness fails from now on so no new traffic is routed to this instance.
+// The requests keep being served for ShutdownDelay while the probes notice it, then the server stops
+// accepting connections and waits for the requests in progress until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.ShuttingDown()
+	if s.server == nil {
+		return nil
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout*time.Second)
-	defer cancel()
+	select {
+	case <-time.After(s.cfg.ShutdownDelay):
+	case <-ctx.Done():
+	}
 
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Info("Server Exited Properly")
-		logger.Fatal("Failed gracefully")
-		logger.Fatal("[FATAL]", zap.Error(err))
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error("Failed gracefully", zap.Error(err))
 		return err
 	}
+	s.logger.Info("Server Exited Properly")
 	return nil
 }
 
 // Module Server Module
 var Module = fx.Module("server",
-	fx.Provide(func(cfg *models.Configuration, logger *zap.Logger, r *chi.Mux) *Server {
-		return NewServer(cfg, logger, r)
+	fx.Provide(func(cfg *models.Configuration, logger *zap.Logger, r *chi.Mux, health impl.HealthService) *Server {
+		return NewServer(cfg, logger, r, health)
 	}),
 )
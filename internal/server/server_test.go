@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+	"io"
+	"kiramishima/ionix/internal/health"
+	"kiramishima/ionix/internal/models"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type migrations struct{}
+
+func (m migrations) Version(context.Context) (uint, bool, error) {
+	return 1, false, nil
+}
+
+func (m migrations) Latest() uint {
+	return 1
+}
+
+// freePort port that nobody listens on
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var logger = zap.NewNop()
+	var svc = health.NewHealthService(sqlx.NewDb(db, "sqlmock"), migrations{}, logger, time.Second)
+
+	r := chi.NewRouter()
+	health.NewHealthHandlers(r, logger, svc, render.New())
+	var release = make(chan struct{})
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+
+	var cfg = &models.Configuration{HTTPServer: models.HTTPServer{ServerAddress: "127.0.0.1", Port: freePort(t), ShutdownDelay: 300 * time.Millisecond}}
+	var base = fmt.Sprintf("http://127.0.0.1:%d", cfg.Port)
+	s := NewServer(cfg, logger, r, svc)
+	assert.NoError(t, s.Run())
+
+	resp, err := http.Get(base + "/readyz")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a request in progress when the shutdown begins
+	var slow = make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var stopped = make(chan error, 1)
+	go func() {
+		stopped <- s.Shutdown(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// readiness fails while the server still serves
+	resp, err = http.Get(base + "/readyz")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, string(body), `{"name":"shutdown","status":"fail"`)
+
+	// the server waits for the request in progress
+	select {
+	case <-stopped:
+		t.Fatal("the server stopped before the request in progress finished")
+	case <-time.After(400 * time.Millisecond):
+	}
+	close(release)
+	assert.Equal(t, "done", <-slow)
+	assert.NoError(t, <-stopped)
+
+	_, err = http.Get(base + "/readyz")
+	assert.Error(t, err)
+}
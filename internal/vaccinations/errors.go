@@ -1,6 +1,10 @@
 package vaccinations
 
-import "errors"
+import (
+	"errors"
+	"kiramishima/ionix/internal/pkg/problem"
+	"net/http"
+)
 
 // Entity Errors
 var (
@@ -20,4 +24,21 @@ var (
 	ErrUpdatingRecord       = errors.New("Fallo al actualizar el registro")
 	ErrDeletingRecord       = errors.New("Fallo al eliminar el registro")
 	ErrInvalidRequestBody   = errors.New("El cuerpo de la petición es invalido")
+	ErrPatientNotFound      = errors.New("Paciente no encontrado")
+	ErrDrugNotFound         = errors.New("No existe el medicamento")
+	ErrDrugRules            = errors.New("La vacunación no cumple con la definición del medicamento")
+	ErrVersionConflict      = errors.New("La vacunación fue modificada por otra petición")
 )
+
+// problems returned to the clients, the errors without entry are internal errors
+func init() {
+	problem.Register(
+		problem.Entry{Err: ErrTimeout, Code: "timeout", Status: http.StatusGatewayTimeout},
+		problem.Entry{Err: ErrVaccinationNotFound, Code: "vaccination_not_found", Status: http.StatusNotFound},
+		problem.Entry{Err: ErrDuplicateVaccination, Code: "vaccination_exists", Status: http.StatusConflict},
+		problem.Entry{Err: ErrVersionConflict, Code: "vaccination_modified", Status: http.StatusPreconditionFailed},
+		problem.Entry{Err: ErrPatientNotFound, Code: "unknown_patient", Status: http.StatusUnprocessableEntity},
+		problem.Entry{Err: ErrDrugNotFound, Code: "unknown_drug", Status: http.StatusUnprocessableEntity},
+		problem.Entry{Err: ErrInvalidRequestBody, Code: "invalid_body", Status: http.StatusBadRequest},
+	)
+}
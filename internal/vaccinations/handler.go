@@ -1,26 +1,29 @@
 package vaccinations
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/export"
+	"kiramishima/ionix/internal/pkg/i18n"
+	"kiramishima/ionix/internal/pkg/importer"
+	"kiramishima/ionix/internal/pkg/patch"
+	"kiramishima/ionix/internal/pkg/problem"
+	"kiramishima/ionix/internal/pkg/timezone"
 	httpUtils "kiramishima/ionix/internal/pkg/utils"
 	"net/http"
-	"os"
-	"strconv"
+	"time"
 )
 
 var _ impl.VaccinationsHandlers = (*handler)(nil)
 
 // NewVaccionationHandlers creates an instance of vaccination handlers
-func NewVaccionationHandlers(r *chi.Mux, logger *zap.Logger, s impl.VaccinationService, render *render.Render, validate *validator.Validate) {
-	var tokenAuth = jwtauth.New("HS256", []byte(os.Getenv("JWT_PRIVATE_KEY")), nil)
-	// logger.Info("token ->", tokenAuth)
+func NewVaccionationHandlers(r *chi.Mux, logger *zap.Logger, s impl.VaccinationService, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) {
 	handler := &handler{
 		logger:   logger,
 		service:  s,
@@ -29,11 +32,30 @@ func NewVaccionationHandlers(r *chi.Mux, logger *zap.Logger, s impl.VaccinationS
 	}
 
 	r.Route("/v1/vaccination", func(r chi.Router) {
-		r.With(jwtauth.Verifier(tokenAuth)).With(jwtauth.Authenticator(tokenAuth)).Get("/", handler.ListVaccinationsHandler)
-		r.With(jwtauth.Verifier(tokenAuth)).With(jwtauth.Authenticator(tokenAuth)).Post("/", handler.CreateVaccinationHandler)
-		r.With(jwtauth.Verifier(tokenAuth)).With(jwtauth.Authenticator(tokenAuth)).Put("/{id}", handler.UpdateVaccinationHandler)
-		r.With(jwtauth.Verifier(tokenAuth)).With(jwtauth.Authenticator(tokenAuth)).Delete("/{id}", handler.DeleteVaccinationHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsRead)).Get("/", handler.ListVaccinationsHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsRead)).Get("/{id}", handler.GetVaccinationHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsWrite)).Post("/", handler.CreateVaccinationHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsWrite)).Put("/{id}", handler.UpdateVaccinationHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsWrite)).Patch("/{id}", handler.PatchVaccinationHandler)
+		r.With(signer.Verifier).With(httpUtils.Authenticator).With(httpUtils.Denylist(denylist)).With(httpUtils.Authorize(models.PermVaccinationsWrite)).Delete("/{id}", handler.DeleteVaccinationHandler)
 	})
+	// custom methods of the collection, outside of the /v1/vaccination/ sub router
+	r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermVaccinationsWrite)).
+		Post("/v1/vaccination:import", handler.ImportVaccinationsHandler)
+	r.With(signer.Verifier, httpUtils.Authenticator, httpUtils.Denylist(denylist), httpUtils.Authorize(models.PermVaccinationsRead)).
+		Get("/v1/vaccination:export", handler.ExportVaccinationsHandler)
+}
+
+// vaccinationColumns columns of the CSV and XLSX exports, the patient is flattened
+var vaccinationColumns = []export.Column[models.Vaccination]{
+	{Name: "id", Value: func(v *models.Vaccination) any { return v.ID }},
+	{Name: "patient_id", Value: func(v *models.Vaccination) any { return v.Patient.ID }},
+	{Name: "patient", Value: func(v *models.Vaccination) any { return v.Patient.Name }},
+	{Name: "document_id", Value: func(v *models.Vaccination) any { return v.Patient.DocumentID }},
+	{Name: "drug_id", Value: func(v *models.Vaccination) any { return v.DrugID }},
+	{Name: "drug", Value: func(v *models.Vaccination) any { return v.Drug }},
+	{Name: "dose", Value: func(v *models.Vaccination) any { return v.Dose }},
+	{Name: "date", Value: func(v *models.Vaccination) any { return v.AppliedAt }},
 }
 
 type handler struct {
@@ -50,25 +72,85 @@ func (h handler) ListVaccinationsHandler(w http.ResponseWriter, req *http.Reques
 	resp, err := h.service.GetListVaccinations(ctx)
 	h.logger.Info("ListVaccinationsHandler", zap.Any("resp", resp))
 	if err != nil {
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "El tiempo para procesar su petición ha excedido"})
-		default:
-			h.logger.Info(err.Error())
-			if errors.Is(err, ErrNoRecords) {
-				_ = h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Vaccination]{Data: make([]*models.Vaccination, 0)})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Error al procesar su petición"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error interno. Por favor intente más tarde"})
-			}
+		h.logger.Info(err.Error())
+		if errors.Is(err, ErrNoRecords) {
+			_ = h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Vaccination]{Data: make([]*models.Vaccination, 0)})
+			return
+		}
+		problem.Write(w, req, err)
+		return
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	for _, vaccination := range resp {
+		vaccination.In(loc)
+	}
+
+	var body = models.ResponseWrapper[[]*models.Vaccination]{Data: resp}
+	if httpUtils.NotModified(w, req, httpUtils.ContentETag(body)) {
+		return
+	}
+
+	if err := h.response.JSON(w, http.StatusOK, body); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+// ExportVaccinationsHandler streams every vaccination of the list in the negotiated format
+func (h handler) ExportVaccinationsHandler(w http.ResponseWriter, req *http.Request) {
+	format, err := export.Negotiate(req)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// the export is not bound to the write timeout of the server
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+
+	var loc = timezone.FromContext(req.Context())
+	var writer = export.NewWriter(w, format, "vaccinations", vaccinationColumns)
+	err = h.service.ExportVaccinations(req.Context(), func(vaccination *models.Vaccination) error {
+		return writer.Write(vaccination.In(loc))
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		if !writer.Started() {
+			problem.Write(w, req, err)
+			return
 		}
+		// the status was sent, the client must not take the truncated file as complete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h handler) GetVaccinationHandler(w http.ResponseWriter, req *http.Request) {
+	VacID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// context
+	ctx := req.Context()
+
+	resp, err := h.service.GetVaccination(ctx, VacID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	if httpUtils.NotModified(w, req, httpUtils.ETag(resp.Version)) {
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[[]*models.Vaccination]{Data: resp}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.Vaccination]{Data: resp.In(timezone.FromContext(req.Context()))}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error interno. Por favor intente más tarde"})
+		problem.Write(w, req, err)
 		return
 	}
 }
@@ -80,7 +162,7 @@ func (h handler) CreateVaccinationHandler(w http.ResponseWriter, req *http.Reque
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidRequestBody.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 	h.logger.Info("[INFO]", zap.Any("CreateVaccinationHandler", form))
@@ -88,7 +170,7 @@ func (h handler) CreateVaccinationHandler(w http.ResponseWriter, req *http.Reque
 	err = form.Validate(h.validate)
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: err.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 	// context
@@ -97,98 +179,208 @@ func (h handler) CreateVaccinationHandler(w http.ResponseWriter, req *http.Reque
 	err = h.service.NewVaccination(ctx, form)
 	if err != nil {
 		// h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
 
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrDuplicateVaccination) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este registro ya se había dado de alta con anterioridad"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "vaccination.created")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) ImportVaccinationsHandler(w http.ResponseWriter, req *http.Request) {
+	format, err := importer.ParseFormat(req.Header.Get("Content-Type"))
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	opts, err := models.ParseImportOptions(req.URL.Query())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	// the upload and the import are not bound to the timeouts of the server, the service has IMPORT_TIMEOUT
+	var rc = http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("[WARN]", zap.Error(err))
+	}
+
+	rows, err := importer.Decode[models.VaccinationForm](format, http.MaxBytesReader(w, req.Body, importer.MaxBytes))
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+
+	validate := func(form *models.VaccinationForm) error { return form.Validate(h.validate) }
+	report, err := importer.Run(req, rows, opts, validate, h.service.ImportVaccinations)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
+	h.logger.Info("ImportVaccinationsHandler", zap.Any("report", report))
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha registrado de manera exitosa"}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, models.ResponseWrapper[*models.ImportReport]{Data: report}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
 
 func (h handler) UpdateVaccinationHandler(w http.ResponseWriter, req *http.Request) {
-	var VacID, _ = strconv.ParseInt(chi.URLParam(req, "id"), 10, 10)
+	VacID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
 	var form = &models.VaccinationForm{}
 
-	err := httpUtils.ReadJSON(w, req, &form)
+	err = httpUtils.ReadJSON(w, req, &form)
 
 	if err != nil {
 		h.logger.Error(err.Error())
-		_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: ErrInvalidRequestBody.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 	h.logger.Info("[INFO]", zap.Any("VacID", VacID), zap.Any("form", form))
+	// PUT replaces the whole vaccination
+	err = form.Validate(h.validate)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
 
 	// context
 	ctx := req.Context()
 
-	err = h.service.UpdateVaccination(ctx, int(VacID), form)
+	err = h.service.UpdateVaccination(ctx, VacID, version, form)
 	if err != nil {
 		// h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	// with If-Match: * the new version is unknown
+	if version != 0 {
+		w.Header().Set("ETag", httpUtils.ETag(version+1))
+	}
 
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrDuplicateVaccination) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este registro ya se ha dado de alta con anterioridad"})
-			} else if errors.Is(err, ErrVaccinationNotFound) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este registro no existe"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "vaccination.updated")}); err != nil {
+		h.logger.Error("[ERROR]", zap.Error(err))
+		problem.Write(w, req, err)
+		return
+	}
+}
+
+func (h handler) PatchVaccinationHandler(w http.ResponseWriter, req *http.Request) {
+	VacID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	patchType, err := patch.ParseType(req.Header.Get("Content-Type"))
+	if err != nil {
+		w.Header().Set("Accept-Patch", patch.Accept)
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	body, err := httpUtils.ReadBody(w, req)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
+	// context
+	ctx := req.Context()
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha actualizado la información de manera exitosa"}); err != nil {
+	vaccination, err := h.service.GetVaccination(ctx, VacID)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	// the patch is applied to this version, the update fails if the vaccination changes in between
+	if version == 0 {
+		version = vaccination.Version
+	} else if version != vaccination.Version {
+		problem.Write(w, req, ErrVersionConflict)
+		return
+	}
+
+	doc, err := json.Marshal(vaccination.Form())
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	doc, err = patchType.Apply(doc, body)
+	if err != nil {
+		h.logger.Error(err.Error())
+		problem.Write(w, req, err)
+		return
+	}
+	var form = &models.VaccinationForm{}
+	if err = httpUtils.DecodeJSON(doc, form); err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	h.logger.Info("[INFO]", zap.Any("VacID", VacID), zap.Any("form", form))
+	// the patched vaccination must be valid as a whole
+	if err = form.Validate(h.validate); err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+
+	err = h.service.UpdateVaccination(ctx, VacID, version, form)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	w.Header().Set("ETag", httpUtils.ETag(version+1))
+
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "vaccination.updated")}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
 
 func (h handler) DeleteVaccinationHandler(w http.ResponseWriter, req *http.Request) {
-	var VacID, _ = strconv.ParseInt(chi.URLParam(req, "id"), 10, 10)
+	VacID, err := httpUtils.ParseID(req, "id")
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
+	version, err := httpUtils.IfMatch(req)
+	if err != nil {
+		problem.Write(w, req, err)
+		return
+	}
 	// context
 	ctx := req.Context()
 
-	err := h.service.DeleteVaccination(ctx, int(VacID))
-	if err != nil {
-		select {
-		case <-ctx.Done():
-			_ = h.response.JSON(w, http.StatusGatewayTimeout, models.ErrorResponse{ErrorMessage: "Tiempo de ejecución"})
-		default:
-			if errors.Is(err, ErrVaccinationNotFound) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Este registro no existe"})
-			} else if errors.Is(err, ErrExecuteStatement) {
-				_ = h.response.JSON(w, http.StatusBadRequest, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			} else {
-				_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: "Ocurrio un error por favor intente más tarde"})
-			}
-		}
+	err = h.service.DeleteVaccination(ctx, VacID, version)
+	if err != nil {
+		problem.Write(w, req, err)
 		return
 	}
 
-	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: "Se ha eliminado el registro de manera exitosa"}); err != nil {
+	if err := h.response.JSON(w, http.StatusOK, models.Message{Message: i18n.T(req.Context(), "vaccination.deleted")}); err != nil {
 		h.logger.Error("[ERROR]", zap.Error(err))
-		_ = h.response.JSON(w, http.StatusInternalServerError, models.ErrorResponse{ErrorMessage: InternalServerError.Error()})
+		problem.Write(w, req, err)
 		return
 	}
 }
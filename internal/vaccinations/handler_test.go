@@ -1,27 +1,28 @@
 package vaccinations
 
 import (
+	"context"
 	"fmt"
 	"kiramishima/ionix/internal/mocks"
 	"kiramishima/ionix/internal/models"
-	"kiramishima/ionix/internal/pkg/utils"
+	"kiramishima/ionix/internal/pkg/signer"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/unrolled/render"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 )
 
-func TestHandler_ListDrugsHandler(t *testing.T) {
-	t.Setenv("JWT_PRIVATE_KEY", "Megaman")
-	t.Setenv("TOKEN_TTL", "3600")
+// testSigner signs the access tokens of the requests
+var testSigner, _ = signer.New(models.JWT{JWTPrivateKey: "Megaman", TokenTTL: 3600})
 
+func TestHandler_ListDrugsHandler(t *testing.T) {
 	testCases := map[string]struct {
 		ID            any
 		buildStubs    func(uc *mocks.MockVaccinationService)
@@ -33,7 +34,7 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 				var data = []*models.Vaccination{
 					{
 						ID:        1,
-						Name:      "Jhon Wick",
+						Patient:   models.PatientSummary{ID: 1, Name: "Jhon Wick"},
 						Drug:      "medicament 1",
 						DrugID:    1,
 						Dose:      1,
@@ -41,7 +42,7 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 					},
 					{
 						ID:        2,
-						Name:      "Jhon Connor",
+						Patient:   models.PatientSummary{ID: 2, Name: "Jhon Connor"},
 						Drug:      "medicament 1",
 						DrugID:    1,
 						Dose:      1,
@@ -88,7 +89,7 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 			url := "/v1/vaccination"
 
 			// Generate Token
-			token, _ := utils.GenerateJWT(&models.User{ID: 2})
+			token, _ := testSigner.Sign(&models.User{ID: 2})
 			jwtToken := fmt.Sprintf("Bearer %s", token)
 			t.Log("JWT -> ", jwtToken)
 			h := http.Header{}
@@ -103,10 +104,10 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 
 			router := chi.NewRouter()
 			logger := zap.NewNop()
-			validate := validator.New()
+			validate := models.NewValidator()
 			r := render.New()
 
-			NewVaccionationHandlers(router, logger, uc, r, validate)
+			NewVaccionationHandlers(router, logger, uc, r, validate, mocks.NewMockTokenDenylist(ctrl), testSigner)
 
 			// router.ServeHTTP(recorder, request)
 			ts := httptest.NewServer(router)
@@ -116,3 +117,42 @@ func TestHandler_ListDrugsHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_PatchVaccinationHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	uc := mocks.NewMockVaccinationService(ctrl)
+	uc.EXPECT().
+		GetVaccination(gomock.Any(), 1).
+		Times(1).
+		Return(&models.Vaccination{ID: 1, Patient: models.PatientSummary{ID: 2}, DrugID: 3, Dose: 1, AppliedAt: time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), Version: 1}, nil)
+	uc.EXPECT().
+		UpdateVaccination(gomock.Any(), 1, 1, gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, _ int, _ int, form *models.VaccinationForm) error {
+			assert.Equal(t, 2, *form.PatientID)
+			assert.Equal(t, 3, *form.DrugID)
+			assert.Equal(t, 2, *form.Dose)
+			assert.Equal(t, time.Date(2024, 6, 5, 9, 30, 0, 0, time.UTC), form.AppliedAt.Time.UTC())
+			return nil
+		})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPatch, "/v1/vaccination/1", strings.NewReader(`{"dose":2,"applied_at":"2024-06-05T09:30:00Z"}`))
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+	request.Header.Set("If-Match", `"1"`)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+	h := handler{
+		logger:   zap.NewNop(),
+		service:  uc,
+		response: render.New(),
+		validate: models.NewValidator(),
+	}
+	h.PatchVaccinationHandler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+}
@@ -0,0 +1,90 @@
+package vaccinations
+
+import (
+	"context"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/metrics"
+	"time"
+)
+
+const repositoryName = "vaccinations"
+
+// errorNames labels of the sentinel errors in the metrics
+var errorNames = metrics.ErrorNames{
+	ErrTimeout:              "timeout",
+	ErrPrepapareQuery:       "prepare_query",
+	ErrVaccinationNotFound:  "vaccination_not_found",
+	ErrExecuteStatement:     "execute_statement",
+	ErrBeginTransaction:     "begin_transaction",
+	ErrDuplicateVaccination: "duplicate_vaccination",
+	ErrCommitTransaction:    "commit_transaction",
+	ErrInsertFailed:         "insert_failed",
+	ErrUpdatingRecord:       "updating_record",
+	ErrDeletingRecord:       "deleting_record",
+	ErrPatientNotFound:      "patient_not_found",
+	ErrDrugNotFound:         "drug_not_found",
+	ErrDrugRules:            "drug_rules",
+	ErrVersionConflict:      "version_conflict",
+}
+
+var _ interfaces.VaccinationRepository = (*instrumentedRepository)(nil)
+
+// instrumentedRepository records the duration and errors of each method of the repository
+type instrumentedRepository struct {
+	next interfaces.VaccinationRepository
+}
+
+// NewInstrumentedVaccinationRepository wraps a vaccination repository with metrics
+func NewInstrumentedVaccinationRepository(next interfaces.VaccinationRepository) *instrumentedRepository {
+	return &instrumentedRepository{next: next}
+}
+
+func (r instrumentedRepository) GetVaccinationsData(ctx context.Context) ([]*models.Vaccination, error) {
+	var start = time.Now()
+	list, err := r.next.GetVaccinationsData(ctx)
+	metrics.ObserveQuery(repositoryName, "GetVaccinationsData", start, err, errorNames)
+	return list, err
+}
+
+func (r instrumentedRepository) ExportVaccinationItems(ctx context.Context, fn func(*models.Vaccination) error) error {
+	var start = time.Now()
+	err := r.next.ExportVaccinationItems(ctx, fn)
+	metrics.ObserveQuery(repositoryName, "ExportVaccinationItems", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) CreateNewVaccinationItem(ctx context.Context, form *models.VaccinationForm) error {
+	var start = time.Now()
+	err := r.next.CreateNewVaccinationItem(ctx, form)
+	metrics.ObserveQuery(repositoryName, "CreateNewVaccinationItem", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) ImportVaccinationItems(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	var start = time.Now()
+	errs, err := r.next.ImportVaccinationItems(ctx, forms, opts)
+	metrics.ObserveQuery(repositoryName, "ImportVaccinationItems", start, err, errorNames)
+	return errs, err
+}
+
+func (r instrumentedRepository) GetVaccinationItemByID(ctx context.Context, vaccinationId int) (*models.Vaccination, error) {
+	var start = time.Now()
+	item, err := r.next.GetVaccinationItemByID(ctx, vaccinationId)
+	metrics.ObserveQuery(repositoryName, "GetVaccinationItemByID", start, err, errorNames)
+	return item, err
+}
+
+func (r instrumentedRepository) UpdateVaccinationItem(ctx context.Context, vaccinationId int, version int, form *models.Vaccination) error {
+	var start = time.Now()
+	err := r.next.UpdateVaccinationItem(ctx, vaccinationId, version, form)
+	metrics.ObserveQuery(repositoryName, "UpdateVaccinationItem", start, err, errorNames)
+	return err
+}
+
+func (r instrumentedRepository) DeleteVaccinationItem(ctx context.Context, vaccinationId int, version int) error {
+	var start = time.Now()
+	err := r.next.DeleteVaccinationItem(ctx, vaccinationId, version)
+	metrics.ObserveQuery(repositoryName, "DeleteVaccinationItem", start, err, errorNames)
+	return err
+}
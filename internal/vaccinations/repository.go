@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
 	"kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/database"
+	"kiramishima/ionix/internal/pkg/tracing"
+	"time"
 )
 
 // implement drug repository
@@ -29,26 +33,30 @@ type repository struct {
 	log *zap.Logger
 }
 
-func (repo repository) GetVaccinationsData(ctx context.Context) ([]*models.Vaccination, error) {
-	var query = `SELECT
+// listVaccinationsQuery vaccinations of the list and the export
+const listVaccinationsQuery = `SELECT
 		v.id,
-		v.name,
+		p.id patient_id,
+		p.name patient,
+		p.document_id,
 		d.name drug,
 		v.drug_id,
 		v.dose,
 		v.applied_at
 	FROM vaccinations v
 	INNER JOIN drugs d on d.id = v.drug_id
-	WHERE d.deleted_at IS NULL OR v.deleted_at IS NULL`
+	INNER JOIN patients p on p.id = v.patient_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND p.deleted_at IS NULL`
 
-	stmt, err := repo.db.PreparexContext(ctx, query)
+func (repo repository) GetVaccinationsData(ctx context.Context) ([]*models.Vaccination, error) {
+	stmt, err := repo.db.PreparexContext(ctx, listVaccinationsQuery)
 	if err != nil {
 		return nil, ErrPrepapareQuery
 	}
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("failed to close statement", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
 		}
 	}(stmt)
 
@@ -63,13 +71,14 @@ func (repo repository) GetVaccinationsData(ctx context.Context) ([]*models.Vacci
 			return list, ErrExecuteStatement
 		}
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var appliedAt sql.NullTime
 		var item = &models.Vaccination{}
-		err = rows.Scan(&item.ID, &item.Name, &item.Drug, &item.DrugID, &item.Dose, &appliedAt)
-
-		if errors.Is(err, sql.ErrNoRows) {
-			break
+		if err := rows.Scan(&item.ID, &item.Patient.ID, &item.Patient.Name, &item.Patient.DocumentID, &item.Drug, &item.DrugID, &item.Dose, &appliedAt); err != nil {
+			tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+			return nil, ErrExecuteStatement
 		}
 
 		if appliedAt.Valid {
@@ -77,69 +86,261 @@ func (repo repository) GetVaccinationsData(ctx context.Context) ([]*models.Vacci
 		}
 		list = append(list, item)
 	}
+	if err := rows.Err(); err != nil {
+		tracing.Logger(ctx, repo.log).Error("[ERROR]", zap.Error(err))
+		return nil, ErrExecuteStatement
+	}
 
 	return list, nil
 }
 
-func (repo repository) CreateNewVaccinationItem(ctx context.Context, form *models.VaccinationForm) error {
-	repo.log.Info("[INFO]", zap.Any("form", form))
-	var query = `INSERT INTO vaccinations (name, drug_id, dose, applied_at)
-	VALUES ($1, $2, $3, CAST($4 AS TIMESTAMP))`
-	stmt, err := repo.db.PreparexContext(ctx, query)
+// ExportVaccinationItems streams the vaccinations of the list to fn, the rows are read from the
+// cursor one at a time and fn must not keep the item
+func (repo repository) ExportVaccinationItems(ctx context.Context, fn func(*models.Vaccination) error) error {
+	stmt, err := repo.db.PreparexContext(ctx, listVaccinationsQuery+` ORDER BY v.id`)
 	if err != nil {
 		return ErrPrepapareQuery
 	}
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("failed to close statement", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
 		}
 	}(stmt)
 
+	rows, err := stmt.QueryxContext(ctx)
+	if err != nil {
+		return ErrExecuteStatement
+	}
+	defer rows.Close()
+
+	var appliedAt sql.NullTime
+	var item = &models.Vaccination{}
+	for rows.Next() {
+		*item = models.Vaccination{}
+		err = rows.Scan(&item.ID, &item.Patient.ID, &item.Patient.Name, &item.Patient.DocumentID, &item.Drug, &item.DrugID, &item.Dose, &appliedAt)
+		if err != nil {
+			return ErrExecuteStatement
+		}
+		if appliedAt.Valid {
+			item.AppliedAt = appliedAt.Time
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ErrExecuteStatement
+	}
+
+	return nil
+}
+
+func (repo repository) CreateNewVaccinationItem(ctx context.Context, form *models.VaccinationForm) error {
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("form", form))
+
 	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return ErrBeginTransaction
 	}
 	defer func(tx *sqlx.Tx) {
 		err := tx.Rollback()
-		if err != nil {
-			repo.log.Error("failed to rollback", zap.Error(err))
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	if err = repo.insertVaccination(ctx, tx, form); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrCommitTransaction
+	}
+	return nil
+}
+
+// ImportVaccinationItems inserts the vaccinations in one transaction, each vaccination in a savepoint so a
+// failed row does not abort the others, the transaction is committed when opts accepts the failed rows
+func (repo repository) ImportVaccinationItems(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
 		}
 	}(tx)
 
-	_, err = stmt.ExecContext(ctx, form.Name, form.DrugID, form.Dose, form.AppliedAt)
+	var errs = make([]error, len(forms))
+	var failed int
+	for i, form := range forms {
+		// the rows already inserted count for the dose schedule of the next ones
+		errs[i] = database.Savepoint(ctx, tx, "import_row", func() error {
+			return repo.insertVaccination(ctx, tx, form)
+		})
+		if errors.Is(errs[i], database.ErrSavepoint) {
+			tracing.Logger(ctx, repo.log).Error("failed to import vaccinations", zap.Error(errs[i]))
+			return nil, ErrExecuteStatement
+		}
+		if errs[i] != nil {
+			failed++
+		}
+	}
+	tracing.Logger(ctx, repo.log).Info("imported vaccinations", zap.Int("rows", len(forms)), zap.Int("failed", failed), zap.Bool("commit", opts.Commit(failed)))
+
+	if !opts.Commit(failed) {
+		return errs, nil
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, ErrCommitTransaction
+	}
+	return errs, nil
+}
+
+// insertVaccination checks the drug rules, inserts the vaccination and records it in the audit log
+func (repo repository) insertVaccination(ctx context.Context, tx *sqlx.Tx, form *models.VaccinationForm) error {
+	var appliedAt = form.AppliedAt.Time
+
+	// the drug rules are checked in the same transaction as the insert
+	if err := repo.checkDrugRules(ctx, tx, *form.DrugID, *form.Dose, appliedAt); err != nil {
+		return err
+	}
+	if err := repo.checkSchedule(ctx, tx, *form.PatientID, *form.DrugID, 0, appliedAt); err != nil {
+		return err
+	}
 
+	var query = `INSERT INTO vaccinations (patient_id, drug_id, dose, applied_at)
+	VALUES ($1, $2, $3, $4) RETURNING id`
+	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
-		repo.log.Info(err.Error())
-		var pgErr *pq.Error
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	var vaccinationId int32
+	err = stmt.QueryRowxContext(ctx, form.PatientID, form.DrugID, form.Dose, form.AppliedAt).Scan(&vaccinationId)
+
+	if err != nil {
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		var pgErr *pgconn.PgError
 		ok := errors.As(err, &pgErr)
 		if ok {
-			repo.log.Info(string(pgErr.Code))
+			tracing.Logger(ctx, repo.log).Info(pgErr.Code)
 			if pgErr.Code == "23505" {
 				return ErrDuplicateVaccination
+			} else if pgErr.Code == "23503" {
+				return ErrPatientNotFound
 			} else {
 				return ErrInsertFailed
 			}
 		}
+		return ErrInsertFailed
+	}
 
+	var after = &auditVaccination{
+		ID:        vaccinationId,
+		PatientID: int32(*form.PatientID),
+		DrugID:    int32(*form.DrugID),
+		Dose:      int32(*form.Dose),
+		AppliedAt: appliedAt,
 	}
-	if err = tx.Commit(); err != nil {
-		return ErrCommitTransaction
+	return audit.Record(ctx, tx, audit.ActionCreate, audit.EntityVaccination, vaccinationId, nil, after)
+}
+
+// checkDrugRules locks the drug row and validates the dose and date against it
+func (repo repository) checkDrugRules(ctx context.Context, tx *sqlx.Tx, drugId int, dose int, appliedAt time.Time) error {
+	var query = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs
+	WHERE deleted_at IS NULL AND id = $1 FOR SHARE`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
 	}
-	return nil
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	var drug = &models.Drug{}
+	err = stmt.QueryRowContext(ctx, drugId).Scan(&drug.ID, &drug.Name, &drug.Approved, &drug.MinDose, &drug.MaxDose, &drug.AvailableAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDrugNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
+	}
+
+	return drug.ValidateApplication(dose, appliedAt)
+}
+
+// checkSchedule validates the application against the previous doses of the series,
+// drugs without schedule are single dose and are not checked
+func (repo repository) checkSchedule(ctx context.Context, tx *sqlx.Tx, patientId int, drugId int, vaccinationId int, appliedAt time.Time) error {
+	var query = `SELECT drug_id, doses, min_interval_days, recommended_interval_days, booster_interval_days
+	FROM drug_schedules WHERE drug_id = $1`
+	stmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(stmt)
+
+	var schedule = &models.DrugSchedule{}
+	err = stmt.QueryRowContext(ctx, drugId).Scan(&schedule.DrugID, &schedule.Doses, &schedule.MinIntervalDays, &schedule.RecommendedIntervalDays, &schedule.BoosterIntervalDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return ErrExecuteStatement
+	}
+
+	query = `SELECT applied_at FROM vaccinations
+	WHERE deleted_at IS NULL AND patient_id = $1 AND drug_id = $2 AND id <> $3 ORDER BY applied_at ASC`
+	appliedStmt, err := tx.PreparexContext(ctx, query)
+	if err != nil {
+		return ErrPrepapareQuery
+	}
+	defer func(stmt *sqlx.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+			tracing.Logger(ctx, repo.log).Error("failed to close statement", zap.Error(err))
+		}
+	}(appliedStmt)
+
+	var applied = make([]time.Time, 0)
+	if err = appliedStmt.SelectContext(ctx, &applied, patientId, drugId, vaccinationId); err != nil {
+		return ErrExecuteStatement
+	}
+
+	return schedule.ValidateNextDose(applied, appliedAt)
 }
 
 func (repo repository) GetVaccinationItemByID(ctx context.Context, vaccinationId int) (*models.Vaccination, error) {
 	var query = `SELECT
 		v.id,
-		v.name,
+		p.id patient_id,
+		p.name patient,
+		p.document_id,
 		d.name drug,
 		v.drug_id,
 		v.dose,
-		v.applied_at
+		v.applied_at,
+		v.version
 	FROM vaccinations v
 	INNER JOIN drugs d on d.id = v.drug_id
-	WHERE (d.deleted_at IS NULL OR v.deleted_at IS NULL) AND v.id = $1`
+	INNER JOIN patients p on p.id = v.patient_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND p.deleted_at IS NULL AND v.id = $1`
 
 	stmt, err := repo.db.PreparexContext(ctx, query)
 	if err != nil {
@@ -148,7 +349,7 @@ func (repo repository) GetVaccinationItemByID(ctx context.Context, vaccinationId
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("failed to prepare statement", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("failed to prepare statement", zap.Error(err))
 		}
 	}(stmt)
 
@@ -156,10 +357,12 @@ func (repo repository) GetVaccinationItemByID(ctx context.Context, vaccinationId
 
 	var appliedAt sql.NullTime
 	var item = &models.Vaccination{}
-	err = row.Scan(&item.ID, &item.Name, &item.Drug, &item.DrugID, &item.Dose, &appliedAt)
-	repo.log.Info("[INFO]", zap.Any("item", item))
+	err = row.Scan(&item.ID, &item.Patient.ID, &item.Patient.Name, &item.Patient.DocumentID, &item.Drug, &item.DrugID, &item.Dose, &appliedAt, &item.Version)
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("item", item))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrVaccinationNotFound
+	} else if err != nil {
+		return nil, ErrExecuteStatement
 	}
 
 	if appliedAt.Valid {
@@ -169,84 +372,154 @@ func (repo repository) GetVaccinationItemByID(ctx context.Context, vaccinationId
 	return item, nil
 }
 
-func (repo repository) UpdateVaccinationItem(ctx context.Context, vaccinationId int, form *models.Vaccination) error {
-	repo.log.Info("[INFO]", zap.Any("form", form))
-	var query = `UPDATE vaccinations SET name = $1, drug_id = $2, dose = $3, applied_at = $4, updated_at=NOW() WHERE id = $5`
-	stmt, err := repo.db.PreparexContext(ctx, query)
+// UpdateVaccinationItem updates the vaccination when it is still in version, 0 updates any version
+func (repo repository) UpdateVaccinationItem(ctx context.Context, vaccinationId int, version int, form *models.Vaccination) error {
+	tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("form", form))
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.lockVaccination(ctx, tx, vaccinationId, version)
+	if err != nil {
+		return err
+	}
+
+	// the drug rules are checked in the same transaction as the update
+	if err = repo.checkDrugRules(ctx, tx, int(form.DrugID), int(form.Dose), form.AppliedAt); err != nil {
+		return err
+	}
+	if err = repo.checkSchedule(ctx, tx, int(form.Patient.ID), int(form.DrugID), vaccinationId, form.AppliedAt); err != nil {
+		return err
+	}
+
+	var query = `UPDATE vaccinations SET patient_id = $1, drug_id = $2, dose = $3, applied_at = $4, updated_at=NOW(), version = version + 1 WHERE id = $5`
+	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
 	}
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("failed to prepare statement", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("failed to prepare statement", zap.Error(err))
 		}
 	}(stmt)
 
-	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
-	if err != nil {
-		return ErrBeginTransaction
-	}
-
-	_, err = stmt.ExecContext(ctx, form.Name, form.DrugID, form.Dose, form.AppliedAt, vaccinationId)
+	_, err = stmt.ExecContext(ctx, form.Patient.ID, form.DrugID, form.Dose, form.AppliedAt, vaccinationId)
 
 	if err != nil {
-		repo.log.Info(err.Error())
-		// log.Println("Code 2 ", errors.Is(err, my.ErrDupeKey))
-		var pgErr *pq.Error
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		var pgErr *pgconn.PgError
 		ok := errors.As(err, &pgErr)
 		if ok {
-			repo.log.Info("[INFO]", zap.Any("PGCode -> ", pgErr.Code))
+			tracing.Logger(ctx, repo.log).Info("[INFO]", zap.Any("PGCode -> ", pgErr.Code))
 			if pgErr.Code == "23505" {
-				return errors.New("row already exists")
+				return ErrDuplicateVaccination
+			} else if pgErr.Code == "23503" {
+				return ErrPatientNotFound
 			} else {
 				return ErrUpdatingRecord
 			}
 		}
+		return ErrUpdatingRecord
+	}
 
+	var after = &auditVaccination{
+		ID:        before.ID,
+		PatientID: form.Patient.ID,
+		DrugID:    form.DrugID,
+		Dose:      form.Dose,
+		AppliedAt: form.AppliedAt,
 	}
+	if err = audit.Record(ctx, tx, audit.ActionUpdate, audit.EntityVaccination, vaccinationId, before, after); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrCommitTransaction
 	}
 	return nil
 }
 
-func (repo repository) DeleteVaccinationItem(ctx context.Context, vaccinationId int) error {
-	var query = `UPDATE vaccinations SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-	stmt, err := repo.db.PreparexContext(ctx, query)
+// DeleteVaccinationItem deletes the vaccination when it is still in version, 0 deletes any version
+func (repo repository) DeleteVaccinationItem(ctx context.Context, vaccinationId int, version int) error {
+	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return ErrBeginTransaction
+	}
+	defer func(tx *sqlx.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tracing.Logger(ctx, repo.log).Error("failed to rollback", zap.Error(err))
+		}
+	}(tx)
+
+	before, err := repo.lockVaccination(ctx, tx, vaccinationId, version)
+	if err != nil {
+		return err
+	}
+
+	var query = `UPDATE vaccinations SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
+	stmt, err := tx.PreparexContext(ctx, query)
 	if err != nil {
 		return ErrPrepapareQuery
 	}
 	defer func(stmt *sqlx.Stmt) {
 		err := stmt.Close()
 		if err != nil {
-			repo.log.Error("failed to prepare statement", zap.Error(err))
+			tracing.Logger(ctx, repo.log).Error("failed to prepare statement", zap.Error(err))
 		}
 	}(stmt)
-	tx, err := repo.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
-	if err != nil {
-		return ErrBeginTransaction
-	}
 
 	_, err = stmt.ExecContext(ctx, vaccinationId)
 
 	if err != nil {
-		repo.log.Info(err.Error())
-		// log.Println("Code 2 ", errors.Is(err, my.ErrDupeKey))
-		var pgErr *pq.Error
-		ok := errors.As(err, &pgErr)
-		if ok {
-			repo.log.Info("[INFO]", zap.Any("PG-CODE", pgErr.Code))
-			if pgErr.Code == "23505" {
-				return errors.New("row already exists")
-			} else {
-				return ErrDeletingRecord
-			}
-		}
+		tracing.Logger(ctx, repo.log).Info(err.Error())
+		return ErrDeletingRecord
+	}
 
+	if err = audit.Record(ctx, tx, audit.ActionDelete, audit.EntityVaccination, vaccinationId, before, nil); err != nil {
+		return err
 	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrCommitTransaction
 	}
 	return nil
 }
+
+// auditVaccination state of a vaccination stored in the audit log
+type auditVaccination struct {
+	ID        int32     `json:"id"`
+	PatientID int32     `json:"patient_id"`
+	DrugID    int32     `json:"drug_id"`
+	Dose      int32     `json:"dose"`
+	AppliedAt time.Time `json:"applied_at"`
+	Version   int       `json:"-"`
+}
+
+// lockVaccination reads the current state of the vaccination and locks the row until the end of the transaction,
+// ErrVersionConflict when the vaccination is not in version, 0 accepts any version
+func (repo repository) lockVaccination(ctx context.Context, tx *sqlx.Tx, vaccinationId int, version int) (*auditVaccination, error) {
+	var query = `SELECT id, patient_id, drug_id, dose, applied_at, version FROM vaccinations
+	WHERE deleted_at IS NULL AND id = $1 FOR UPDATE`
+
+	var item = &auditVaccination{}
+	err := tx.QueryRowxContext(ctx, query, vaccinationId).Scan(&item.ID, &item.PatientID, &item.DrugID, &item.Dose, &item.AppliedAt, &item.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVaccinationNotFound
+	} else if err != nil {
+		tracing.Logger(ctx, repo.log).Error("failed to read vaccination", zap.Error(err))
+		return nil, ErrExecuteStatement
+	}
+	if version != 0 && item.Version != version {
+		return nil, ErrVersionConflict
+	}
+	return item, nil
+}
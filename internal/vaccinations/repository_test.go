@@ -3,6 +3,7 @@ package vaccinations
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"kiramishima/ionix/internal/audit"
+	"kiramishima/ionix/internal/models"
 )
 
 func TestRepository_GetVaccinationsData(t *testing.T) {
@@ -32,18 +35,23 @@ func TestRepository_GetVaccinationsData(t *testing.T) {
 
 	var query = `SELECT
 		v.id,
-		v.name,
+		p.id patient_id,
+		p.name patient,
+		p.document_id,
 		d.name drug,
 		v.drug_id,
 		v.dose,
 		v.applied_at
 	FROM vaccinations v
 	INNER JOIN drugs d on d.id = v.drug_id
-	WHERE d.deleted_at IS NULL OR v.deleted_at IS NULL`
+	INNER JOIN patients p on p.id = v.patient_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND p.deleted_at IS NULL`
 
-	var rows = sqlmock.NewRows([]string{"id", "name", "drug", "drug_id", "dose", "applied_at"}).
-		FromCSVString("1,jhon wick,aspirina,1,5,2024-03-18 15:45:00").
-		FromCSVString("2,jhon connor,cafiaspirina,1,5,2024-03-18 15:45:00")
+	var columns = []string{"id", "patient_id", "patient", "document_id", "drug", "drug_id", "dose", "applied_at"}
+	var appliedAt = time.Date(2024, 3, 18, 15, 45, 0, 0, time.UTC)
+	var rows = sqlmock.NewRows(columns).
+		AddRow(1, 1, "jhon wick", "AAA001", "aspirina", 1, 5, appliedAt).
+		AddRow(2, 2, "jhon connor", "AAA002", "cafiaspirina", 1, 5, appliedAt)
 
 	t.Run("OK", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
@@ -57,7 +65,37 @@ func TestRepository_GetVaccinationsData(t *testing.T) {
 		t.Log(len(data), err)
 		assert.NoError(t, err)
 		assert.Equal(t, len(data), 2)
-		assert.Equal(t, data[0].Name, "jhon wick")
+		assert.Equal(t, data[0].Patient.Name, "jhon wick")
+		assert.Equal(t, appliedAt, data[0].AppliedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Scan and rows errors", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		// a row that can not be scanned is not returned
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, 1, "jhon wick", "AAA001", "aspirina", 1, 5, appliedAt).
+				AddRow("two", 2, "jhon connor", "AAA002", "cafiaspirina", 1, 5, appliedAt))
+
+		data, err := repo.GetVaccinationsData(ctx)
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.Nil(t, data)
+
+		// the error that ends the cursor is not a shorter list
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, 1, "jhon wick", "AAA001", "aspirina", 1, 5, appliedAt).
+				AddRow(2, 2, "jhon connor", "AAA002", "cafiaspirina", 1, 5, appliedAt).
+				RowError(1, errors.New("connection reset")))
+
+		data, err = repo.GetVaccinationsData(ctx)
+		assert.ErrorIs(t, err, ErrExecuteStatement)
+		assert.Nil(t, data)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -76,3 +114,320 @@ func TestRepository_GetVaccinationsData(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestRepository_ExportVaccinationItems(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	repo := NewVaccinationRepository(sqlxDB, logger)
+
+	var appliedAt = time.Date(2024, 3, 18, 15, 45, 0, 0, time.UTC)
+	// the soft deleted vaccinations and the vaccinations of deleted drugs are never exported
+	mock.ExpectPrepare(`SELECT
+		v.id,
+		p.id patient_id,
+		p.name patient,
+		p.document_id,
+		d.name drug,
+		v.drug_id,
+		v.dose,
+		v.applied_at
+	FROM vaccinations v
+	INNER JOIN drugs d on d.id = v.drug_id
+	INNER JOIN patients p on p.id = v.patient_id
+	WHERE v.deleted_at IS NULL AND d.deleted_at IS NULL AND p.deleted_at IS NULL ORDER BY v.id`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "patient_id", "patient", "document_id", "drug", "drug_id", "dose", "applied_at"}).
+			AddRow(1, 1, "jhon wick", "AAA001", "aspirina", 1, 5, appliedAt).
+			AddRow(2, 2, "jhon connor", nil, "cafiaspirina", 1, 5, appliedAt))
+
+	var patients []string
+	err = repo.ExportVaccinationItems(context.Background(), func(vaccination *models.Vaccination) error {
+		patients = append(patients, vaccination.Patient.Name)
+		assert.Equal(t, appliedAt, vaccination.AppliedAt)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"jhon wick", "jhon connor"}, patients)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CreateNewVaccinationItem(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewVaccinationRepository(sqlxDB, logger)
+
+	var drugQuery = `SELECT id, name, approved, min_dose, max_dose, available_at FROM drugs
+	WHERE deleted_at IS NULL AND id = $1 FOR SHARE`
+	var query = `INSERT INTO vaccinations (patient_id, drug_id, dose, applied_at)
+	VALUES ($1, $2, $3, $4) RETURNING id`
+	var scheduleQuery = `SELECT drug_id, doses, min_interval_days, recommended_interval_days, booster_interval_days
+	FROM drug_schedules WHERE drug_id = $1`
+	var appliedQuery = `SELECT applied_at FROM vaccinations
+	WHERE deleted_at IS NULL AND patient_id = $1 AND drug_id = $2 AND id <> $3 ORDER BY applied_at ASC`
+	var drugColumns = []string{"id", "name", "approved", "min_dose", "max_dose", "available_at"}
+	var scheduleColumns = []string{"drug_id", "doses", "min_interval_days", "recommended_interval_days", "booster_interval_days"}
+	var availableAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newForm := func(dose int, appliedAt string) *models.VaccinationForm {
+		var patientID = 1
+		var drugID = 1
+		tm, _ := models.ParseDateTime(appliedAt)
+		return &models.VaccinationForm{PatientID: &patientID, DrugID: &drugID, Dose: &dose, AppliedAt: &models.DateTime{Time: tm}}
+	}
+
+	t.Run("Insert is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+		var form = newForm(2, "2024-03-18 15:45:00")
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(drugColumns).AddRow(1, "aspirina", true, 1, 5, availableAt))
+		mock.ExpectPrepare(scheduleQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(form.PatientID, form.DrugID, form.Dose, form.AppliedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityVaccination, "1", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateNewVaccinationItem(ctx, form)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Next dose of the series is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+		var form = newForm(2, "2024-03-18 15:45:00")
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(drugColumns).AddRow(1, "aspirina", true, 1, 5, availableAt))
+		mock.ExpectPrepare(scheduleQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(scheduleColumns).AddRow(1, 2, 21, 28, nil))
+		mock.ExpectPrepare(appliedQuery).
+			ExpectQuery().
+			WithArgs(1, 1, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"applied_at"}).AddRow(time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)))
+		mock.ExpectPrepare(query).
+			ExpectQuery().
+			WithArgs(form.PatientID, form.DrugID, form.Dose, form.AppliedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionCreate, audit.EntityVaccination, "1", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateNewVaccinationItem(ctx, form)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dose applied before the minimum interval", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(drugColumns).AddRow(1, "aspirina", true, 1, 5, availableAt))
+		mock.ExpectPrepare(scheduleQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(scheduleColumns).AddRow(1, 2, 21, 28, nil))
+		mock.ExpectPrepare(appliedQuery).
+			ExpectQuery().
+			WithArgs(1, 1, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"applied_at"}).AddRow(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)))
+		mock.ExpectRollback()
+
+		err := repo.CreateNewVaccinationItem(ctx, newForm(2, "2024-03-18 15:45:00"))
+		var ve models.ValidationErrors
+		assert.ErrorAs(t, err, &ve)
+		assert.Equal(t, 1, len(ve))
+		assert.Equal(t, "applied_at", ve[0].Field)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Backdated dose before the minimum interval of the next dose", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(drugColumns).AddRow(1, "aspirina", true, 1, 5, availableAt))
+		mock.ExpectPrepare(scheduleQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(scheduleColumns).AddRow(1, 2, 21, 28, nil))
+		mock.ExpectPrepare(appliedQuery).
+			ExpectQuery().
+			WithArgs(1, 1, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"applied_at"}).AddRow(time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)))
+		mock.ExpectRollback()
+
+		// the dose of 2024-04-01 would be the second dose only 14 days after this one
+		err := repo.CreateNewVaccinationItem(ctx, newForm(1, "2024-03-18 15:45:00"))
+		var ve models.ValidationErrors
+		assert.ErrorAs(t, err, &ve)
+		assert.Equal(t, 1, len(ve))
+		assert.Equal(t, "applied_at", ve[0].Field)
+		assert.Equal(t, "dose_too_late", ve[0].Rule)
+		assert.Equal(t, "1 2024-03-11T10:00:00Z", ve[0].Param)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dose and date out of the drug definition", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+		var form = newForm(9, "2023-03-18 15:45:00")
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(drugColumns).AddRow(1, "aspirina", false, 1, 5, availableAt))
+		mock.ExpectRollback()
+
+		err := repo.CreateNewVaccinationItem(ctx, form)
+		var ve models.ValidationErrors
+		assert.ErrorAs(t, err, &ve)
+		assert.Equal(t, 3, len(ve))
+		assert.Equal(t, "drug_id", ve[0].Field)
+		assert.Equal(t, "dose", ve[1].Field)
+		assert.Equal(t, "applied_at", ve[2].Field)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Drug not found", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(drugQuery).
+			ExpectQuery().
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.CreateNewVaccinationItem(ctx, newForm(2, "2024-03-18 15:45:00"))
+		assert.EqualError(t, err, ErrDrugNotFound.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_DeleteVaccinationItem(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			logger.Error("", zap.Error(err))
+		}
+	}(db)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	c := context.Background()
+
+	repo := NewVaccinationRepository(sqlxDB, logger)
+
+	var lockQuery = `SELECT id, patient_id, drug_id, dose, applied_at, version FROM vaccinations
+	WHERE deleted_at IS NULL AND id = $1 FOR UPDATE`
+	var query = `UPDATE vaccinations SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
+	var columns = []string{"id", "patient_id", "drug_id", "dose", "applied_at", "version"}
+
+	t.Run("Deleted is OK", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, 1, 2, time.Date(2024, 3, 18, 15, 45, 0, 0, time.UTC), 1))
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(audit.InsertQuery).
+			WithArgs(nil, audit.ActionDelete, audit.EntityVaccination, "1",
+				`{"before":{"applied_at":"2024-03-18T15:45:00Z","dose":2,"drug_id":1,"id":1,"patient_id":1},"after":null}`, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := repo.DeleteVaccinationItem(ctx, 1, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Stale version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, 1, 2, time.Date(2024, 3, 18, 15, 45, 0, 0, time.UTC), 2))
+		mock.ExpectRollback()
+
+		err := repo.DeleteVaccinationItem(ctx, 1, 1)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Vaccination not found", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(c, time.Duration(5)*time.Second)
+		defer cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(2).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.DeleteVaccinationItem(ctx, 2, 0)
+		assert.EqualError(t, err, ErrVaccinationNotFound.Error())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -6,17 +6,20 @@ import (
 	"go.uber.org/zap"
 	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/metrics"
+	"kiramishima/ionix/internal/pkg/tracing"
 	"time"
 )
 
 var _ impl.VaccinationService = (*service)(nil)
 
 // NewVaccinationService creates a new vaccination service
-func NewVaccinationService(repo impl.VaccinationRepository, logger *zap.Logger, timeout time.Duration) *service {
+func NewVaccinationService(repo impl.VaccinationRepository, logger *zap.Logger, timeout time.Duration, importTimeout time.Duration) *service {
 	return &service{
 		logger:         logger,
 		repository:     repo,
 		contextTimeOut: timeout,
+		importTimeOut:  importTimeout,
 	}
 }
 
@@ -24,6 +27,8 @@ type service struct {
 	logger         *zap.Logger
 	repository     impl.VaccinationRepository
 	contextTimeOut time.Duration
+	// importTimeOut bounds the imports instead of contextTimeOut, every row of a file runs in one transaction
+	importTimeOut time.Duration
 }
 
 func (svc service) GetListVaccinations(ctx context.Context) ([]*models.Vaccination, error) {
@@ -32,7 +37,7 @@ func (svc service) GetListVaccinations(ctx context.Context) ([]*models.Vaccinati
 	data, err := svc.repository.GetVaccinationsData(cxt)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
@@ -47,10 +52,58 @@ func (svc service) GetListVaccinations(ctx context.Context) ([]*models.Vaccinati
 			}
 		}
 	}
-	svc.logger.Info("GetListVaccinations", zap.Any("data", data))
+	tracing.Logger(ctx, svc.logger).Info("GetListVaccinations", zap.Any("data", data))
 	return data, nil
 }
 
+// ExportVaccinations streams the vaccinations to fn, the export is bound to the request instead of the timeout
+// of the service because it can take much longer than a page
+func (svc service) ExportVaccinations(ctx context.Context, fn func(*models.Vaccination) error) error {
+	err := svc.repository.ExportVaccinationItems(ctx, fn)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ErrTimeout
+		default:
+			if errors.Is(err, ErrPrepapareQuery) || errors.Is(err, ErrExecuteStatement) {
+				return ErrExecuteStatement
+			}
+			// the error of fn writing the item
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc service) GetVaccination(ctx context.Context, vaccinationId int) (*models.Vaccination, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
+	defer cancel()
+
+	vaccination, err := svc.repository.GetVaccinationItemByID(cxt, vaccinationId)
+
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrTimeout
+		default:
+			if errors.Is(err, ErrVaccinationNotFound) {
+				return nil, ErrVaccinationNotFound
+			} else if errors.Is(err, ErrExecuteStatement) {
+				return nil, ErrExecuteStatement
+			} else {
+				return nil, ErrServiceVaccination
+			}
+		}
+	}
+
+	return vaccination, nil
+}
+
 func (svc service) NewVaccination(ctx context.Context, form *models.VaccinationForm) error {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
@@ -58,70 +111,108 @@ func (svc service) NewVaccination(ctx context.Context, form *models.VaccinationF
 	err := svc.repository.CreateNewVaccinationItem(ctx, form)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-cxt.Done():
 			return ErrTimeout
 		default:
-			if errors.Is(err, ErrDuplicateVaccination) {
+			var ve models.ValidationErrors
+			if errors.As(err, &ve) {
+				return ve
+			} else if errors.Is(err, ErrDuplicateVaccination) {
 				return ErrDuplicateVaccination
 			} else if errors.Is(err, ErrVaccinationNotFound) {
 				return ErrVaccinationNotFound
+			} else if errors.Is(err, ErrPatientNotFound) {
+				return ErrPatientNotFound
+			} else if errors.Is(err, ErrDrugNotFound) {
+				return ErrDrugNotFound
 			} else {
 				return ErrExecuteStatement
 			}
 		}
 	}
 
+	metrics.VaccinationsRecorded.Inc()
 	return nil
 }
 
-func (svc service) UpdateVaccination(ctx context.Context, vaccinationId int, form *models.VaccinationForm) error {
+// ImportVaccinations imports the validated vaccinations, the error of each row is returned in the order of forms
+func (svc service) ImportVaccinations(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	cxt, cancel := context.WithTimeout(ctx, svc.importTimeOut)
+	defer cancel()
+
+	errs, err := svc.repository.ImportVaccinationItems(cxt, forms, opts)
+	if err != nil {
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
+
+		select {
+		case <-cxt.Done():
+			return nil, ErrTimeout
+		default:
+			return nil, ErrExecuteStatement
+		}
+	}
+
+	var failed int
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if opts.Commit(failed) {
+		metrics.VaccinationsRecorded.Add(float64(len(forms) - failed))
+	}
+	return errs, nil
+}
+
+func (svc service) UpdateVaccination(ctx context.Context, vaccinationId int, version int, form *models.VaccinationForm) error {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 	// Retrieve the data
 	vaccination, err := svc.repository.GetVaccinationItemByID(cxt, vaccinationId)
-	svc.logger.Info("UpdateVaccination", zap.Any("data", vaccination), zap.Any("err", err))
+	tracing.Logger(ctx, svc.logger).Info("UpdateVaccination", zap.Any("data", vaccination), zap.Any("err", err))
 
 	if errors.Is(err, ErrVaccinationNotFound) {
 		return ErrVaccinationNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
 	}
-	svc.logger.Info("UpdateVaccination", zap.Any("data", vaccination))
+	tracing.Logger(ctx, svc.logger).Info("UpdateVaccination", zap.Any("data", vaccination))
 
-	//
-	if form.Name != nil {
-		vaccination.Name = *form.Name
-	}
-	if form.DrugID != nil {
-		vaccination.DrugID = int32(*form.DrugID)
-	}
-	if form.Dose != nil {
-		vaccination.Dose = int32(*form.Dose)
-	}
-	if form.AppliedAt != nil {
-		var dt = *form.AppliedAt
-		layout := "2006-01-02 15:04:05"
-		tm, _ := time.Parse(layout, dt)
-		svc.logger.Info("UpdateVaccination", zap.Any("tm", tm))
-		vaccination.AppliedAt = tm
-	}
-	svc.logger.Info("UpdateVaccination", zap.Any("form", form))
+	// the form replaces the vaccination, it was validated by the handler
+	vaccination.Patient.ID = int32(*form.PatientID)
+	vaccination.DrugID = int32(*form.DrugID)
+	vaccination.Dose = int32(*form.Dose)
+	vaccination.AppliedAt = form.AppliedAt.Time
+	tracing.Logger(ctx, svc.logger).Info("UpdateVaccination", zap.Any("form", form))
 
 	// Call repository
-	err = svc.repository.UpdateVaccinationItem(cxt, vaccinationId, vaccination)
+	err = svc.repository.UpdateVaccinationItem(cxt, vaccinationId, version, vaccination)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
 			return ErrTimeout
 		default:
-			if errors.Is(err, ErrExecuteStatement) {
+			var ve models.ValidationErrors
+			if errors.As(err, &ve) {
+				return ve
+			} else if errors.Is(err, ErrExecuteStatement) {
 				return ErrExecuteStatement
 			} else if errors.Is(err, ErrVaccinationNotFound) {
 				return ErrVaccinationNotFound
+			} else if errors.Is(err, ErrVersionConflict) {
+				return ErrVersionConflict
+			} else if errors.Is(err, ErrDrugNotFound) {
+				return ErrDrugNotFound
+			} else if errors.Is(err, ErrDuplicateVaccination) {
+				return ErrDuplicateVaccination
+			} else if errors.Is(err, ErrPatientNotFound) {
+				return ErrPatientNotFound
 			} else {
 				return ErrUpdatingRecord
 			}
@@ -131,22 +222,24 @@ func (svc service) UpdateVaccination(ctx context.Context, vaccinationId int, for
 	return nil
 }
 
-func (svc service) DeleteVaccination(ctx context.Context, vaccinationId int) error {
+func (svc service) DeleteVaccination(ctx context.Context, vaccinationId int, version int) error {
 	cxt, cancel := context.WithTimeout(ctx, svc.contextTimeOut)
 	defer cancel()
 
 	_, err := svc.repository.GetVaccinationItemByID(cxt, vaccinationId)
-	svc.logger.Info("DeleteVaccination", zap.Any("vaccinationId", vaccinationId), zap.Any("err", err))
+	tracing.Logger(ctx, svc.logger).Info("DeleteVaccination", zap.Any("vaccinationId", vaccinationId), zap.Any("err", err))
 
 	if errors.Is(err, ErrVaccinationNotFound) {
 		return ErrVaccinationNotFound
+	} else if err != nil {
+		return ErrExecuteStatement
 	}
 
 	// Call repository
-	err = svc.repository.DeleteVaccinationItem(cxt, vaccinationId)
+	err = svc.repository.DeleteVaccinationItem(cxt, vaccinationId, version)
 
 	if err != nil {
-		svc.logger.Error(err.Error())
+		tracing.Logger(ctx, svc.logger).Error(err.Error())
 
 		select {
 		case <-ctx.Done():
@@ -156,6 +249,8 @@ func (svc service) DeleteVaccination(ctx context.Context, vaccinationId int) err
 				return ErrExecuteStatement
 			} else if errors.Is(err, ErrVaccinationNotFound) {
 				return ErrVaccinationNotFound
+			} else if errors.Is(err, ErrVersionConflict) {
+				return ErrVersionConflict
 			} else {
 				return ErrDeletingRecord
 			}
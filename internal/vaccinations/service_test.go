@@ -4,6 +4,7 @@ import (
 	"context"
 	"kiramishima/ionix/internal/mocks"
 	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/importer"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func TestService_GetListDrugs(t *testing.T) {
 	var data = []*models.Vaccination{
 		{
 			ID:        1,
-			Name:      "Jhon Wick",
+			Patient:   models.PatientSummary{ID: 1, Name: "Jhon Wick"},
 			Drug:      "medicament 1",
 			DrugID:    1,
 			Dose:      1,
@@ -33,7 +34,7 @@ func TestService_GetListDrugs(t *testing.T) {
 		},
 		{
 			ID:        2,
-			Name:      "Jhon Connor",
+			Patient:   models.PatientSummary{ID: 2, Name: "Jhon Connor"},
 			Drug:      "medicament 1",
 			DrugID:    1,
 			Dose:      1,
@@ -44,7 +45,7 @@ func TestService_GetListDrugs(t *testing.T) {
 	repo.EXPECT().GetVaccinationsData(gomock.Any()).Times(1).Return(data, nil)
 	repo.EXPECT().GetVaccinationsData(gomock.Any()).Times(1).Return(nil, ErrNoRecords)
 
-	svc := NewVaccinationService(repo, logger, 5)
+	svc := NewVaccinationService(repo, logger, 5, time.Minute)
 
 	t.Run("Ok- Getting Data", func(t *testing.T) {
 		ctx := context.Background()
@@ -62,3 +63,73 @@ func TestService_GetListDrugs(t *testing.T) {
 		assert.Equal(t, len(item) == 0, true)
 	})
 }
+
+func TestService_GetVaccination(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockVaccinationRepository(mockCtrl)
+
+	var vaccination = &models.Vaccination{
+		ID:        1,
+		Patient:   models.PatientSummary{ID: 3, Name: "Jhon Wick"},
+		Drug:      "medicament 1",
+		DrugID:    1,
+		Dose:      1,
+		AppliedAt: time.Now(),
+	}
+
+	svc := NewVaccinationService(repo, logger, 5*time.Second, time.Minute)
+
+	t.Run("Ok- Getting Data", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetVaccinationItemByID(gomock.Any(), 1).Times(1).Return(vaccination, nil)
+
+		var item, err = svc.GetVaccination(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, vaccination.Patient.Name, item.Patient.Name)
+	})
+
+	t.Run("No existing record", func(t *testing.T) {
+		ctx := context.Background()
+		repo.EXPECT().GetVaccinationItemByID(gomock.Any(), 2).Times(1).Return(nil, ErrVaccinationNotFound)
+
+		var item, err = svc.GetVaccination(ctx, 2)
+		assert.Nil(t, item)
+		assert.EqualError(t, err, ErrVaccinationNotFound.Error())
+	})
+}
+
+func TestService_ImportVaccinations_Timeout(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	mockCtrl := gomock.NewController(t)
+
+	defer mockCtrl.Finish()
+
+	repo := mocks.NewMockVaccinationRepository(mockCtrl)
+
+	// a file at the row limit takes longer than the timeout of the other requests
+	var forms = make([]*models.VaccinationForm, importer.MaxRows)
+	repo.EXPECT().
+		ImportVaccinationItems(gomock.Any(), gomock.Len(importer.MaxRows), models.ImportOptions{Mode: models.ImportAtomic}).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, forms []*models.VaccinationForm, _ models.ImportOptions) ([]error, error) {
+			time.Sleep(20 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.Greater(t, time.Until(deadline), 30*time.Second)
+			return make([]error, len(forms)), nil
+		})
+
+	svc := NewVaccinationService(repo, logger, time.Millisecond, time.Minute)
+	errs, err := svc.ImportVaccinations(context.Background(), forms, models.ImportOptions{Mode: models.ImportAtomic})
+	assert.NoError(t, err)
+	assert.Len(t, errs, importer.MaxRows)
+}
@@ -0,0 +1,75 @@
+package vaccinations
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"kiramishima/ionix/internal/interfaces"
+	"kiramishima/ionix/internal/models"
+	"kiramishima/ionix/internal/pkg/tracing"
+)
+
+var _ interfaces.VaccinationService = (*tracedService)(nil)
+
+// tracedService starts a span per method of the service, the statements of the repository are its children
+type tracedService struct {
+	next interfaces.VaccinationService
+}
+
+// NewTracedVaccinationService wraps a vaccination service with spans
+func NewTracedVaccinationService(next interfaces.VaccinationService) *tracedService {
+	return &tracedService{next: next}
+}
+
+func (s tracedService) GetListVaccinations(ctx context.Context) ([]*models.Vaccination, error) {
+	ctx, span := tracing.Start(ctx, "VaccinationService.GetListVaccinations")
+	list, err := s.next.GetListVaccinations(ctx)
+	tracing.End(span, err)
+	return list, err
+}
+
+func (s tracedService) ExportVaccinations(ctx context.Context, fn func(*models.Vaccination) error) error {
+	ctx, span := tracing.Start(ctx, "VaccinationService.ExportVaccinations")
+	var rows int
+	err := s.next.ExportVaccinations(ctx, func(vaccination *models.Vaccination) error {
+		rows++
+		return fn(vaccination)
+	})
+	span.SetAttributes(attribute.Int("export.rows", rows))
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) GetVaccination(ctx context.Context, vaccinationId int) (*models.Vaccination, error) {
+	ctx, span := tracing.Start(ctx, "VaccinationService.GetVaccination", attribute.Int("vaccination.id", vaccinationId))
+	item, err := s.next.GetVaccination(ctx, vaccinationId)
+	tracing.End(span, err)
+	return item, err
+}
+
+func (s tracedService) NewVaccination(ctx context.Context, form *models.VaccinationForm) error {
+	ctx, span := tracing.Start(ctx, "VaccinationService.NewVaccination")
+	err := s.next.NewVaccination(ctx, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) ImportVaccinations(ctx context.Context, forms []*models.VaccinationForm, opts models.ImportOptions) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "VaccinationService.ImportVaccinations", attribute.Int("import.rows", len(forms)), attribute.Bool("import.dry_run", opts.DryRun))
+	errs, err := s.next.ImportVaccinations(ctx, forms, opts)
+	tracing.End(span, err)
+	return errs, err
+}
+
+func (s tracedService) UpdateVaccination(ctx context.Context, vaccinationId int, version int, form *models.VaccinationForm) error {
+	ctx, span := tracing.Start(ctx, "VaccinationService.UpdateVaccination", attribute.Int("vaccination.id", vaccinationId))
+	err := s.next.UpdateVaccination(ctx, vaccinationId, version, form)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedService) DeleteVaccination(ctx context.Context, vaccinationId int, version int) error {
+	ctx, span := tracing.Start(ctx, "VaccinationService.DeleteVaccination", attribute.Int("vaccination.id", vaccinationId))
+	err := s.next.DeleteVaccination(ctx, vaccinationId, version)
+	tracing.End(span, err)
+	return err
+}
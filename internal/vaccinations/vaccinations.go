@@ -7,19 +7,20 @@ import (
 	"github.com/unrolled/render"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	impl "kiramishima/ionix/internal/interfaces"
 	"kiramishima/ionix/internal/models"
 	"time"
 )
 
 // Module auth
 var Module = fx.Module("vaccinations",
-	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate) error {
+	fx.Invoke(func(conn *sqlx.DB, logger *zap.Logger, cfg *models.Configuration, r *chi.Mux, render *render.Render, validate *validator.Validate, denylist impl.TokenDenylist, signer impl.TokenSigner) error {
 		// loads repository
-		var repo = NewVaccinationRepository(conn, logger)
+		var repo = NewInstrumentedVaccinationRepository(NewVaccinationRepository(conn, logger))
 		// loads service
-		var svc = NewVaccinationService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second)
+		var svc = NewTracedVaccinationService(NewVaccinationService(repo, logger, time.Duration(cfg.ContextTimeout)*time.Second, time.Duration(cfg.ImportTimeout)*time.Second))
 		// loads handlers
-		NewVaccionationHandlers(r, logger, svc, render, validate)
+		NewVaccionationHandlers(r, logger, svc, render, validate, denylist, signer)
 		return nil
 	}),
 )
@@ -0,0 +1,13 @@
+ALTER TABLE vaccinations ADD COLUMN name VARCHAR(120);
+
+UPDATE vaccinations v SET name = p.name
+FROM patients p
+WHERE p.id = v.patient_id;
+
+ALTER TABLE vaccinations ALTER COLUMN name SET NOT NULL;
+ALTER TABLE vaccinations DROP CONSTRAINT IF EXISTS vaccinations_patient_id_drug_id_applied_at_key;
+ALTER TABLE vaccinations ADD CONSTRAINT vaccinations_name_drug_id_applied_at_key UNIQUE (name, drug_id, applied_at);
+ALTER TABLE vaccinations DROP CONSTRAINT IF EXISTS fk_patients;
+ALTER TABLE vaccinations DROP COLUMN patient_id;
+
+DROP TABLE IF EXISTS patients;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS patients(
+    id SERIAL NOT NULL PRIMARY KEY,
+    name VARCHAR(120) NOT NULL,
+    document_id VARCHAR(40) NULL UNIQUE,
+    birthdate DATE NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_patients_birthdate ON patients(birthdate);
+
+-- back-fill: one patient per vaccination already registered, the name does not tell apart two people
+-- with the same name so their doses are never merged; the doses of the same person are joined by
+-- moving them to one of the patients
+ALTER TABLE patients ADD COLUMN vaccination_id INTEGER;
+
+INSERT INTO patients (name, vaccination_id)
+SELECT name, id FROM vaccinations;
+
+ALTER TABLE vaccinations ADD COLUMN patient_id INTEGER;
+
+UPDATE vaccinations v SET patient_id = p.id
+FROM patients p
+WHERE p.vaccination_id = v.id;
+
+ALTER TABLE patients DROP COLUMN vaccination_id;
+
+ALTER TABLE vaccinations ALTER COLUMN patient_id SET NOT NULL;
+ALTER TABLE vaccinations ADD CONSTRAINT fk_patients FOREIGN KEY (patient_id) REFERENCES patients(id);
+ALTER TABLE vaccinations DROP CONSTRAINT IF EXISTS vaccinations_name_drug_id_applied_at_key;
+ALTER TABLE vaccinations ADD CONSTRAINT vaccinations_patient_id_drug_id_applied_at_key UNIQUE (patient_id, drug_id, applied_at);
+ALTER TABLE vaccinations DROP COLUMN name;
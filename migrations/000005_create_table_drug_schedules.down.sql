@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_vaccinations_patient_drug;
+DROP TABLE IF EXISTS drug_schedules;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS drug_schedules(
+    drug_id INTEGER NOT NULL PRIMARY KEY,
+    doses SMALLINT NOT NULL CHECK (doses > 0),
+    min_interval_days INTEGER NOT NULL CHECK (min_interval_days >= 0),
+    recommended_interval_days INTEGER NOT NULL CHECK (recommended_interval_days >= min_interval_days),
+    booster_interval_days INTEGER NULL CHECK (booster_interval_days > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    CONSTRAINT fk_drugs FOREIGN KEY (drug_id) REFERENCES drugs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vaccinations_patient_drug ON vaccinations(patient_id, drug_id, applied_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    actor_id BIGINT NULL,
+    action VARCHAR(20) NOT NULL,
+    entity VARCHAR(40) NOT NULL,
+    entity_id VARCHAR(40) NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR(128) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
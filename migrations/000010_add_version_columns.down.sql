@@ -0,0 +1,3 @@
+ALTER TABLE drugs DROP COLUMN version;
+
+ALTER TABLE vaccinations DROP COLUMN version;
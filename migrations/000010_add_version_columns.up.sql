@@ -0,0 +1,4 @@
+-- optimistic concurrency, every update increments the version returned as ETag
+ALTER TABLE drugs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE vaccinations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
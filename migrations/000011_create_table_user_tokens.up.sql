@@ -0,0 +1,18 @@
+-- the accounts created before the verification flow are considered verified
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = NOW();
+
+-- single use tokens of the password reset and email verification, only the hash is stored
+CREATE TABLE IF NOT EXISTS user_tokens(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMPTZ,
+    CONSTRAINT fk_users FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose);
@@ -0,0 +1,8 @@
+-- failed sign ins per email and per IP, the emails are counted even when the account does not exist
+CREATE TABLE IF NOT EXISTS sign_in_failures(
+    scope VARCHAR(8) NOT NULL,
+    subject VARCHAR(320) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, subject)
+);
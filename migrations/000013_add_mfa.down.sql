@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_required_roles;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_secret;
@@ -0,0 +1,23 @@
+-- totp second factor, the secret is pending until the first code confirms it and mfa_last_step keeps
+-- the period of the last accepted code so a code can not be used twice
+ALTER TABLE users ADD COLUMN mfa_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN mfa_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN mfa_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- single use codes to sign in without the authenticator, only the hash is stored
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    CONSTRAINT fk_users FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id, code_hash);
+
+-- roles whose users have to sign in with a second factor, the admins can change the drugs
+CREATE TABLE IF NOT EXISTS mfa_required_roles(
+    role VARCHAR(16) NOT NULL PRIMARY KEY
+);
+
+INSERT INTO mfa_required_roles(role) VALUES ('admin') ON CONFLICT DO NOTHING;
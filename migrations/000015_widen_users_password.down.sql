@@ -0,0 +1,2 @@
+-- fails while there are argon2id hashes, those users have to reset their password with bcrypt first
+ALTER TABLE users ALTER COLUMN password TYPE CHAR(60);
@@ -0,0 +1,3 @@
+-- the column only fit the 60 characters of bcrypt, the argon2id hashes are close to 100 characters and
+-- keep the parameters in the hash so their length changes with them
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
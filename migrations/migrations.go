@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS sql files of the migrations, embedded in the binary
+//
+//go:embed *.sql
+var FS embed.FS